@@ -0,0 +1,120 @@
+package rlog
+
+import (
+	"path"
+	"runtime"
+)
+
+// callerInfo holds everything rlog derives from the program counter of a log
+// call site: the file, line and function name, and the decision of the log
+// and trace filters for that file. Working this out involves
+// runtime.CallersFrames, splitting the path and glob matching every filter,
+// so it's done once per call site and then cached by the logger.
+//
+// Instances are never modified once they're stored in the cache. When the
+// filters change a new instance is stored instead.
+type callerInfo struct {
+	moduleAndFileName string
+	fullPath          string
+	packageName       string
+	functionName      string
+	shortFunction     string
+	line              int
+
+	// The filter generation the decisions below were made for.
+	generation   uint32
+	logLevel     Level
+	logMatched   bool
+	traceLevel   Level
+	traceMatched bool
+}
+
+// allows checks whether a message of the given log or trace level, coming
+// from this call site, passes the filters.
+func (ci *callerInfo) allows(logLevel Level, traceLevel int) bool {
+	if traceLevel == notATrace {
+		return ci.logMatched && int(logLevel) <= int(ci.logLevel)
+	}
+	return ci.traceMatched && traceLevel <= int(ci.traceLevel)
+}
+
+// applyFilters evaluates the log and trace filters for this call site and
+// records the generation they belong to.
+func (ci *callerInfo) applyFilters(f *filters) {
+	ci.generation = f.generation
+	ci.logLevel, ci.logMatched = f.log.levelFor(ci.moduleAndFileName)
+	ci.traceLevel, ci.traceMatched = f.trace.levelFor(ci.moduleAndFileName)
+}
+
+// newCallerInfo extracts the file, line and function name from a stack frame.
+func newCallerInfo(frame runtime.Frame) *callerInfo {
+	// We only want to print or examine file and package name, so use the
+	// last two elements of the full path. The path package deals with
+	// different path formats on different systems, so we use that instead
+	// of just string-split.
+	dirPath, fileName := path.Split(frame.File)
+	var moduleName string
+	if dirPath != "" {
+		dirPath = dirPath[:len(dirPath)-1]
+		_, moduleName = path.Split(dirPath)
+	}
+	packageName, shortFunction := splitFunctionName(frame.Function)
+	return &callerInfo{
+		moduleAndFileName: moduleName + "/" + fileName,
+		fullPath:          frame.File,
+		packageName:       packageName,
+		functionName:      frame.Function,
+		shortFunction:     shortFunction,
+		line:              frame.Line,
+	}
+}
+
+// caller returns the (possibly cached) information about the function that
+// called into rlog. The skip argument has the same meaning as for
+// runtime.Caller, seen from the function calling caller. The decisions are
+// made with the filters f.
+func (l *logger) caller(skip int, f *filters) *callerInfo {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		// No caller information available. The filters are still applied,
+		// as if the message came from a file without name.
+		ci := &callerInfo{}
+		ci.applyFilters(f)
+		return ci
+	}
+
+	return l.callerForPC(pcs[0], f)
+}
+
+// callerForPC returns the (possibly cached) information about the call site
+// with the given program counter, as returned by runtime.Callers. The decisions
+// are made with the filters f.
+func (l *logger) callerForPC(pc uintptr, f *filters) *callerInfo {
+	l.callersMutex.RLock()
+	ci, ok := l.callers[pc]
+	l.callersMutex.RUnlock()
+	if ok && ci.generation == f.generation {
+		return ci
+	}
+
+	if ok {
+		// The filters changed since we looked at this call site. The file
+		// information is still good, only the decisions are re-evaluated.
+		refreshed := *ci
+		ci = &refreshed
+	} else {
+		// Only hand a copy to CallersFrames, so that pcs stays on the stack
+		// in the (common) cached case.
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		ci = newCallerInfo(frame)
+	}
+	ci.applyFilters(f)
+
+	l.callersMutex.Lock()
+	if l.callers == nil {
+		l.callers = make(map[uintptr]*callerInfo)
+	}
+	l.callers[pc] = ci
+	l.callersMutex.Unlock()
+	return ci
+}
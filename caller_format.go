@@ -0,0 +1,184 @@
+package rlog
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// callerField identifies a piece of the caller info which can be used in a
+// caller format.
+type callerField int
+
+const (
+	callerFieldLiteral callerField = iota
+	callerFieldPID
+	callerFieldPaddedPID
+	callerFieldGID
+	callerFieldFile
+	callerFieldBaseName
+	callerFieldPath
+	callerFieldPackage
+	callerFieldLine
+	callerFieldFunction
+	callerFieldShortFunction
+)
+
+// Translation from template placeholder to caller field.
+var callerFieldNames = map[string]callerField{
+	"pid":           callerFieldPID,
+	"gid":           callerFieldGID,
+	"file":          callerFieldFile,
+	"basename":      callerFieldBaseName,
+	"path":          callerFieldPath,
+	"package":       callerFieldPackage,
+	"line":          callerFieldLine,
+	"function":      callerFieldFunction,
+	"shortfunction": callerFieldShortFunction,
+}
+
+type callerFormatPart struct {
+	field   callerField
+	literal string
+}
+
+// CallerFormat describes how formatters render the caller info of an entry.
+// It is compiled once from the RLOG_CALLER_FORMAT setting, so rendering it
+// doesn't need to parse anything.
+type CallerFormat struct {
+	parts []callerFormatPart
+}
+
+// The predefined caller formats.
+var (
+	// LongCallerFormat is the process ID, the directory and name of the file,
+	// the line and the fully qualified function name. This is the default.
+	LongCallerFormat = mustParseCallerFormat("{paddedpid} {file}:{line} {function}")
+	// ShortCallerFormat is just the directory and name of the file and the
+	// line.
+	ShortCallerFormat = mustParseCallerFormat("{file}:{line}")
+	// PackageCallerFormat is the import path of the package, the name of the
+	// file and the line.
+	PackageCallerFormat = mustParseCallerFormat("{package}/{basename}:{line}")
+)
+
+// ParseCallerFormat compiles the value of the RLOG_CALLER_FORMAT setting. It
+// accepts the names of the predefined formats ("short", "long" or "package")
+// or a template in which the placeholders {pid}, {gid}, {file}, {basename},
+// {path}, {package}, {line}, {function} and {shortfunction} are replaced by
+// the corresponding parts of the caller info. An empty string selects the
+// long format.
+func ParseCallerFormat(s string) (*CallerFormat, error) {
+	switch strings.ToUpper(s) {
+	case "", "LONG":
+		return LongCallerFormat, nil
+	case "SHORT":
+		return ShortCallerFormat, nil
+	case "PACKAGE":
+		return PackageCallerFormat, nil
+	}
+	if !strings.Contains(s, "{") {
+		return nil, fmt.Errorf("caller format '%s' is unknown", s)
+	}
+	return parseCallerTemplate(s, false)
+}
+
+func mustParseCallerFormat(s string) *CallerFormat {
+	format, err := parseCallerTemplate(s, true)
+	if err != nil {
+		panic(err)
+	}
+	return format
+}
+
+// parseCallerTemplate splits a template into literals and placeholders. The
+// internal flag allows placeholders which are reserved for the predefined
+// formats.
+func parseCallerTemplate(s string, internal bool) (*CallerFormat, error) {
+	format := &CallerFormat{}
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			format.parts = append(format.parts, callerFormatPart{literal: s})
+			break
+		}
+		if start > 0 {
+			format.parts = append(format.parts, callerFormatPart{literal: s[:start]})
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in caller format '%s'", s)
+		}
+		name := s[start+1 : start+end]
+		field, ok := callerFieldNames[name]
+		if !ok && internal && name == "paddedpid" {
+			field, ok = callerFieldPaddedPID, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder '{%s}' in caller format", name)
+		}
+		format.parts = append(format.parts, callerFormatPart{field: field})
+		s = s[start+end+1:]
+	}
+	return format, nil
+}
+
+// Append renders the caller info according to this format and appends it to
+// the output.
+func (format *CallerFormat) Append(output []byte, info *EntryCallerInfo) []byte {
+	for _, part := range format.parts {
+		switch part.field {
+		case callerFieldLiteral:
+			output = append(output, part.literal...)
+		case callerFieldPID:
+			output = strconv.AppendInt(output, int64(info.PID), 10)
+		case callerFieldPaddedPID:
+			// Left aligned in a column of 5, as rlog always did.
+			l := len(output)
+			output = strconv.AppendInt(output, int64(info.PID), 10)
+			for i := len(output) - l; i < 5; i++ {
+				output = append(output, ' ')
+			}
+		case callerFieldGID:
+			output = strconv.AppendUint(output, info.GID, 10)
+		case callerFieldFile:
+			output = append(output, info.FileName...)
+		case callerFieldBaseName:
+			output = append(output, path.Base(info.FileName)...)
+		case callerFieldPath:
+			output = append(output, info.FullPath...)
+		case callerFieldPackage:
+			output = append(output, info.Package...)
+		case callerFieldLine:
+			output = strconv.AppendInt(output, int64(info.Line), 10)
+		case callerFieldFunction:
+			output = append(output, info.FunctionName...)
+		case callerFieldShortFunction:
+			output = append(output, info.ShortFunction...)
+		}
+	}
+	return output
+}
+
+// String renders the caller info according to this format.
+func (format *CallerFormat) String(info *EntryCallerInfo) string {
+	return string(format.Append(nil, info))
+}
+
+// splitFunctionName splits a fully qualified function name, as reported by
+// the runtime, into the import path of its package and the function name
+// relative to the package. For example
+// "github.com/lab259/rlog/v2.(*logger).Info" is split into
+// "github.com/lab259/rlog/v2" and "(*logger).Info".
+func splitFunctionName(name string) (string, string) {
+	// The package path ends at the first dot after the last slash. Dots in
+	// the last element of the import path are escaped by the linker as %2e.
+	lastSlash := strings.LastIndexByte(name, '/')
+	dot := strings.IndexByte(name[lastSlash+1:], '.')
+	if dot < 0 {
+		return "", name
+	}
+	dot += lastSlash + 1
+	return strings.Replace(name[:dot], "%2e", ".", -1), name[dot+1:]
+}
@@ -0,0 +1,75 @@
+package rlog
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CallerFormat", func() {
+	info := &EntryCallerInfo{
+		PID:           42,
+		GID:           7,
+		FileName:      "rlog/logger.go",
+		FullPath:      "/src/github.com/lab259/rlog/logger.go",
+		Package:       "github.com/lab259/rlog/v2",
+		Line:          123,
+		FunctionName:  "github.com/lab259/rlog/v2.(*logger).Info",
+		ShortFunction: "(*logger).Info",
+	}
+
+	It("should render the long format by default", func() {
+		format, err := ParseCallerFormat("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format.String(info)).To(Equal("42    rlog/logger.go:123 github.com/lab259/rlog/v2.(*logger).Info"))
+	})
+
+	It("should render the short format", func() {
+		format, err := ParseCallerFormat("short")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format.String(info)).To(Equal("rlog/logger.go:123"))
+	})
+
+	It("should render the package format", func() {
+		format, err := ParseCallerFormat("PACKAGE")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format.String(info)).To(Equal("github.com/lab259/rlog/v2/logger.go:123"))
+	})
+
+	It("should render a template", func() {
+		format, err := ParseCallerFormat("{basename}:{line} {shortfunction} ({pid}/{gid}) {path}")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format.String(info)).To(Equal("logger.go:123 (*logger).Info (42/7) /src/github.com/lab259/rlog/logger.go"))
+	})
+
+	It("should fail with an unknown format name", func() {
+		_, err := ParseCallerFormat("medium")
+		Expect(err).To(MatchError("caller format 'medium' is unknown"))
+	})
+
+	It("should fail with an unknown placeholder", func() {
+		_, err := ParseCallerFormat("{file}:{column}")
+		Expect(err).To(MatchError("unknown placeholder '{column}' in caller format"))
+
+		_, err = ParseCallerFormat("{paddedpid}")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail with an unterminated placeholder", func() {
+		_, err := ParseCallerFormat("{file}:{line")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should split function names", func() {
+		pkg, fn := splitFunctionName("github.com/lab259/rlog/v2.(*logger).Info")
+		Expect(pkg).To(Equal("github.com/lab259/rlog/v2"))
+		Expect(fn).To(Equal("(*logger).Info"))
+
+		pkg, fn = splitFunctionName("main.main.func1")
+		Expect(pkg).To(Equal("main"))
+		Expect(fn).To(Equal("main.func1"))
+
+		pkg, fn = splitFunctionName("gopkg.in/yaml%2ev2.Unmarshal")
+		Expect(pkg).To(Equal("gopkg.in/yaml.v2"))
+		Expect(fn).To(Equal("Unmarshal"))
+	})
+})
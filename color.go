@@ -0,0 +1,200 @@
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ColorMode tells when the default formatter colors its output.
+type ColorMode int
+
+const (
+	// ColorAuto colors the output of terminals. NO_COLOR disables and
+	// FORCE_COLOR enables the colors anyway, e.g. for CI systems which
+	// support ANSI escape codes.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output.
+	ColorAlways
+	// ColorNever doesn't color the output.
+	ColorNever
+)
+
+// ParseColorMode translates the RLOG_COLOR setting: auto (default), always or
+// never.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("color mode '%s' is unknown", s)
+}
+
+// enabled tells whether output written to file is colored. The file may be nil
+// if the output isn't a file.
+func (mode ColorMode) enabled(file *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	// See https://no-color.org and https://force-color.org.
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && !strings.EqualFold(force, "false")
+	}
+	return file != nil && terminal.IsTerminal(int(file.Fd()))
+}
+
+// ColorScheme holds the colors of the default formatter, as attributes of
+// github.com/fatih/color.
+type ColorScheme struct {
+	// Levels are the colors of the level names, per level.
+	Levels map[Level][]color.Attribute
+	// Key is the color of the field keys. When nil, the color of the level
+	// is used.
+	Key []color.Attribute
+	// Caller is the color of the caller info.
+	Caller []color.Attribute
+	// Time is the color of the timestamp.
+	Time []color.Attribute
+}
+
+// DefaultColorScheme returns the colors used when none are configured.
+func DefaultColorScheme() ColorScheme {
+	return ColorScheme{
+		Levels: map[Level][]color.Attribute{
+			levelCrit:  {color.BgRed, color.FgWhite},
+			levelErr:   {color.FgRed},
+			levelWarn:  {color.FgYellow},
+			levelInfo:  {color.FgCyan},
+			levelDebug: {color.FgMagenta},
+			levelTrace: {color.FgHiBlack},
+		},
+	}
+}
+
+// ParseColorScheme translates the RLOG_COLORS setting. It's a list of
+// name=color pairs, separated by commas, which override the colors of the
+// DefaultColorScheme. The names are the levels (critical, error, warn, info,
+// debug, trace), key, caller and time. A color combines attributes with "+",
+// e.g. bold+red or bg-red+white:
+//
+//   - black, red, green, yellow, blue, magenta, cyan and white, also prefixed
+//     by hi- for the bright variant, by bg- for the background and by bg-hi-
+//     for the bright background.
+//   - bold, faint, italic, underline, blink and reverse.
+//   - SGR codes as numbers, e.g. 1 for bold.
+//   - none, for no color.
+func ParseColorScheme(s string) (ColorScheme, error) {
+	scheme := DefaultColorScheme()
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 {
+			return scheme, fmt.Errorf("color '%s' is malformed, expected name=color", pair)
+		}
+		name := strings.ToLower(strings.TrimSpace(tokens[0]))
+		attributes, err := parseColorAttributes(tokens[1])
+		if err != nil {
+			return scheme, err
+		}
+		switch name {
+		case "key":
+			scheme.Key = attributes
+		case "caller":
+			scheme.Caller = attributes
+		case "time":
+			scheme.Time = attributes
+		default:
+			level, ok := levelNumbers[strings.ToUpper(name)]
+			if !ok || level == levelNone {
+				return scheme, fmt.Errorf("color name '%s' is unknown", name)
+			}
+			scheme.Levels[level] = attributes
+		}
+	}
+	return scheme, nil
+}
+
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+var colorStyles = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+	"blink":     color.BlinkSlow,
+	"reverse":   color.ReverseVideo,
+}
+
+// parseColorAttributes translates a color of the RLOG_COLORS setting. none
+// results in an empty, but not nil, list.
+func parseColorAttributes(s string) ([]color.Attribute, error) {
+	attributes := []color.Attribute{}
+	for _, name := range strings.Split(s, "+") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "none" {
+			continue
+		}
+		if attribute, ok := colorStyles[name]; ok {
+			attributes = append(attributes, attribute)
+			continue
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 0 && n <= 255 {
+			attributes = append(attributes, color.Attribute(n))
+			continue
+		}
+		// The bright colors are 60 above the normal ones, the backgrounds 10.
+		offset := color.Attribute(0)
+		if strings.HasPrefix(name, "bg-") {
+			name = name[len("bg-"):]
+			offset += color.BgBlack - color.FgBlack
+		}
+		if strings.HasPrefix(name, "hi-") {
+			name = name[len("hi-"):]
+			offset += color.FgHiBlack - color.FgBlack
+		}
+		attribute, ok := colorNames[name]
+		if !ok {
+			return nil, fmt.Errorf("color '%s' is unknown", s)
+		}
+		attributes = append(attributes, attribute+offset)
+	}
+	return attributes, nil
+}
+
+// colorFunc creates a Color of the attributes, or fmt.Sprint if the output
+// isn't colored.
+func colorFunc(enabled bool, attributes []color.Attribute) Color {
+	if !enabled || len(attributes) == 0 {
+		return fmt.Sprint
+	}
+	c := color.New(attributes...)
+	c.EnableColor()
+	return c.Sprint
+}
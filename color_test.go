@@ -0,0 +1,141 @@
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/fatih/color"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Colors", func() {
+	// withColorEnv runs callback with NO_COLOR and FORCE_COLOR set as given.
+	withColorEnv := func(noColor, forceColor string, callback func()) {
+		OverrideEnv(map[string]string{
+			"NO_COLOR":    noColor,
+			"FORCE_COLOR": forceColor,
+		}, func() error {
+			callback()
+			return nil
+		})
+	}
+
+	Describe("ColorMode", func() {
+		It("should parse the modes", func() {
+			Expect(ParseColorMode("")).To(Equal(ColorAuto))
+			Expect(ParseColorMode("Always")).To(Equal(ColorAlways))
+			Expect(ParseColorMode("never")).To(Equal(ColorNever))
+			_, err := ParseColorMode("sometimes")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should not color files which aren't terminals in auto mode", func() {
+			withColorEnv("", "", func() {
+				Expect(ColorAuto.enabled(nil)).To(BeFalse())
+				Expect(ColorAlways.enabled(nil)).To(BeTrue())
+			})
+		})
+
+		It("should honour FORCE_COLOR in auto mode", func() {
+			withColorEnv("", "1", func() {
+				Expect(ColorAuto.enabled(nil)).To(BeTrue())
+				Expect(ColorNever.enabled(nil)).To(BeFalse())
+			})
+			withColorEnv("", "0", func() {
+				Expect(ColorAuto.enabled(nil)).To(BeFalse())
+			})
+		})
+
+		It("should honour NO_COLOR in auto mode", func() {
+			withColorEnv("1", "1", func() {
+				Expect(ColorAuto.enabled(nil)).To(BeFalse())
+				Expect(ColorAlways.enabled(nil)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("ColorScheme", func() {
+		It("should override the default colors", func() {
+			scheme, err := ParseColorScheme("error=bold+red, KEY=blue, caller=hi-black, time=bg-hi-white+black, info=none")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scheme.Levels[LevelError]).To(Equal([]color.Attribute{color.Bold, color.FgRed}))
+			Expect(scheme.Levels[LevelWarn]).To(Equal([]color.Attribute{color.FgYellow}))
+			Expect(scheme.Levels[LevelInfo]).To(BeEmpty())
+			Expect(scheme.Key).To(Equal([]color.Attribute{color.FgBlue}))
+			Expect(scheme.Caller).To(Equal([]color.Attribute{color.FgHiBlack}))
+			Expect(scheme.Time).To(Equal([]color.Attribute{color.BgHiWhite, color.FgBlack}))
+		})
+
+		It("should accept SGR codes", func() {
+			scheme, err := ParseColorScheme("debug=4+35")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scheme.Levels[LevelDebug]).To(Equal([]color.Attribute{color.Underline, color.FgMagenta}))
+		})
+
+		It("should reject unknown names and colors", func() {
+			_, err := ParseColorScheme("fatal=red")
+			Expect(err).To(MatchError(ContainSubstring("fatal")))
+			_, err = ParseColorScheme("error=pink")
+			Expect(err).To(MatchError(ContainSubstring("pink")))
+			_, err = ParseColorScheme("error")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("default formatter", func() {
+		It("should color the output always if configured", func() {
+			logger, buff := newBufferLogger(Config{
+				Color:          "always",
+				Colors:         "key=blue,caller=green,time=yellow",
+				ShowCallerInfo: true,
+			})
+			logger.WithField("user", "joe").Warn("colored")
+
+			Expect(buff.String()).To(ContainSubstring("\x1b[33mWARN\x1b[0m"))
+			Expect(buff.String()).To(ContainSubstring("\x1b[34muser\x1b[0m=joe"))
+			Expect(buff.String()).To(MatchRegexp(`^\x1b\[33m\S+\x1b\[0m `))
+			Expect(buff.String()).To(ContainSubstring(" [\x1b[32m"))
+		})
+
+		It("should not color the output if configured", func() {
+			withColorEnv("", "1", func() {
+				logger, buff := newBufferLogger(Config{Color: "never"})
+				logger.WithField("user", "joe").Warn("plain")
+				Expect(buff.String()).ToNot(ContainSubstring("\x1b["))
+			})
+		})
+
+		It("should decide about the colors again when the output changes", func() {
+			var logger *logger
+			withColorEnv("", "1", func() {
+				var buff *bytes.Buffer
+				logger, buff = newBufferLogger(Config{})
+				logger.Warn("colored")
+				Expect(buff.String()).To(ContainSubstring("\x1b[33mWARN\x1b[0m"))
+			})
+
+			withColorEnv("", "", func() {
+				file, err := ioutil.TempFile("", "rlog-color")
+				Expect(err).ToNot(HaveOccurred())
+				defer os.Remove(file.Name())
+				defer file.Close()
+				logger.SetOutput(file)
+				logger.Warn("plain")
+
+				content, err := ioutil.ReadFile(file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring("WARN"))
+				Expect(string(content)).ToNot(ContainSubstring("\x1b["))
+			})
+		})
+
+		It("should reject invalid settings", func() {
+			_, err := NewLogger(Config{Color: "sometimes"})
+			Expect(err).To(HaveOccurred())
+			_, err = NewLogger(Config{Colors: "error=pink"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
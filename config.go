@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -23,18 +24,76 @@ type Config struct {
 	TraceLevel string
 	// The time format spec for date/time stamps in output
 	logTimeFormat string
+	// TimeZone is the time zone of the date/time stamps: UTC (default),
+	// Local or an IANA name like Europe/Berlin.
+	TimeZone string
 	// Name of logfile
 	LogFile string
 	// Name of config file
 	confFile string
-	// Name of logstream: stdout, stderr or NONE
+	// Name of logstream: stdout, stderr, syslog, journald, gelf, otlp or NONE, or the URL
+	// of a network sink, like tcp://localhost:5170
 	LogStream string
+	// SyslogAddress is where the syslog daemon listens, as
+	// <network>://<address> (e.g. udp://localhost:514 or unix:///dev/log).
+	// Empty means the local daemon.
+	SyslogAddress string
+	// SyslogFormat is RFC5424 (default) or RFC3164.
+	SyslogFormat string
+	// SyslogFacility is the name of the syslog facility, e.g. local0.
+	SyslogFacility string
+	// SyslogAppName identifies the program. Defaults to the executable name.
+	SyslogAppName string
+	// SyslogStructuredDataID is the SD-ID under which the fields of an entry
+	// are sent as structured data.
+	SyslogStructuredDataID string
+	// GELFAddress is the host:port of the Graylog GELF UDP input.
+	GELFAddress string
+	// GELFCompression is none (default), gzip or zlib.
+	GELFCompression string
+	// OTLPEndpoint is the URL of the OTLP/HTTP logs endpoint.
+	OTLPEndpoint string
+	// OTLPResource holds resource attributes as key=value pairs, separated
+	// by commas.
+	OTLPResource string
 	// Flag to determine if date/time is logged at all
 	LogNoTime bool
 	// CallerInfo is a flag to determine if caller info is logged
 	ShowCallerInfo bool
+	// CallerFormat determines how the caller info is rendered: short, long,
+	// package or a template. See ParseCallerFormat.
+	CallerFormat string
 	// Flag to determine if goroute ID shows in caller info
 	ShowGoroutineID bool
+	// StacktraceLevel is the log level from which on a stack trace is
+	// attached to the entries (e.g. ERROR). Empty disables stack traces.
+	StacktraceLevel string
+	// RedactKeys holds glob patterns of field keys whose values are redacted,
+	// separated by commas, e.g. password,*token*. They are case-insensitive.
+	RedactKeys string
+	// RedactPatterns names the predefined patterns redacted in the messages
+	// and field values, separated by commas: email, jwt and credit_card.
+	RedactPatterns string
+	// RedactRegex is a regular expression redacted in the messages and field
+	// values.
+	RedactRegex string
+	// RedactStrategy is mask (default), hash or drop.
+	RedactStrategy string
+	// DuplicateKeys is keep (default), to log all the fields with the same
+	// key, or last, to log only the last one.
+	DuplicateKeys string
+	// Color tells when the default formatter colors its output: auto
+	// (default), always or never. See ParseColorMode.
+	Color string
+	// Colors overrides the colors of the default formatter, e.g.
+	// error=bold+red,key=blue. See ParseColorScheme.
+	Colors string
+	// Template determines the layout of the lines of the default formatter,
+	// e.g. {time} {level:-8} {msg:-60} {fields}. See ParseLineTemplate.
+	Template string
+	// Multiline tells how messages with line breaks are written: raw
+	// (default), escape, indent or split. See ParseMultilineMode.
+	Multiline string
 	// Interval in seconds for checking config file
 	confCheckInterv string
 }
@@ -48,17 +107,38 @@ func (config *Config) LoadFromEnv(prefix string) {
 	}
 	// Read the initial configuration from the environment variables
 	*config = Config{
-		Formatter:       os.Getenv(fmt.Sprintf("%s_FORMATTER", prefix)),
-		LogLevel:        os.Getenv(fmt.Sprintf("%s_LOG_LEVEL", prefix)),
-		TraceLevel:      os.Getenv(fmt.Sprintf("%s_TRACE_LEVEL", prefix)),
-		logTimeFormat:   os.Getenv(fmt.Sprintf("%s_TIME_FORMAT", prefix)),
-		LogFile:         os.Getenv(fmt.Sprintf("%s_LOG_FILE", prefix)),
-		confFile:        os.Getenv(fmt.Sprintf("%s_CONF_FILE", prefix)),
-		LogStream:       strings.ToUpper(os.Getenv(fmt.Sprintf("%s_LOG_STREAM", prefix))),
-		LogNoTime:       isTrueBoolString(os.Getenv(fmt.Sprintf("%s_LOG_NOTIME", prefix))),
-		ShowCallerInfo:  isTrueBoolString(os.Getenv(fmt.Sprintf("%s_CALLER_INFO", prefix))),
-		ShowGoroutineID: isTrueBoolString(os.Getenv(fmt.Sprintf("%s_GOROUTINE_ID", prefix))),
-		confCheckInterv: os.Getenv(fmt.Sprintf("%s_CONF_CHECK_INTERVAL", prefix)),
+		Formatter:              os.Getenv(fmt.Sprintf("%s_FORMATTER", prefix)),
+		LogLevel:               os.Getenv(fmt.Sprintf("%s_LOG_LEVEL", prefix)),
+		TraceLevel:             os.Getenv(fmt.Sprintf("%s_TRACE_LEVEL", prefix)),
+		logTimeFormat:          os.Getenv(fmt.Sprintf("%s_TIME_FORMAT", prefix)),
+		TimeZone:               os.Getenv(fmt.Sprintf("%s_TIME_ZONE", prefix)),
+		LogFile:                os.Getenv(fmt.Sprintf("%s_LOG_FILE", prefix)),
+		confFile:               os.Getenv(fmt.Sprintf("%s_CONF_FILE", prefix)),
+		LogStream:              normalizeLogStream(os.Getenv(fmt.Sprintf("%s_LOG_STREAM", prefix))),
+		SyslogAddress:          os.Getenv(fmt.Sprintf("%s_SYSLOG_ADDRESS", prefix)),
+		SyslogFormat:           os.Getenv(fmt.Sprintf("%s_SYSLOG_FORMAT", prefix)),
+		SyslogFacility:         os.Getenv(fmt.Sprintf("%s_SYSLOG_FACILITY", prefix)),
+		SyslogAppName:          os.Getenv(fmt.Sprintf("%s_SYSLOG_APP_NAME", prefix)),
+		SyslogStructuredDataID: os.Getenv(fmt.Sprintf("%s_SYSLOG_SD_ID", prefix)),
+		GELFAddress:            os.Getenv(fmt.Sprintf("%s_GELF_ADDRESS", prefix)),
+		GELFCompression:        os.Getenv(fmt.Sprintf("%s_GELF_COMPRESSION", prefix)),
+		OTLPEndpoint:           os.Getenv(fmt.Sprintf("%s_OTLP_ENDPOINT", prefix)),
+		OTLPResource:           os.Getenv(fmt.Sprintf("%s_OTLP_RESOURCE", prefix)),
+		LogNoTime:              isTrueBoolString(os.Getenv(fmt.Sprintf("%s_LOG_NOTIME", prefix))),
+		ShowCallerInfo:         isTrueBoolString(os.Getenv(fmt.Sprintf("%s_CALLER_INFO", prefix))),
+		CallerFormat:           os.Getenv(fmt.Sprintf("%s_CALLER_FORMAT", prefix)),
+		ShowGoroutineID:        isTrueBoolString(os.Getenv(fmt.Sprintf("%s_GOROUTINE_ID", prefix))),
+		StacktraceLevel:        os.Getenv(fmt.Sprintf("%s_STACKTRACE_LEVEL", prefix)),
+		RedactKeys:             os.Getenv(fmt.Sprintf("%s_REDACT_KEYS", prefix)),
+		RedactPatterns:         os.Getenv(fmt.Sprintf("%s_REDACT_PATTERNS", prefix)),
+		RedactRegex:            os.Getenv(fmt.Sprintf("%s_REDACT_REGEX", prefix)),
+		RedactStrategy:         os.Getenv(fmt.Sprintf("%s_REDACT_STRATEGY", prefix)),
+		DuplicateKeys:          os.Getenv(fmt.Sprintf("%s_DUPLICATE_KEYS", prefix)),
+		Color:                  os.Getenv(fmt.Sprintf("%s_COLOR", prefix)),
+		Colors:                 os.Getenv(fmt.Sprintf("%s_COLORS", prefix)),
+		Template:               os.Getenv(fmt.Sprintf("%s_FORMAT_TEMPLATE", prefix)),
+		Multiline:              os.Getenv(fmt.Sprintf("%s_MULTILINE", prefix)),
+		confCheckInterv:        os.Getenv(fmt.Sprintf("%s_CONF_CHECK_INTERVAL", prefix)),
 	}
 }
 
@@ -87,17 +167,58 @@ func (config *Config) loadFromStream(stream io.Reader) error {
 			config.TraceLevel = val
 		case "RLOG_TIME_FORMAT":
 			config.logTimeFormat = val
+		case "RLOG_TIME_ZONE":
+			config.TimeZone = val
 		case "RLOG_LOG_FILE":
 			config.LogFile = val
 		case "RLOG_LOG_STREAM":
-			val = strings.ToUpper(val)
-			config.LogStream = val
+			config.LogStream = normalizeLogStream(val)
+		case "RLOG_SYSLOG_ADDRESS":
+			config.SyslogAddress = val
+		case "RLOG_SYSLOG_FORMAT":
+			config.SyslogFormat = val
+		case "RLOG_SYSLOG_FACILITY":
+			config.SyslogFacility = val
+		case "RLOG_SYSLOG_APP_NAME":
+			config.SyslogAppName = val
+		case "RLOG_SYSLOG_SD_ID":
+			config.SyslogStructuredDataID = val
+		case "RLOG_GELF_ADDRESS":
+			config.GELFAddress = val
+		case "RLOG_GELF_COMPRESSION":
+			config.GELFCompression = val
+		case "RLOG_OTLP_ENDPOINT":
+			config.OTLPEndpoint = val
+		case "RLOG_OTLP_RESOURCE":
+			config.OTLPResource = val
 		case "RLOG_LOG_NOTIME":
 			config.LogNoTime = isTrueBoolString(val)
 		case "RLOG_CALLER_INFO":
 			config.ShowCallerInfo = isTrueBoolString(val)
+		case "RLOG_CALLER_FORMAT":
+			config.CallerFormat = val
 		case "RLOG_GOROUTINE_ID":
 			config.ShowGoroutineID = isTrueBoolString(val)
+		case "RLOG_STACKTRACE_LEVEL":
+			config.StacktraceLevel = val
+		case "RLOG_REDACT_KEYS":
+			config.RedactKeys = val
+		case "RLOG_REDACT_PATTERNS":
+			config.RedactPatterns = val
+		case "RLOG_REDACT_REGEX":
+			config.RedactRegex = val
+		case "RLOG_REDACT_STRATEGY":
+			config.RedactStrategy = val
+		case "RLOG_DUPLICATE_KEYS":
+			config.DuplicateKeys = val
+		case "RLOG_COLOR":
+			config.Color = val
+		case "RLOG_COLORS":
+			config.Colors = val
+		case "RLOG_FORMAT_TEMPLATE":
+			config.Template = val
+		case "RLOG_MULTILINE":
+			config.Multiline = val
 		default:
 			rlogIssue("Unknown or illegal setting name in config file %d. Ignored.", lineN)
 		}
@@ -105,7 +226,11 @@ func (config *Config) loadFromStream(stream io.Reader) error {
 	return nil
 }
 
-// LoadFromFile load the configuration from a file.
+// LoadFromFile load the configuration from a file. Files ending in .yaml or
+// .yml are loaded with LoadFromYAML, files ending in .json with LoadFromJSON,
+// files ending in .toml with LoadFromTOML, and all others as KEY=VALUE lines.
+// Errors in YAML, JSON and TOML files are returned as *ConfigError, with the
+// name of the file.
 func (config *Config) LoadFromFile(fileName string) error {
 	// Scan over the config file, line by line
 	file, err := os.Open(fileName)
@@ -117,7 +242,20 @@ func (config *Config) LoadFromFile(fileName string) error {
 	}
 	defer file.Close()
 
-	return config.loadFromStream(file)
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		err = config.LoadFromYAML(file)
+	case ".json":
+		err = config.LoadFromJSON(file)
+	case ".toml":
+		err = config.LoadFromTOML(file)
+	default:
+		return config.loadFromStream(file)
+	}
+	if configErr, ok := err.(*ConfigError); ok {
+		configErr.File = fileName
+	}
+	return err
 }
 
 // We keep a copy of what was supplied via environment variables, since we will
@@ -132,3 +270,12 @@ func configFromEnv() Config {
 	config.LoadFromEnv("RLOG")
 	return config
 }
+
+// normalizeLogStream upper cases the name of a log stream. URLs are kept as
+// they are, since paths are case sensitive.
+func normalizeLogStream(s string) string {
+	if strings.Contains(s, "://") {
+		return s
+	}
+	return strings.ToUpper(s)
+}
@@ -0,0 +1,404 @@
+package rlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	toml "github.com/pelletier/go-toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConfigError is an error in a YAML, JSON or TOML configuration, at the position of
+// the offending setting.
+type ConfigError struct {
+	// File is the name of the configuration file, if it was loaded from one.
+	File string
+	// Line and Column start at 1. The column is 0 if it isn't known, which
+	// is the case for syntax errors of YAML files.
+	Line   int
+	Column int
+	// Message describes the error.
+	Message string
+}
+
+func (err *ConfigError) Error() string {
+	position := fmt.Sprintf("line %d", err.Line)
+	if err.Column > 0 {
+		position += fmt.Sprintf(", column %d", err.Column)
+	}
+	if err.File != "" {
+		position = err.File + ":" + strconv.Itoa(err.Line)
+		if err.Column > 0 {
+			position += ":" + strconv.Itoa(err.Column)
+		}
+	}
+	return position + ": " + err.Message
+}
+
+// configNode is a value of a YAML, JSON or TOML configuration, with the position it
+// starts at.
+type configNode struct {
+	line, column int
+	// value is nil, a string, bool or float64, a []*configNode for sequences
+	// or a []configEntry for mappings.
+	value interface{}
+}
+
+// configEntry is a key of a mapping, with its position and value.
+type configEntry struct {
+	key          string
+	line, column int
+	value        *configNode
+}
+
+func (node *configNode) errorf(format string, a ...interface{}) error {
+	return &ConfigError{Line: node.line, Column: node.column, Message: fmt.Sprintf(format, a...)}
+}
+
+func (entry *configEntry) errorf(format string, a ...interface{}) error {
+	return &ConfigError{Line: entry.line, Column: entry.column, Message: fmt.Sprintf(format, a...)}
+}
+
+// LoadFromYAML loads the configuration from a YAML document. Only the
+// settings present in the document are changed. See the README for the
+// schema. Errors in the document are returned as *ConfigError.
+func (config *Config) LoadFromYAML(stream io.Reader) error {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	root, err := parseYAMLConfig(data)
+	if err != nil {
+		return err
+	}
+	return config.loadFromNode(root)
+}
+
+// LoadFromJSON loads the configuration from a JSON document, which follows
+// the same schema as LoadFromYAML.
+func (config *Config) LoadFromJSON(stream io.Reader) error {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	root, err := parseJSONConfig(data)
+	if err != nil {
+		return err
+	}
+	return config.loadFromNode(root)
+}
+
+// LoadFromTOML loads the configuration from a TOML document, which follows
+// the same schema as LoadFromYAML.
+func (config *Config) LoadFromTOML(stream io.Reader) error {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	root, err := parseTOMLConfig(data)
+	if err != nil {
+		return err
+	}
+	return config.loadFromNode(root)
+}
+
+// yamlLinePattern matches the line yaml.v3 prefixes its syntax errors with.
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): `)
+
+// parseYAMLConfig parses a YAML document. The nodes of yaml.v3 tell where the
+// values are.
+func parseYAMLConfig(data []byte) (*configNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		line := 1
+		if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = message[len(match[0]):]
+		}
+		return nil, &ConfigError{Line: line, Message: message}
+	}
+	if len(doc.Content) == 0 {
+		// The document is empty, or only holds comments.
+		return &configNode{line: 1, column: 1, value: []configEntry{}}, nil
+	}
+	return yamlNode(doc.Content[0], nil)
+}
+
+// yamlNode converts a node of yaml.v3. The aliases being expanded are kept in
+// expanding, so that an alias which refers to itself fails.
+func yamlNode(n *yaml.Node, expanding map[*yaml.Node]bool) (*configNode, error) {
+	node := &configNode{line: n.Line, column: n.Column}
+	if n.Kind == yaml.AliasNode {
+		if expanding[n] {
+			return nil, node.errorf("alias '%s' refers to itself", n.Value)
+		}
+		if expanding == nil {
+			expanding = make(map[*yaml.Node]bool)
+		}
+		expanding[n] = true
+		defer delete(expanding, n)
+		n = n.Alias
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		entries := make([]configEntry, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			value, err := yamlNode(n.Content[i+1], expanding)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, configEntry{key: key.Value, line: key.Line, column: key.Column, value: value})
+		}
+		node.value = entries
+	case yaml.SequenceNode:
+		items := make([]*configNode, len(n.Content))
+		for i, item := range n.Content {
+			var err error
+			if items[i], err = yamlNode(item, expanding); err != nil {
+				return nil, err
+			}
+		}
+		node.value = items
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := n.Decode(&value); err != nil {
+			return nil, node.errorf("%s", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+		switch value := value.(type) {
+		case int:
+			node.value = float64(value)
+		case int64:
+			node.value = float64(value)
+		case uint64:
+			node.value = float64(value)
+		case float64, string, bool, nil:
+			node.value = value
+		default:
+			node.value = n.Value
+		}
+	}
+	return node, nil
+}
+
+// parseJSONConfig parses a JSON document. The tokens are read with a
+// json.Decoder, whose input offset tells where they start.
+func parseJSONConfig(data []byte) (*configNode, error) {
+	parser := &jsonConfigParser{
+		data:    data,
+		decoder: json.NewDecoder(bytes.NewReader(data)),
+		text:    textPosition{data: data, line: 1, column: 1},
+	}
+	root, err := parser.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parser.token(); err != io.EOF {
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ConfigError{Line: parser.line, Column: parser.column, Message: "invalid character after top-level value"}
+	}
+	return root, nil
+}
+
+// textPosition finds the line and column of byte offsets in data. As the
+// offsets mostly grow, it goes on from the last one, so that the document is
+// scanned once.
+type textPosition struct {
+	data                 []byte
+	offset, line, column int
+}
+
+// at returns the line and column of the byte offset.
+func (t *textPosition) at(offset int) (int, int) {
+	if offset > len(t.data) {
+		offset = len(t.data)
+	}
+	if offset < t.offset {
+		t.offset, t.line, t.column = 0, 1, 1
+	}
+	for t.offset < offset {
+		r, size := utf8.DecodeRune(t.data[t.offset:])
+		if r == '\n' {
+			t.line++
+			t.column = 1
+		} else {
+			t.column++
+		}
+		t.offset += size
+	}
+	return t.line, t.column
+}
+
+// jsonConfigParser converts the tokens of a JSON document to nodes.
+type jsonConfigParser struct {
+	data    []byte
+	decoder *json.Decoder
+	text    textPosition
+	// line and column are the position of the last token.
+	line, column int
+}
+
+// token reads the next token and records its position. The offset of the
+// decoder is after the previous token, so the token starts after the spaces
+// and separators which follow it.
+func (p *jsonConfigParser) token() (json.Token, error) {
+	start := int(p.decoder.InputOffset())
+	for start < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[start]) >= 0 {
+		start++
+	}
+	p.line, p.column = p.text.at(start)
+	token, err := p.decoder.Token()
+	switch err := err.(type) {
+	case nil:
+		return token, nil
+	case *json.SyntaxError:
+		// The decoder blames the comma of a trailing comma, while the
+		// scanner of json.Unmarshal finds the character after it.
+		var value interface{}
+		if syntaxErr, ok := json.Unmarshal(p.data, &value).(*json.SyntaxError); ok {
+			err = syntaxErr
+		}
+		// The offset is after the offending character.
+		line, column := p.text.at(int(err.Offset) - 1)
+		return nil, &ConfigError{Line: line, Column: column, Message: err.Error()}
+	}
+	if err == io.ErrUnexpectedEOF || (err == io.EOF && p.decoder.More()) {
+		line, column := p.text.at(len(p.data) - 1)
+		return nil, &ConfigError{Line: line, Column: column, Message: "unexpected end of JSON input"}
+	}
+	return nil, err
+}
+
+// value reads the value starting at the next token.
+func (p *jsonConfigParser) value() (*configNode, error) {
+	token, err := p.token()
+	if err == io.EOF {
+		line, column := p.text.at(len(p.data))
+		return nil, &ConfigError{Line: line, Column: column, Message: "unexpected end of JSON input"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	node := &configNode{line: p.line, column: p.column}
+	switch token {
+	case json.Delim('{'):
+		entries := []configEntry{}
+		for p.decoder.More() {
+			key, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			entry := configEntry{key: key.(string), line: p.line, column: p.column}
+			if entry.value, err = p.value(); err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		if _, err := p.token(); err != nil {
+			return nil, err
+		}
+		node.value = entries
+	case json.Delim('['):
+		items := []*configNode{}
+		for p.decoder.More() {
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if _, err := p.token(); err != nil {
+			return nil, err
+		}
+		node.value = items
+	default:
+		node.value = token
+	}
+	return node, nil
+}
+
+// tomlPositionPattern matches the position go-toml prefixes its errors with.
+var tomlPositionPattern = regexp.MustCompile(`^\((\d+), (\d+)\): `)
+
+// parseTOMLConfig parses a TOML document. go-toml knows where the keys are,
+// but neither the order of the keys nor the position of the items of arrays,
+// so the keys are sorted by their position and the items are placed at their
+// key.
+func parseTOMLConfig(data []byte) (*configNode, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		message := err.Error()
+		line, column := 1, 0
+		if match := tomlPositionPattern.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			column, _ = strconv.Atoi(match[2])
+			message = message[len(match[0]):]
+		}
+		return nil, &ConfigError{Line: line, Column: column, Message: message}
+	}
+	return tomlNode(tree, 1, 1), nil
+}
+
+// tomlNode converts a value of go-toml, which is at the given position.
+func tomlNode(value interface{}, line, column int) *configNode {
+	node := &configNode{line: line, column: column}
+	switch value := value.(type) {
+	case *toml.Tree:
+		entries := make([]configEntry, 0, len(value.Keys()))
+		for _, key := range value.Keys() {
+			entry := configEntry{key: key, line: line, column: column}
+			// The keys of inline tables have no position.
+			if position := value.GetPositionPath([]string{key}); !position.Invalid() {
+				entry.line, entry.column = position.Line, position.Col
+			}
+			entry.value = tomlNode(value.GetPath([]string{key}), entry.line, entry.column)
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if a.line != b.line {
+				return a.line < b.line
+			}
+			if a.column != b.column {
+				return a.column < b.column
+			}
+			return a.key < b.key
+		})
+		node.value = entries
+	case []*toml.Tree:
+		items := make([]*configNode, len(value))
+		for i, tree := range value {
+			itemLine, itemColumn := line, column
+			if position := tree.Position(); !position.Invalid() {
+				itemLine, itemColumn = position.Line, position.Col
+			}
+			items[i] = tomlNode(tree, itemLine, itemColumn)
+		}
+		node.value = items
+	case []interface{}:
+		items := make([]*configNode, len(value))
+		for i, item := range value {
+			items[i] = tomlNode(item, line, column)
+		}
+		node.value = items
+	case int64:
+		node.value = float64(value)
+	case float64, string, bool, nil:
+		node.value = value
+	default:
+		// Dates and times.
+		node.value = fmt.Sprint(value)
+	}
+	return node
+}
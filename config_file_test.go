@@ -0,0 +1,318 @@
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config files", func() {
+	const yamlConfig = `# Example configuration
+levels:
+  log: warn
+  trace: 2
+  stacktrace: ERROR
+filters:
+  log:
+    server.go: DEBUG
+    "db/*": error   # quoted pattern
+  trace:
+    db/query.go: 5
+output:
+  stream: syslog
+  file: /var/log/app.log
+  syslog:
+    address: udp://localhost:514
+    format: rfc3164
+    facility: local0
+    app_name: app
+    sd_id: rlog@32473
+  gelf:
+    address: graylog:12201
+    compression: gzip
+  otlp:
+    endpoint: http://collector:4318/v1/logs
+    resource:
+      service.name: app
+      deployment.environment: prod
+format:
+  formatter: json
+  time:
+    format: unixmilli
+    zone: Europe/Berlin
+    disabled: false
+  caller:
+    enabled: true
+    format: short
+    goroutine_id: yes
+  color: never
+  colors:
+    error: bold+red
+    key: blue
+  template: "{time} {level:-8} {msg}"
+  multiline: indent
+  duplicate_keys: last
+redaction:
+  keys:
+  - password
+  - "*token*"
+  patterns: [email, jwt]
+  regex: secret-[0-9]+
+  strategy: hash
+`
+
+	const jsonConfig = `{
+  "levels": {"log": "warn", "trace": 2},
+  "filters": {"log": {"server.go": "DEBUG", "db/*": "error"}},
+  "output": {"stream": "stdout", "otlp": {"resource": {"service.name": "app"}}},
+  "format": {"formatter": "text", "time": {"disabled": true}, "caller": {"enabled": true}},
+  "redaction": {"keys": ["password", "*token*"], "strategy": "drop"}
+}`
+
+	const tomlConfig = `[levels]
+log = "warn"
+trace = 2
+
+[filters.log]
+"server.go" = "DEBUG"
+"db/*" = "error"
+
+[output]
+stream = "stdout"
+otlp = {resource = {"service.name" = "app"}}
+
+[format]
+formatter = "text"
+time.disabled = true
+caller = {enabled = true}
+
+[redaction]
+keys = ["password", "*token*"]
+strategy = "drop"
+`
+
+	// expectError checks that err is a *ConfigError at the position with a
+	// message containing the substring.
+	expectError := func(err error, line, column int, substring string) {
+		ExpectWithOffset(1, err).To(HaveOccurred())
+		configErr, ok := err.(*ConfigError)
+		ExpectWithOffset(1, ok).To(BeTrue(), err.Error())
+		ExpectWithOffset(1, configErr.Message).To(ContainSubstring(substring))
+		ExpectWithOffset(1, []int{configErr.Line, configErr.Column}).To(Equal([]int{line, column}), err.Error())
+	}
+
+	It("should load all the settings from YAML", func() {
+		var config Config
+		Expect(config.LoadFromYAML(strings.NewReader(yamlConfig))).To(Succeed())
+		Expect(config).To(Equal(Config{
+			LogLevel:               "server.go=DEBUG,db/*=error,warn",
+			TraceLevel:             "db/query.go=5,2",
+			StacktraceLevel:        "ERROR",
+			LogStream:              "SYSLOG",
+			LogFile:                "/var/log/app.log",
+			SyslogAddress:          "udp://localhost:514",
+			SyslogFormat:           "rfc3164",
+			SyslogFacility:         "local0",
+			SyslogAppName:          "app",
+			SyslogStructuredDataID: "rlog@32473",
+			GELFAddress:            "graylog:12201",
+			GELFCompression:        "gzip",
+			OTLPEndpoint:           "http://collector:4318/v1/logs",
+			OTLPResource:           "service.name=app,deployment.environment=prod",
+			Formatter:              "json",
+			logTimeFormat:          "unixmilli",
+			TimeZone:               "Europe/Berlin",
+			ShowCallerInfo:         true,
+			CallerFormat:           "short",
+			ShowGoroutineID:        true,
+			Color:                  "never",
+			Colors:                 "error=bold+red,key=blue",
+			Template:               "{time} {level:-8} {msg}",
+			Multiline:              "indent",
+			DuplicateKeys:          "last",
+			RedactKeys:             "password,*token*",
+			RedactPatterns:         "email,jwt",
+			RedactRegex:            "secret-[0-9]+",
+			RedactStrategy:         "hash",
+		}))
+	})
+
+	It("should load the settings from JSON", func() {
+		var config Config
+		Expect(config.LoadFromJSON(strings.NewReader(jsonConfig))).To(Succeed())
+		Expect(config).To(Equal(Config{
+			LogLevel:       "server.go=DEBUG,db/*=error,warn",
+			TraceLevel:     "2",
+			LogStream:      "STDOUT",
+			OTLPResource:   "service.name=app",
+			Formatter:      "text",
+			LogNoTime:      true,
+			ShowCallerInfo: true,
+			RedactKeys:     "password,*token*",
+			RedactStrategy: "drop",
+		}))
+	})
+
+	It("should load the settings from TOML", func() {
+		var config Config
+		Expect(config.LoadFromTOML(strings.NewReader(tomlConfig))).To(Succeed())
+		Expect(config).To(Equal(Config{
+			LogLevel:       "server.go=DEBUG,db/*=error,warn",
+			TraceLevel:     "2",
+			LogStream:      "STDOUT",
+			OTLPResource:   "service.name=app",
+			Formatter:      "text",
+			LogNoTime:      true,
+			ShowCallerInfo: true,
+			RedactKeys:     "password,*token*",
+			RedactStrategy: "drop",
+		}))
+	})
+
+	It("should only change the settings present in the file", func() {
+		config := Config{LogLevel: "DEBUG", Formatter: "json", LogFile: "app.log"}
+		Expect(config.LoadFromYAML(strings.NewReader("format:\n  formatter: text\noutput:\n  file:\n"))).To(Succeed())
+		Expect(config).To(Equal(Config{LogLevel: "DEBUG", Formatter: "text", LogFile: "app.log"}))
+
+		Expect(config.LoadFromYAML(strings.NewReader("# nothing\n"))).To(Succeed())
+		Expect(config.Formatter).To(Equal("text"))
+	})
+
+	It("should configure a logger", func() {
+		var config Config
+		Expect(config.LoadFromYAML(strings.NewReader(
+			"levels:\n  log: error\nfilters:\n  log:\n    config_file_test.go: info\nformat:\n  formatter: json\n  time:\n    disabled: true\n",
+		))).To(Succeed())
+		logger, err := NewLogger(config)
+		Expect(err).ToNot(HaveOccurred())
+		buff := bytes.NewBuffer(nil)
+		logger.SetOutput(buff)
+		logger.Info("hello")
+		logger.Debug("hidden")
+		Expect(buff.String()).To(Equal(`{"level":"INFO","msg":"hello"}` + "\n"))
+	})
+
+	It("should report the position of invalid YAML settings", func() {
+		for _, test := range []struct {
+			doc          string
+			line, column int
+			substring    string
+		}{
+			{"levels:\n  log: WARN\n  tarce: 2\n", 3, 3, "unknown setting 'levels.tarce'"},
+			{"format:\n  color: sometimes\n", 2, 10, "color mode 'sometimes' is unknown"},
+			{"format:\n  time:\n    disabled: maybe\n", 3, 15, "'format.time.disabled' must be true or false"},
+			{"filters:\n  log:\n    a.go: INFO\n    b.go: LOUD\n", 4, 11, "log level 'LOUD' is unknown"},
+			{"filters:\n  log:\n    a=b.go: INFO\n", 3, 5, "filter pattern 'a=b.go'"},
+			{"redaction:\n  patterns:\n    - email\n    - phone\n", 4, 7, "redact pattern 'phone' is unknown"},
+			{"redaction:\n  keys: password\n", 2, 9, "'redaction.keys' must be a list"},
+			{"output:\n  stream: ftp://host\n", 2, 11, "log stream network 'ftp' is not supported"},
+			{"output:\n  - stream: stdout\n", 2, 3, "'output' must be a mapping"},
+			{"levels:\n  log: WARN\nlevels:\n  log: INFO\n", 3, 1, "'levels' is set more than once"},
+			{"format:\n  colors:\n    error: pink\n", 3, 12, "pink"},
+			{"rotation:\n  max_size: 10\n", 1, 1, "unknown setting 'rotation'"},
+			{"- levels\n", 1, 1, "the configuration must be a mapping"},
+		} {
+			var config Config
+			expectError(config.LoadFromYAML(strings.NewReader(test.doc)), test.line, test.column, test.substring)
+			Expect(config).To(Equal(Config{}))
+		}
+	})
+
+	It("should locate the values of flow collections", func() {
+		var config Config
+		expectError(config.LoadFromYAML(strings.NewReader("format: {formatter: json, color: sometimes}\n")), 1, 34, "color mode 'sometimes' is unknown")
+		expectError(config.LoadFromYAML(strings.NewReader("redaction:\n  patterns: [email, phone]\n")), 2, 21, "redact pattern 'phone' is unknown")
+	})
+
+	It("should expand aliases", func() {
+		var config Config
+		Expect(config.LoadFromYAML(strings.NewReader("levels:\n  log: &level WARN\nfilters:\n  log:\n    a.go: *level\n"))).To(Succeed())
+		Expect(config.LogLevel).To(Equal("a.go=WARN,WARN"))
+	})
+
+	It("should report the line of YAML syntax errors", func() {
+		var config Config
+		expectError(config.LoadFromYAML(strings.NewReader("levels:\n  log: WARN\n  trace: [1\n")), 2, 0, "did not find expected")
+		expectError(config.LoadFromYAML(strings.NewReader("levels: log: WARN\n")), 1, 0, "mapping values are not allowed")
+	})
+
+	It("should read JSON indented with tabs and with escapes", func() {
+		var config Config
+		Expect(config.LoadFromJSON(strings.NewReader("{\n\t\"output\": {\n\t\t\"file\": \"\\/var\\/log\\/caf\\u00e9.log\"\n\t}\n}"))).To(Succeed())
+		Expect(config.LogFile).To(Equal("/var/log/café.log"))
+	})
+
+	It("should report the position of invalid JSON settings", func() {
+		var config Config
+		expectError(config.LoadFromJSON(strings.NewReader("{\n  \"format\": {\"formater\": \"json\"}\n}")), 2, 14, "unknown setting 'format.formater'")
+		expectError(config.LoadFromJSON(strings.NewReader("{\"levels\": {\"trace\": \"high\"}}")), 1, 22, "trace level 'high' is not a number")
+		expectError(config.LoadFromJSON(strings.NewReader("{\"redaction\": {\"keys\": [\"a\", true]}}")), 1, 30, "'redaction.keys[1]' must be a string")
+		expectError(config.LoadFromJSON(strings.NewReader("[]")), 1, 1, "the configuration must be a mapping")
+	})
+
+	It("should report the position of JSON syntax errors", func() {
+		var config Config
+		expectError(config.LoadFromJSON(strings.NewReader("{\n  \"levels\": {\"log\": \"WARN\",}\n}")), 2, 28, "invalid character '}'")
+		expectError(config.LoadFromJSON(strings.NewReader("{\"levels\": ")), 1, 11, "unexpected end of JSON input")
+		expectError(config.LoadFromJSON(strings.NewReader("{\"levels\" {}}")), 1, 11, "after object key")
+		expectError(config.LoadFromJSON(strings.NewReader("{}\n{}")), 2, 1, "after top-level value")
+		expectError(config.LoadFromJSON(strings.NewReader("{} x")), 1, 4, "after top-level value")
+		expectError(config.LoadFromJSON(strings.NewReader("")), 1, 1, "unexpected end of JSON input")
+	})
+
+	It("should report the position of invalid TOML settings", func() {
+		var config Config
+		expectError(config.LoadFromTOML(strings.NewReader("[format]\nformater = \"json\"\n")), 2, 1, "unknown setting 'format.formater'")
+		expectError(config.LoadFromTOML(strings.NewReader("[redaction]\nkeys = [\"a\", true]\n")), 2, 1, "'redaction.keys[1]' must be a string")
+		expectError(config.LoadFromTOML(strings.NewReader("[levels]\nlog =\n")), 3, 1, "expecting a value")
+	})
+
+	Describe("LoadFromFile", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "rlog-config")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		load := func(name, content string) (Config, error) {
+			fileName := filepath.Join(dir, name)
+			Expect(ioutil.WriteFile(fileName, []byte(content), 0644)).To(Succeed())
+			var config Config
+			return config, config.LoadFromFile(fileName)
+		}
+
+		It("should detect the format by the extension", func() {
+			config, err := load("rlog.yml", "levels:\n  log: WARN\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.LogLevel).To(Equal("WARN"))
+
+			config, err = load("rlog.JSON", `{"levels": {"log": "ERROR"}}`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.LogLevel).To(Equal("ERROR"))
+
+			config, err = load("rlog.toml", "[levels]\nlog = \"INFO\"\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.LogLevel).To(Equal("INFO"))
+
+			config, err = load("rlog.conf", "RLOG_LOG_LEVEL=DEBUG\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.LogLevel).To(Equal("DEBUG"))
+		})
+
+		It("should report the file name with the position", func() {
+			_, err := load("rlog.yaml", "levels:\n  log: LOUD\n")
+			Expect(err).To(MatchError(filepath.Join(dir, "rlog.yaml") + ":2:8: log level 'LOUD' is unknown"))
+		})
+	})
+})
@@ -0,0 +1,428 @@
+package rlog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// configSetting is a setting of the schema of YAML and JSON configurations.
+// It either holds other settings, or applies its value to the loader.
+type configSetting struct {
+	settings map[string]configSetting
+	apply    func(loader *configLoader, name string, node *configNode) error
+}
+
+// configLoader applies the settings of a YAML or JSON configuration.
+type configLoader struct {
+	config *Config
+	// The log and trace levels are made up of the level and the filters,
+	// which are separate settings.
+	log, trace levelSpec
+}
+
+// levelSpec collects the parts of a RLOG_LOG_LEVEL or RLOG_TRACE_LEVEL
+// setting.
+type levelSpec struct {
+	set     bool
+	level   string
+	filters []string
+}
+
+// String returns the spec as understood by filterSpec.fromString: the
+// filters in the order they are checked, followed by the global level.
+func (spec *levelSpec) String() string {
+	parts := spec.filters
+	if spec.level != "" {
+		parts = append(parts[:len(parts):len(parts)], spec.level)
+	}
+	return strings.Join(parts, ",")
+}
+
+// configSchema is the schema of YAML and JSON configurations. It is
+// documented in the README.
+var configSchema = configSetting{settings: map[string]configSetting{
+	"levels": {settings: map[string]configSetting{
+		"log": {apply: func(loader *configLoader, name string, node *configNode) error {
+			level, err := configString(name, node, checkLogLevel)
+			if err != nil {
+				return err
+			}
+			loader.log.set, loader.log.level = true, level
+			return nil
+		}},
+		"trace": {apply: func(loader *configLoader, name string, node *configNode) error {
+			level, err := configString(name, node, checkTraceLevel)
+			if err != nil {
+				return err
+			}
+			loader.trace.set, loader.trace.level = true, level
+			return nil
+		}},
+		"stacktrace": stringSetting(func(s string) error {
+			_, err := parseStacktraceLevel(s)
+			return err
+		}, func(config *Config, s string) { config.StacktraceLevel = s }),
+	}},
+	"filters": {settings: map[string]configSetting{
+		"log": {apply: func(loader *configLoader, name string, node *configNode) error {
+			filters, err := configFilters(name, node, checkLogLevel)
+			if err != nil {
+				return err
+			}
+			loader.log.set, loader.log.filters = true, filters
+			return nil
+		}},
+		"trace": {apply: func(loader *configLoader, name string, node *configNode) error {
+			filters, err := configFilters(name, node, checkTraceLevel)
+			if err != nil {
+				return err
+			}
+			loader.trace.set, loader.trace.filters = true, filters
+			return nil
+		}},
+	}},
+	"output": {settings: map[string]configSetting{
+		"stream": stringSetting(checkLogStream, func(config *Config, s string) { config.LogStream = normalizeLogStream(s) }),
+		"file":   stringSetting(nil, func(config *Config, s string) { config.LogFile = s }),
+		"syslog": {settings: map[string]configSetting{
+			"address": stringSetting(func(s string) error {
+				_, err := syslogOptionsFromConfig(Config{SyslogAddress: s})
+				return err
+			}, func(config *Config, s string) { config.SyslogAddress = s }),
+			"format": stringSetting(func(s string) error {
+				_, err := syslogOptionsFromConfig(Config{SyslogFormat: s})
+				return err
+			}, func(config *Config, s string) { config.SyslogFormat = s }),
+			"facility": stringSetting(func(s string) error {
+				_, err := ParseSyslogFacility(s)
+				return err
+			}, func(config *Config, s string) { config.SyslogFacility = s }),
+			"app_name": stringSetting(nil, func(config *Config, s string) { config.SyslogAppName = s }),
+			"sd_id":    stringSetting(nil, func(config *Config, s string) { config.SyslogStructuredDataID = s }),
+		}},
+		"gelf": {settings: map[string]configSetting{
+			"address":     stringSetting(nil, func(config *Config, s string) { config.GELFAddress = s }),
+			"compression": stringSetting(checkGELFCompression, func(config *Config, s string) { config.GELFCompression = s }),
+		}},
+		"otlp": {settings: map[string]configSetting{
+			"endpoint": stringSetting(nil, func(config *Config, s string) { config.OTLPEndpoint = s }),
+			"resource": {apply: func(loader *configLoader, name string, node *configNode) error {
+				pairs, err := configPairs(name, node, nil)
+				if err != nil {
+					return err
+				}
+				loader.config.OTLPResource = strings.Join(pairs, ",")
+				return nil
+			}},
+		}},
+	}},
+	"format": {settings: map[string]configSetting{
+		"formatter": stringSetting(checkFormatter, func(config *Config, s string) { config.Formatter = s }),
+		"time": {settings: map[string]configSetting{
+			"format": stringSetting(nil, func(config *Config, s string) { config.logTimeFormat = s }),
+			"zone": stringSetting(func(s string) error {
+				_, err := ParseTimeFormat("", s)
+				return err
+			}, func(config *Config, s string) { config.TimeZone = s }),
+			"disabled": boolSetting(func(config *Config, b bool) { config.LogNoTime = b }),
+		}},
+		"caller": {settings: map[string]configSetting{
+			"enabled": boolSetting(func(config *Config, b bool) { config.ShowCallerInfo = b }),
+			"format": stringSetting(func(s string) error {
+				_, err := ParseCallerFormat(s)
+				return err
+			}, func(config *Config, s string) { config.CallerFormat = s }),
+			"goroutine_id": boolSetting(func(config *Config, b bool) { config.ShowGoroutineID = b }),
+		}},
+		"color": stringSetting(func(s string) error {
+			_, err := ParseColorMode(s)
+			return err
+		}, func(config *Config, s string) { config.Color = s }),
+		"colors": {apply: func(loader *configLoader, name string, node *configNode) error {
+			pairs, err := configPairs(name, node, func(pair string) error {
+				_, err := ParseColorScheme(pair)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			loader.config.Colors = strings.Join(pairs, ",")
+			return nil
+		}},
+		"template": stringSetting(func(s string) error {
+			_, err := ParseLineTemplate(s)
+			return err
+		}, func(config *Config, s string) { config.Template = s }),
+		"multiline": stringSetting(func(s string) error {
+			_, err := ParseMultilineMode(s)
+			return err
+		}, func(config *Config, s string) { config.Multiline = s }),
+		"duplicate_keys": stringSetting(func(s string) error {
+			_, err := parseDuplicateKeys(s)
+			return err
+		}, func(config *Config, s string) { config.DuplicateKeys = s }),
+	}},
+	"redaction": {settings: map[string]configSetting{
+		"keys": {apply: func(loader *configLoader, name string, node *configNode) error {
+			keys, err := configStrings(name, node, func(s string) error {
+				if strings.Contains(s, ",") {
+					return fmt.Errorf("redact key '%s' can't contain ','", s)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			loader.config.RedactKeys = strings.Join(keys, ",")
+			return nil
+		}},
+		"patterns": {apply: func(loader *configLoader, name string, node *configNode) error {
+			patterns, err := configStrings(name, node, func(s string) error {
+				_, err := redactorFromConfig(Config{RedactPatterns: s})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			loader.config.RedactPatterns = strings.Join(patterns, ",")
+			return nil
+		}},
+		"regex": stringSetting(func(s string) error {
+			if _, err := regexp.Compile(s); err != nil {
+				return fmt.Errorf("redact regex '%s' is malformed: %s", s, err)
+			}
+			return nil
+		}, func(config *Config, s string) { config.RedactRegex = s }),
+		"strategy": stringSetting(func(s string) error {
+			_, err := redactorFromConfig(Config{RedactStrategy: s})
+			return err
+		}, func(config *Config, s string) { config.RedactStrategy = s }),
+	}},
+}}
+
+// loadFromNode applies the settings of a YAML or JSON configuration. The
+// config is only changed if all of them are valid.
+func (config *Config) loadFromNode(root *configNode) error {
+	updated := *config
+	loader := &configLoader{config: &updated}
+	if _, ok := root.value.([]configEntry); !ok {
+		return root.errorf("the configuration must be a mapping")
+	}
+	if err := loader.load(configSchema, "", root); err != nil {
+		return err
+	}
+	if loader.log.set {
+		updated.LogLevel = loader.log.String()
+	}
+	if loader.trace.set {
+		updated.TraceLevel = loader.trace.String()
+	}
+	*config = updated
+	return nil
+}
+
+// load applies the settings of the mapping in node, which is the setting
+// with the given name.
+func (loader *configLoader) load(setting configSetting, name string, node *configNode) error {
+	entries, ok := node.value.([]configEntry)
+	if !ok {
+		return node.errorf("'%s' must be a mapping", name)
+	}
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		entryName := entry.key
+		if name != "" {
+			entryName = name + "." + entry.key
+		}
+		if seen[entry.key] {
+			return entry.errorf("'%s' is set more than once", entryName)
+		}
+		seen[entry.key] = true
+		child, ok := setting.settings[entry.key]
+		if !ok {
+			return entry.errorf("unknown setting '%s'", entryName)
+		}
+		// Settings without a value are left as they are.
+		if entry.value.value == nil {
+			continue
+		}
+		var err error
+		if child.apply != nil {
+			err = child.apply(loader, entryName, entry.value)
+		} else {
+			err = loader.load(child, entryName, entry.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringSetting is a setting with a string value, which is checked by check,
+// if not nil, and then set.
+func stringSetting(check func(string) error, set func(*Config, string)) configSetting {
+	return configSetting{apply: func(loader *configLoader, name string, node *configNode) error {
+		s, err := configString(name, node, check)
+		if err != nil {
+			return err
+		}
+		set(loader.config, s)
+		return nil
+	}}
+}
+
+// boolSetting is a setting with a boolean value.
+func boolSetting(set func(*Config, bool)) configSetting {
+	return configSetting{apply: func(loader *configLoader, name string, node *configNode) error {
+		b, ok := node.value.(bool)
+		if s, isString := node.value.(string); isString {
+			// The booleans of YAML 1.1, which YAML 1.2 reads as strings.
+			switch strings.ToLower(s) {
+			case "yes", "y", "on":
+				b, ok = true, true
+			case "no", "n", "off":
+				b, ok = false, true
+			}
+		}
+		if !ok {
+			return node.errorf("'%s' must be true or false", name)
+		}
+		set(loader.config, b)
+		return nil
+	}}
+}
+
+// configString returns the value of a string setting. Numbers are accepted
+// as well, so that e.g. trace levels don't need quotes.
+func configString(name string, node *configNode, check func(string) error) (string, error) {
+	var s string
+	switch value := node.value.(type) {
+	case string:
+		s = value
+	case float64:
+		s = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return "", node.errorf("'%s' must be a string", name)
+	}
+	if check != nil {
+		if err := check(s); err != nil {
+			return "", node.errorf("%s", err)
+		}
+	}
+	return s, nil
+}
+
+// configStrings returns the values of a setting with a list of strings.
+func configStrings(name string, node *configNode, check func(string) error) ([]string, error) {
+	items, ok := node.value.([]*configNode)
+	if !ok {
+		return nil, node.errorf("'%s' must be a list", name)
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, err := configString(fmt.Sprintf("%s[%d]", name, i), item, check)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// configPairs returns the key=value pairs of a setting with a mapping of
+// strings. Each pair is checked by check, if not nil.
+func configPairs(name string, node *configNode, check func(string) error) ([]string, error) {
+	entries, ok := node.value.([]configEntry)
+	if !ok {
+		return nil, node.errorf("'%s' must be a mapping", name)
+	}
+	pairs := make([]string, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		if strings.ContainsAny(entry.key, ",=") {
+			return nil, entry.errorf("key '%s' of '%s' can't contain ',' or '='", entry.key, name)
+		}
+		value, err := configString(name+"."+entry.key, entry.value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(value, ",") {
+			return nil, entry.value.errorf("'%s.%s' can't contain ','", name, entry.key)
+		}
+		pairs[i] = entry.key + "=" + value
+		if check != nil {
+			if err := check(pairs[i]); err != nil {
+				return nil, entry.value.errorf("%s", err)
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// configFilters returns the filters of a mapping from file patterns to levels,
+// in their order.
+func configFilters(name string, node *configNode, checkLevel func(string) error) ([]string, error) {
+	entries, ok := node.value.([]configEntry)
+	if !ok {
+		return nil, node.errorf("'%s' must be a mapping", name)
+	}
+	filters := make([]string, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		if entry.key == "" || strings.ContainsAny(entry.key, ",=") {
+			return nil, entry.errorf("filter pattern '%s' must not be empty or contain ',' or '='", entry.key)
+		}
+		level, err := configString(name+"."+entry.key, entry.value, checkLevel)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = entry.key + "=" + level
+	}
+	return filters, nil
+}
+
+func checkLogLevel(s string) error {
+	if level, ok := levelNumbers[strings.ToUpper(s)]; !ok || level == levelTrace {
+		return fmt.Errorf("log level '%s' is unknown", s)
+	}
+	return nil
+}
+
+func checkTraceLevel(s string) error {
+	if _, err := strconv.Atoi(s); err != nil {
+		return fmt.Errorf("trace level '%s' is not a number", s)
+	}
+	return nil
+}
+
+func checkLogStream(s string) error {
+	if strings.Contains(s, "://") {
+		_, err := ParseNetworkURL(s)
+		return err
+	}
+	switch strings.ToUpper(s) {
+	case "", "STDERR", "STDOUT", "SYSLOG", "JOURNALD", "GELF", "OTLP", "NONE":
+		return nil
+	}
+	return fmt.Errorf("log stream '%s' is unknown", s)
+}
+
+func checkGELFCompression(s string) error {
+	switch strings.ToLower(s) {
+	case "", "none", "gzip", "zlib":
+		return nil
+	}
+	return fmt.Errorf("GELF compression '%s' is unknown", s)
+}
+
+func checkFormatter(s string) error {
+	switch s {
+	case "", "default", "text", "json", "gelf", "otlp":
+		return nil
+	}
+	return fmt.Errorf("formatter '%s' is unknown", s)
+}
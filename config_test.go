@@ -111,6 +111,99 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	It("should keep the case of a log stream URL", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_LOG_STREAM=unix:///run/Agent.sock")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.LogStream).To(Equal("unix:///run/Agent.sock"))
+	})
+
+	It("should load the syslog settings from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_SYSLOG_ADDRESS=udp://localhost:514")
+		fmt.Fprintln(buff, "RLOG_SYSLOG_FORMAT=RFC3164")
+		fmt.Fprintln(buff, "RLOG_SYSLOG_FACILITY=local0")
+		fmt.Fprintln(buff, "RLOG_SYSLOG_APP_NAME=app")
+		fmt.Fprintln(buff, "RLOG_SYSLOG_SD_ID=fields@32473")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.SyslogAddress).To(Equal("udp://localhost:514"))
+		Expect(config.SyslogFormat).To(Equal("RFC3164"))
+		Expect(config.SyslogFacility).To(Equal("local0"))
+		Expect(config.SyslogAppName).To(Equal("app"))
+		Expect(config.SyslogStructuredDataID).To(Equal("fields@32473"))
+	})
+
+	It("should load the syslog settings from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_SYSLOG_ADDRESS":  "udp://localhost:514",
+			"RLOG_SYSLOG_FORMAT":   "RFC3164",
+			"RLOG_SYSLOG_FACILITY": "local0",
+			"RLOG_SYSLOG_APP_NAME": "app",
+			"RLOG_SYSLOG_SD_ID":    "fields@32473",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.SyslogAddress).To(Equal("udp://localhost:514"))
+			Expect(config.SyslogFormat).To(Equal("RFC3164"))
+			Expect(config.SyslogFacility).To(Equal("local0"))
+			Expect(config.SyslogAppName).To(Equal("app"))
+			Expect(config.SyslogStructuredDataID).To(Equal("fields@32473"))
+			return nil
+		})
+	})
+
+	It("should load the GELF settings from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_GELF_ADDRESS=graylog:12201")
+		fmt.Fprintln(buff, "RLOG_GELF_COMPRESSION=gzip")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.GELFAddress).To(Equal("graylog:12201"))
+		Expect(config.GELFCompression).To(Equal("gzip"))
+	})
+
+	It("should load the GELF settings from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_GELF_ADDRESS":     "graylog:12201",
+			"RLOG_GELF_COMPRESSION": "gzip",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.GELFAddress).To(Equal("graylog:12201"))
+			Expect(config.GELFCompression).To(Equal("gzip"))
+			return nil
+		})
+	})
+
+	It("should load the OTLP settings from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_OTLP_ENDPOINT=http://collector:4318/v1/logs")
+		fmt.Fprintln(buff, "RLOG_OTLP_RESOURCE=service.name=api")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.OTLPEndpoint).To(Equal("http://collector:4318/v1/logs"))
+		Expect(config.OTLPResource).To(Equal("service.name=api"))
+	})
+
+	It("should load the OTLP settings from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_OTLP_ENDPOINT": "http://collector:4318/v1/logs",
+			"RLOG_OTLP_RESOURCE": "service.name=api",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.OTLPEndpoint).To(Equal("http://collector:4318/v1/logs"))
+			Expect(config.OTLPResource).To(Equal("service.name=api"))
+			return nil
+		})
+	})
+
 	It("should load the no time flag from the stream", func() {
 		buff := bytes.NewBuffer(nil)
 		fmt.Fprintln(buff, "RLOG_LOG_NOTIME=true")
@@ -151,6 +244,26 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	It("should load the caller format from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_CALLER_FORMAT=short")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.CallerFormat).To(Equal("short"))
+	})
+
+	It("should load the caller format from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_CALLER_FORMAT": "{file}:{line}",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.CallerFormat).To(Equal("{file}:{line}"))
+			return nil
+		})
+	})
+
 	It("should load the go routine id from the stream", func() {
 		buff := bytes.NewBuffer(nil)
 		fmt.Fprintln(buff, "RLOG_GOROUTINE_ID=true")
@@ -171,6 +284,162 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	It("should load the stack trace level from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_STACKTRACE_LEVEL=ERROR")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.StacktraceLevel).To(Equal("ERROR"))
+	})
+
+	It("should load the stack trace level from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_STACKTRACE_LEVEL": "ERROR",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.StacktraceLevel).To(Equal("ERROR"))
+			return nil
+		})
+	})
+
+	It("should load the redaction settings from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_REDACT_KEYS=password,*token*")
+		fmt.Fprintln(buff, "RLOG_REDACT_PATTERNS=email")
+		fmt.Fprintln(buff, "RLOG_REDACT_REGEX=pin=\\d+")
+		fmt.Fprintln(buff, "RLOG_REDACT_STRATEGY=hash")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.RedactKeys).To(Equal("password,*token*"))
+		Expect(config.RedactPatterns).To(Equal("email"))
+		Expect(config.RedactRegex).To(Equal(`pin=\d+`))
+		Expect(config.RedactStrategy).To(Equal("hash"))
+	})
+
+	It("should load the redaction settings from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_REDACT_KEYS":     "password",
+			"RLOG_REDACT_PATTERNS": "jwt",
+			"RLOG_REDACT_REGEX":    "pin",
+			"RLOG_REDACT_STRATEGY": "drop",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.RedactKeys).To(Equal("password"))
+			Expect(config.RedactPatterns).To(Equal("jwt"))
+			Expect(config.RedactRegex).To(Equal("pin"))
+			Expect(config.RedactStrategy).To(Equal("drop"))
+			return nil
+		})
+	})
+
+	It("should load the duplicate keys setting from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_DUPLICATE_KEYS=last")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.DuplicateKeys).To(Equal("last"))
+	})
+
+	It("should load the duplicate keys setting from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_DUPLICATE_KEYS": "last",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.DuplicateKeys).To(Equal("last"))
+			return nil
+		})
+	})
+
+	It("should load the color settings from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_COLOR=always")
+		fmt.Fprintln(buff, "RLOG_COLORS=error=bold+red,key=blue")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.Color).To(Equal("always"))
+		Expect(config.Colors).To(Equal("error=bold+red,key=blue"))
+	})
+
+	It("should load the color settings from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_COLOR":  "never",
+			"RLOG_COLORS": "key=blue",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.Color).To(Equal("never"))
+			Expect(config.Colors).To(Equal("key=blue"))
+			return nil
+		})
+	})
+
+	It("should load the format template from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_FORMAT_TEMPLATE={time} {level:-8} {msg}")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.Template).To(Equal("{time} {level:-8} {msg}"))
+	})
+
+	It("should load the format template from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_FORMAT_TEMPLATE": "{lvl} {msg}",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.Template).To(Equal("{lvl} {msg}"))
+			return nil
+		})
+	})
+
+	It("should load the multiline mode from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_MULTILINE=indent")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.Multiline).To(Equal("indent"))
+	})
+
+	It("should load the multiline mode from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_MULTILINE": "split",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.Multiline).To(Equal("split"))
+			return nil
+		})
+	})
+
+	It("should load the time zone from the stream", func() {
+		buff := bytes.NewBuffer(nil)
+		fmt.Fprintln(buff, "RLOG_TIME_ZONE=Local")
+
+		var config Config
+		Expect(config.loadFromStream(buff)).To(Succeed())
+		Expect(config.TimeZone).To(Equal("Local"))
+	})
+
+	It("should load the time zone from the env", func() {
+		OverrideEnv(map[string]string{
+			"RLOG_TIME_ZONE": "Europe/Berlin",
+		}, func() error {
+			var config Config
+			config.LoadFromEnv("")
+			Expect(config.TimeZone).To(Equal("Europe/Berlin"))
+			return nil
+		})
+	})
+
 	It("should not fail when finding a unknown variable", func() {
 		buff := bytes.NewBuffer(nil)
 		fmt.Fprintln(buff, "RLOG_UNKNOWN_VARIABLE=anyvalue")
@@ -0,0 +1,89 @@
+package rlog
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Names of the fields which hold the trace correlation of an entry.
+const (
+	TraceIDField = "trace_id"
+	SpanIDField  = "span_id"
+)
+
+// SpanContextFunc returns the trace and span ID, hex encoded, of the span
+// active in the context. ok is false if there is none.
+type SpanContextFunc func(ctx context.Context) (traceID string, spanID string, ok bool)
+
+var spanContextFunc atomic.Value
+
+// SetSpanContextFunc sets how WithContext finds the active span of a context.
+// rlog doesn't depend on a tracing library, so this has to be set once at
+// startup, for example to otelrlog.SpanContext for OpenTelemetry.
+func SetSpanContextFunc(f SpanContextFunc) {
+	spanContextFunc.Store(f)
+}
+
+// spanFields returns the fields carrying the trace correlation of the span
+// active in the context, if any.
+func spanFields(ctx context.Context) FieldsArr {
+	f, _ := spanContextFunc.Load().(SpanContextFunc)
+	if f == nil || ctx == nil {
+		return nil
+	}
+	traceID, spanID, ok := f(ctx)
+	if !ok {
+		return nil
+	}
+	return FieldsArr{TraceIDField, traceID, SpanIDField, spanID}
+}
+
+// WithContext returns a sub-logger whose entries carry the trace_id and
+// span_id of the span active in ctx. If there is none, the entries are the
+// same as those of the logger.
+func (l *logger) WithContext(ctx context.Context) Logger {
+	return newSubLogger(l, spanFields(ctx))
+}
+
+// WithContext returns a sub-logger whose entries carry the trace_id and
+// span_id of the span active in ctx. If there is none, the entries are the
+// same as those of the logger.
+func (logger *subLogger) WithContext(ctx context.Context) Logger {
+	return newSubLogger(logger, spanFields(ctx))
+}
+
+// WithContext returns a sub-logger of the default logger whose entries carry
+// the trace_id and span_id of the span active in ctx.
+func WithContext(ctx context.Context) Logger {
+	return DefaultLogger.WithContext(ctx)
+}
+
+// LoggerWithContext returns a sub-logger of l whose entries carry the
+// trace_id and span_id of the span active in ctx. Loggers without
+// WithContext get the fields through WithFieldsArr.
+func LoggerWithContext(l Logger, ctx context.Context) Logger {
+	if cl, ok := l.(contextLogger); ok {
+		return cl.WithContext(ctx)
+	}
+	if fields := spanFields(ctx); fields != nil {
+		return l.WithFieldsArr(fields...)
+	}
+	return l
+}
+
+type loggerContextKey struct{}
+
+// NewContext returns a copy of ctx carrying the logger, e.g. the sub-logger
+// of a request, which the code handling the request gets with FromContext.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if it
+// carries none.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(Logger); ok {
+		return l
+	}
+	return DefaultLogger
+}
@@ -0,0 +1,78 @@
+package rlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type spanKey struct{}
+
+// fakeSpanContext finds the IDs stored under spanKey.
+func fakeSpanContext(ctx context.Context) (string, string, bool) {
+	ids, ok := ctx.Value(spanKey{}).([2]string)
+	return ids[0], ids[1], ok
+}
+
+// spanCtx carries a span for fakeSpanContext.
+var spanCtx = context.WithValue(context.Background(), spanKey{}, [2]string{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+
+var _ = Describe("WithContext", func() {
+	var (
+		logger *logger
+		buff   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger, err = NewLogger(Config{LogNoTime: true, Formatter: "text"})
+		Expect(err).ToNot(HaveOccurred())
+		buff = bytes.NewBuffer(nil)
+		logger.SetOutput(buff)
+		SetSpanContextFunc(fakeSpanContext)
+	})
+
+	AfterEach(func() {
+		SetSpanContextFunc(nil)
+	})
+
+	It("should add the trace and span ID of the active span", func() {
+		logger.WithContext(spanCtx).Info("handled")
+		Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 msg="handled"`))
+	})
+
+	It("should keep the fields of a sub-logger", func() {
+		LoggerWithContext(logger.WithField("user", "bob"), spanCtx).Info("handled")
+		Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO user=bob trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 msg="handled"`))
+	})
+
+	It("should add the fields through loggers without WithContext", func() {
+		LoggerWithContext(struct{ Logger }{logger}, spanCtx).Info("handled")
+		Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 msg="handled"`))
+	})
+
+	It("should add nothing without active span", func() {
+		logger.WithContext(context.Background()).Info("handled")
+		Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO msg="handled"`))
+	})
+
+	It("should add nothing without span context function", func() {
+		SetSpanContextFunc(nil)
+		logger.WithContext(spanCtx).Info("handled")
+		Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO msg="handled"`))
+	})
+})
+
+var _ = Describe("FromContext", func() {
+	It("should return the logger of the context", func() {
+		sub := DefaultLogger.WithField("request_id", "abc")
+		Expect(FromContext(NewContext(context.Background(), sub))).To(BeIdenticalTo(sub))
+	})
+
+	It("should fall back to the default logger", func() {
+		Expect(FromContext(context.Background())).To(Equal(Logger(DefaultLogger)))
+	})
+})
@@ -97,21 +97,109 @@
 // * RLOG_CALLER_INFO: If this variable is set to "1", "yes" or something else
 //   that evaluates to 'true' then the message also contains the caller
 //   information, consisting of the process ID, file and line number as well as
-//   function name from which the log message was called. Default: No - meaning
-//   that no caller info is logged.
+//   function name from which the log message was called. The text formatter,
+//   which used to leave it out, writes it as caller field after the level,
+//   e.g. level=INFO caller="app/main.go:12" msg="started". Default: No -
+//   meaning that no caller info is logged.
 //
 // * RLOG_GOROUTINE_ID: If this variable is set to "1", "yes" or something else
 //   that evaluates to 'true' AND the printing of caller info is requested, then
 //   the caller info contains the goroutine ID, separated from the process ID by a
-//   ':'. Note that calculation of the goroutine ID has a performance impact, so
-//   please only enable this option if needed.
+//   ':'. On amd64 and arm64 the ID is read directly from the runtime, which is
+//   cheap. On other platforms it is parsed from the output of runtime.Stack,
+//   which has a performance impact, so please only enable this option there if
+//   needed. Setting RLOG_GOID_CHECK checks every ID read from the runtime
+//   against runtime.Stack, and falls back to it on a mismatch.
+//
+// * RLOG_CALLER_FORMAT: Determines how the caller info is rendered. "long"
+//   is the process ID, file, line and fully qualified function name. "short" is
+//   just the file (with its directory) and line. "package" is the import path of
+//   the package, the file name and the line. Anything else is taken as a
+//   template, in which the placeholders {pid}, {gid}, {file}, {basename},
+//   {path}, {package}, {line}, {function} and {shortfunction} are replaced, for
+//   example "{basename}:{line} {shortfunction}". The JSON formatter always
+//   carries every part as its own key. Default: long.
+//
+// * RLOG_COLOR: When the default formatter colors its output: "auto" colors
+//   the output of terminals, unless NO_COLOR is set, and any output if
+//   FORCE_COLOR is set, for example on CI systems which support ANSI escape
+//   codes. "always" and "never" ignore both variables. Default: auto.
+//
+// * RLOG_COLORS: Overrides the colors of the default formatter, as a list of
+//   name=color pairs separated by commas, for example
+//   "error=bold+red,key=blue,caller=hi-black,time=faint". The names are the
+//   levels, "key" (field keys, colored like the level by default), "caller"
+//   and "time". A color combines black, red, green, yellow, blue, magenta,
+//   cyan or white, optionally prefixed by hi-, bg- or bg-hi-, with bold,
+//   faint, italic, underline, blink, reverse or SGR codes, using "+". "none"
+//   removes the color. Default: Not set.
+//
+// * RLOG_FORMAT_TEMPLATE: The layout of the lines of the default formatter,
+//   for example "{time} {level:-8} [{caller} ]{msg:-60} {fields}". The
+//   placeholders are {time}, {level} (e.g. CRITICAL), {lvl} (the first four
+//   letters, e.g. CRIT), {tracelevel}, {caller}, {gid}, {msg} and {fields}.
+//   As with the verbs of the fmt package, {msg:-60} pads to 60 characters,
+//   left aligned, {level:8} right aligned, {tracelevel:05} with zeros and
+//   {caller:.30} cuts at 30 characters; widths go up to 1024. Parts in square
+//   brackets are left out if one of their placeholders is empty. Braces,
+//   brackets and backslashes are escaped with a backslash. Default: Not set -
+//   the time, level, trace level, caller info, message and fields follow each
+//   other.
+//
+// * RLOG_FORMATTER: Selects how log lines are rendered: "default" (human
+//   readable, colored on terminals), "text" (key=value pairs), "json" (one
+//   JSON object per line), "gelf" (one GELF 1.1 message per line, the JSON
+//   format of Graylog) or "otlp" (one OTLP-JSON log record per line, following
+//   the OpenTelemetry logs data model). Default: default. The "json" formatter
+//   writes fields named like its own keys, e.g. level or msg, with a fields.
+//   prefix, like "fields.level".
+//
+// * RLOG_MULTILINE: How messages with line breaks, like SQL queries or stack
+//   dumps, are written: "raw" writes the line breaks as they are, "escape" as
+//   \n, so each entry stays on a single line, "indent" indents the
+//   continuation lines under the message and "split" writes each line as its
+//   own entry, which share the "multiline_id" field and carry their line
+//   number in "multiline_line". The JSON based formatters always escape line
+//   breaks, so only "split" changes their output. Default: raw.
+//
+// * RLOG_STACKTRACE_LEVEL: Set to a log level, for example "ERROR", to attach
+//   the stack trace of the logging goroutine to every message of that level or
+//   more severe. The default formatter prints the frames indented below the
+//   line, the text formatter as an escaped "stack" field and the JSON formatter
+//   as an array of frames. Default: Not set - meaning that no stack traces are
+//   captured.
+//
+// * RLOG_DUPLICATE_KEYS: How fields with the same key, for example set again
+//   by a sub-logger, are logged: "keep" logs all of them, "last" only the last
+//   one. Default: keep.
+//
+// * RLOG_REDACT_KEYS: Glob patterns of field keys, separated by commas, whose
+//   values are redacted before formatting, for example "password,*token*".
+//   The keys are matched case-insensitively. Default: Not set.
+//
+// * RLOG_REDACT_PATTERNS: Predefined patterns, separated by commas, which are
+//   redacted in the messages and in the field values: "email", "jwt" and
+//   "credit_card" (checked with the Luhn algorithm). Default: Not set.
+//
+// * RLOG_REDACT_REGEX: A regular expression which is redacted in the messages
+//   and in the field values. Default: Not set.
+//
+// * RLOG_REDACT_STRATEGY: What happens to redacted values: "mask" replaces
+//   them by "[REDACTED]", "hash" by a short SHA-256, so that equal values can
+//   still be correlated, and "drop" removes the fields or the matched text.
+//   Default: mask.
 //
 // * RLOG_TIME_FORMAT: Use this variable to customize the date/time format. The
 //   format is specified either by the well known formats listed in
 //   https://golang.org/src/time/format.go, for example "UnixDate" or "RFC3339".
 //   Or as an example date/time output, which is described here:
 //   https://golang.org/pkg/time/#Time.Format Default: Not set - formatted
-//   according to RFC3339.
+//   according to RFC3339. "unix", "unixmilli" and "unixnano" log the seconds,
+//   milliseconds or nanoseconds since the Unix epoch instead, which the JSON
+//   formatter writes as numbers.
+//
+// * RLOG_TIME_ZONE: The time zone of the date/time stamps: "UTC", "Local" or
+//   an IANA name like "Europe/Berlin". Default: UTC.
 //
 // * RLOG_LOG_NOTIME: If this variable is set to "1", "yes" or something else
 //   that evaluates to 'true' then no date/time stamp is logged with each log
@@ -125,10 +213,69 @@
 //   file.
 //
 // * RLOG_LOG_STREAM: Use this to direct the log output to a different output
-//   stream, instead of stderr. This accepts three values: "stderr", "stdout" or
-//   "none". If either stderr or stdout is defined here AND a logfile is specified
-//   via RLOG_LOG_FILE then the output is sent to both. Default: Not set -
-//   meaning the output goes to stderr.
+//   stream, instead of stderr. This accepts "stderr", "stdout", "syslog",
+//   "journald", "gelf", "otlp", "none" or the URL of a network sink (see
+//   below). If //   either stderr or stdout is defined here AND a logfile is specified via
+//   RLOG_LOG_FILE then the output is sent to both. Default: Not set - meaning
+//   the output goes to stderr.
+//
+// * RLOG_SYSLOG_ADDRESS: Where the syslog daemon listens when RLOG_LOG_STREAM
+//   is "syslog", as <network>://<address>, for example udp://host:514,
+//   tcp://host:601 or unix:///dev/log. Default: Not set - meaning the local
+//   daemon is used.
+//
+// * RLOG_SYSLOG_FORMAT: "RFC5424" or "RFC3164". Default: RFC5424.
+//
+// * RLOG_SYSLOG_FACILITY: The syslog facility, for example "daemon" or
+//   "local0". Default: user.
+//
+// * RLOG_SYSLOG_APP_NAME: The application name sent with each message.
+//   Default: the name of the executable.
+//
+// * RLOG_SYSLOG_SD_ID: The SD-ID (for example fields@32473) under which the
+//   fields of a message are sent as RFC 5424 structured data. Default: Not set -
+//   meaning the fields are appended to the message.
+//
+// * RLOG_LOG_STREAM=journald sends the messages to systemd-journald, using its
+//   native protocol (Linux only). The level becomes the PRIORITY, the fields of
+//   a message become journal fields (upper cased, with invalid characters
+//   replaced by "_") and, if RLOG_CALLER_INFO is set, the caller is sent as
+//   CODE_FILE, CODE_LINE and CODE_FUNC. Fields named like the fields of the
+//   journal, like MESSAGE or PRIORITY, are prefixed with RLOG_.
+//
+// * RLOG_LOG_STREAM=tcp://host:port ships the formatted lines to a network
+//   sink, like a local Vector or Fluent Bit agent. The networks "tcp", "udp",
+//   "unix" and "unixgram" are supported, for example udp://localhost:5170 or
+//   unix:///run/agent.sock. Lines are sent in the background. While the sink
+//   is unreachable, rlog reconnects with exponential backoff and keeps up to
+//   1024 lines in memory, dropping the oldest ones after that. The URL accepts
+//   the parameters "buffer" (number of lines kept in memory), "max_line"
+//   (longer lines are truncated, default 65536 bytes, or 65506 bytes for
+//   datagrams), "spill" (a file which takes the lines that don't fit into
+//   memory, instead of dropping them) and "max_spill" (the size in bytes at
+//   which the spill file drops new lines, default 100 MiB), for example
+//   tcp://localhost:5170?buffer=10000&spill=/var/spool/app.log. Lines the
+//   socket refuses, like datagrams which are too large, are dropped.
+//
+// * RLOG_GELF_ADDRESS: The host:port of the Graylog GELF UDP input when
+//   RLOG_LOG_STREAM is "gelf". Messages which don't fit into a datagram are
+//   chunked. Default: localhost:12201.
+//
+// * RLOG_GELF_COMPRESSION: "none", "gzip" or "zlib". Default: none.
+//
+// * RLOG_OTLP_ENDPOINT: The OTLP/HTTP logs endpoint of the OpenTelemetry
+//   collector when RLOG_LOG_STREAM is "otlp". The records are sent as JSON, in
+//   batches of up to 512 records, at least once a second. Default:
+//   http://localhost:4318/v1/logs.
+//
+// * RLOG_OTLP_RESOURCE: Resource attributes for the "otlp" formatter and log
+//   stream, as comma separated key=value pairs, for example
+//   service.name=api,deployment.environment=prod. service.name (the name of
+//   the executable), host.name and process.pid are always sent, unless they
+//   are overridden here.
+//
+// The log levels are mapped to syslog severities as follows: CRITICAL to crit,
+// ERROR to err, WARN to warning, INFO to info, DEBUG and TRACE to debug.
 //
 // There are two more settings, related to the configuration file, which can only
 // be set via environment variables.
@@ -205,6 +352,77 @@
 //
 // * Spaces or further '=' characters within values are taken as they are.
 //
+// YAML, JSON AND TOML CONFIG FILES
+//
+// Per-sink and per-file settings are easier to express in YAML, JSON or TOML.
+// Config.LoadFromYAML, Config.LoadFromJSON and Config.LoadFromTOML load such a
+// document, and Config.LoadFromFile picks the format by the extension of the
+// file: .yaml, .yml, .json or .toml, falling back to the format above. All the
+// settings are optional; those which are left out, or have no value, keep what
+// the Config had before:
+//
+//     levels:
+//       log: INFO                # RLOG_LOG_LEVEL
+//       trace: 2                 # RLOG_TRACE_LEVEL
+//       stacktrace: ERROR        # RLOG_STACKTRACE_LEVEL
+//     filters:                   # levels per file, checked in this order
+//       log:
+//         server.go: DEBUG
+//         db/*: WARN
+//       trace:
+//         db/query.go: 5
+//     output:
+//       stream: syslog           # RLOG_LOG_STREAM
+//       file: /var/log/myapp.log # RLOG_LOG_FILE
+//       syslog:                  # RLOG_SYSLOG_*
+//         address: udp://localhost:514
+//         format: rfc5424
+//         facility: local0
+//         app_name: myapp
+//         sd_id: rlog@32473
+//       gelf:                    # RLOG_GELF_*
+//         address: graylog:12201
+//         compression: gzip
+//       otlp:                    # RLOG_OTLP_*
+//         endpoint: http://collector:4318/v1/logs
+//         resource:
+//           service.name: myapp
+//     format:
+//       formatter: json          # RLOG_FORMATTER
+//       time:
+//         format: RFC3339        # RLOG_TIME_FORMAT
+//         zone: Europe/Berlin    # RLOG_TIME_ZONE
+//         disabled: false        # RLOG_LOG_NOTIME
+//       caller:
+//         enabled: true          # RLOG_CALLER_INFO
+//         format: short          # RLOG_CALLER_FORMAT
+//         goroutine_id: false    # RLOG_GOROUTINE_ID
+//       color: auto              # RLOG_COLOR
+//       colors:                  # RLOG_COLORS
+//         error: bold+red
+//       template: "{time} {level:-8} {msg:-60} {fields}"
+//       multiline: indent        # RLOG_MULTILINE
+//       duplicate_keys: last     # RLOG_DUPLICATE_KEYS
+//     redaction:                 # RLOG_REDACT_*
+//       keys: [password, "*token*"]
+//       patterns: [email, jwt]
+//       regex: secret-[0-9]+
+//       strategy: hash
+//
+// JSON and TOML files have the same structure. The values are checked against
+// the schema, and the Config is only changed if all of them are valid. Errors
+// are returned as *ConfigError, with the line and column of the offending
+// setting:
+//
+//     /etc/rlog/myapp.yaml:2:8: log level 'LOUD' is unknown
+//
+// YAML files are read as YAML 1.2, where yes and no are strings; the boolean
+// settings accept them anyway, along with on and off. Syntax errors of YAML
+// files only report the line. The keys of TOML inline tables lose their order,
+// so write the filters of TOML files as tables, like [filters.log]. rlog
+// doesn't rotate log files, so there are no rotation settings; use an external
+// tool like logrotate with its copytruncate option.
+//
 // COMBINING CONFIGURATION FROM ENVIRONMENT VARIABLES AND CONFIG FILE
 //
 // Generally, environment variables take precedence. Assume you have set a log
@@ -1,24 +1,45 @@
 package rlog
 
+import "time"
+
 type EntryCallerInfo struct {
-	PID          int
-	GID          uint64
-	FileName     string
-	Line         int
+	PID int
+	GID uint64
+	// FileName is the name of the file, prefixed with the name of the
+	// directory it lives in (e.g. "rlog/logger.go").
+	FileName string
+	// FullPath is the full path of the file, as recorded by the compiler.
+	FullPath string
+	// Package is the import path of the package (e.g.
+	// "github.com/lab259/rlog/v2").
+	Package string
+	Line    int
+	// FunctionName is the fully qualified name of the function.
 	FunctionName string
+	// ShortFunction is the name of the function without the package (e.g.
+	// "(*logger).Info").
+	ShortFunction string
 }
 
 type Entry struct {
-	Time        string
+	// Time is when the entry was logged, or zero if no time is logged. The
+	// formatters render it according to their TimeFormat.
+	Time        time.Time
 	CallerInfo  EntryCallerInfo
 	Level       Level
 	TraceLevel  int
 	FieldsCache string
 	Fields      FieldsArr
 	Message     string
+	// Stack is the stack trace of the goroutine which logged the entry,
+	// starting at the call site. It is only captured for entries at or above
+	// the RLOG_STACKTRACE_LEVEL.
+	Stack []StackFrame
 }
 
 func (entry *Entry) Reset() {
 	entry.FieldsCache = ""
 	entry.Message = ""
+	entry.CallerInfo = EntryCallerInfo{}
+	entry.Stack = entry.Stack[:0]
 }
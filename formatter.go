@@ -7,6 +7,14 @@ type LogFormatter interface {
 	Format(entry *Entry) []byte
 }
 
+// EntryWriter is implemented by outputs that need more than the formatted line,
+// for example to map the level of an entry to a severity. When the output of a
+// logger implements it, WriteEntry is called instead of Write, and the entry
+// isn't formatted for it.
+type EntryWriter interface {
+	WriteEntry(entry *Entry) error
+}
+
 func AcquireOutput() []byte {
 	return outputPool.Get().([]byte)[0:0]
 }
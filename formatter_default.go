@@ -3,51 +3,75 @@ package rlog
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
-
-	"github.com/fatih/color"
-
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 type Color func(a ...interface{}) string
 
 type defaultFormatter struct {
 	Colors map[Level]Color
-	Width  uint
-}
-
-func clr(file *os.File, value ...color.Attribute) Color {
-	c := color.New(value...)
-	if file != nil && terminal.IsTerminal(int(file.Fd())) {
-		c.EnableColor()
-	} else {
-		c.DisableColor()
-	}
-	return c.Sprint
+	// KeyColor is the color of the field keys. When not set the color of the
+	// level is used.
+	KeyColor Color
+	// CallerColor and TimeColor are the colors of the caller info and the
+	// timestamp. When not set they aren't colored.
+	CallerColor Color
+	TimeColor   Color
+	Width       uint
+	// CallerFormat determines how the caller info is rendered. When not set
+	// the LongCallerFormat is used.
+	CallerFormat *CallerFormat
+	// Template determines the layout of the lines. When not set the time,
+	// level, trace level, caller info, message and fields follow each other.
+	Template *LineTemplate
+	// Multiline tells how line breaks in messages are written.
+	Multiline MultilineMode
+	// TimeFormat determines how the time is rendered. When not set the
+	// DefaultTimeFormat is used.
+	TimeFormat *TimeFormat
 }
 
-func newClrs(file *os.File) map[Level]Color {
-	return map[Level]Color{
-		levelNone:  fmt.Sprint,
-		levelCrit:  clr(file, color.BgRed, color.FgWhite),
-		levelErr:   clr(file, color.FgRed),
-		levelWarn:  clr(file, color.FgYellow),
-		levelInfo:  clr(file, color.FgCyan),
-		levelDebug: clr(file, color.FgMagenta),
-		levelTrace: clr(file, color.FgHiBlack),
-	}
+// NewDefaultFormatter creates the default formatter with the
+// DefaultColorScheme, coloring the output if file is a terminal.
+func NewDefaultFormatter(file *os.File) *defaultFormatter {
+	return NewDefaultFormatterWithColors(file, ColorAuto, DefaultColorScheme())
 }
 
-func NewDefaultFormatter(file *os.File) *defaultFormatter {
-	return &defaultFormatter{
-		Colors: newClrs(file),
+// NewDefaultFormatterWithColors creates the default formatter with the colors
+// of the scheme. The mode tells whether to color the output written to file,
+// which may be nil if the output isn't a file.
+func NewDefaultFormatterWithColors(file *os.File, mode ColorMode, scheme ColorScheme) *defaultFormatter {
+	enabled := mode.enabled(file)
+	formatter := &defaultFormatter{
+		Colors: map[Level]Color{levelNone: fmt.Sprint},
 		Width:  60,
 	}
+	for level, attributes := range scheme.Levels {
+		formatter.Colors[level] = colorFunc(enabled, attributes)
+	}
+	if scheme.Key != nil {
+		formatter.KeyColor = colorFunc(enabled, scheme.Key)
+	}
+	if enabled && len(scheme.Caller) > 0 {
+		formatter.CallerColor = colorFunc(enabled, scheme.Caller)
+	}
+	if enabled && len(scheme.Time) > 0 {
+		formatter.TimeColor = colorFunc(enabled, scheme.Time)
+	}
+	return formatter
 }
 
-func (formatter *defaultFormatter) isTTY() bool {
-	return terminal.IsTerminal(int(os.Stdout.Fd()))
+// withColors returns a copy of the formatter which colors the output written
+// to file as the mode and scheme tell.
+func (formatter *defaultFormatter) withColors(file *os.File, mode ColorMode, scheme ColorScheme) *defaultFormatter {
+	colored := NewDefaultFormatterWithColors(file, mode, scheme)
+	updated := *formatter
+	updated.Colors = colored.Colors
+	updated.KeyColor = colored.KeyColor
+	updated.CallerColor = colored.CallerColor
+	updated.TimeColor = colored.TimeColor
+	return &updated
 }
 
 func (formatter *defaultFormatter) Color(entry *Entry) Color {
@@ -63,8 +87,17 @@ func (formatter *defaultFormatter) FormatField(key string, data interface{}) str
 }
 
 func (formatter *defaultFormatter) formatField(entry *Entry, key string, data interface{}) string {
-	cl := formatter.Color(entry)
+	cl := formatter.KeyColor
+	if cl == nil {
+		cl = formatter.Color(entry)
+	}
 
+	data = resolveValue(data)
+	if fields, ok := nestedFields(data); ok && len(fields) >= 2 {
+		return formatDottedFields(key, fields, formatter.Separator(), func(key string, data interface{}) string {
+			return formatter.formatField(entry, key, data)
+		})
+	}
 	s := fmt.Sprint(data)
 	if !(strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")) && strings.ContainsAny(s, `" `) {
 		replacer := strings.NewReplacer(`"`, `\"`, "\\", "\\\\")
@@ -78,8 +111,8 @@ func (formatter *defaultFormatter) FormatFields(fields FieldsArr) string {
 }
 
 func (formatter *defaultFormatter) formatFields(entry *Entry) string {
-	s := make([]string, len(entry.Fields))
-	for i := 0; i < len(entry.Fields); i += 2 {
+	s := make([]string, len(entry.Fields)/2)
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
 		key, ok := entry.Fields[i].(string)
 		if !ok {
 			key = fmt.Sprint(key)
@@ -96,9 +129,19 @@ func (formatter *defaultFormatter) Separator() string {
 func (formatter *defaultFormatter) Format(entry *Entry) []byte {
 	output := AcquireOutput()
 
+	if formatter.Template != nil {
+		output = formatter.appendTemplate(output, formatter.Template, entry)
+		output = append(output, '\n')
+		return formatter.appendStack(output, entry)
+	}
+
 	// If a time is defined
-	if entry.Time != "" {
-		output = append(output, entry.Time...)
+	if !entry.Time.IsZero() {
+		if formatter.TimeColor != nil {
+			output = append(output, formatter.TimeColor(formatter.TimeFormat.Format(entry.Time))...)
+		} else {
+			output = formatter.TimeFormat.Append(output, entry.Time)
+		}
 		output = append(output, formatter.Separator()...)
 	}
 
@@ -123,7 +166,17 @@ func (formatter *defaultFormatter) Format(entry *Entry) []byte {
 	hasCallerInfoGID := entry.CallerInfo.GID > 0
 
 	if hasCallerInfo {
-		output = append(output, fmt.Sprintf(" [%-5d %s:%d %s] ", entry.CallerInfo.PID, entry.CallerInfo.FileName, entry.CallerInfo.Line, entry.CallerInfo.FunctionName)...)
+		callerFormat := formatter.CallerFormat
+		if callerFormat == nil {
+			callerFormat = LongCallerFormat
+		}
+		output = append(output, " ["...)
+		if formatter.CallerColor != nil {
+			output = append(output, formatter.CallerColor(callerFormat.String(&entry.CallerInfo))...)
+		} else {
+			output = callerFormat.Append(output, &entry.CallerInfo)
+		}
+		output = append(output, "] "...)
 	}
 
 	if hasCallerInfoGID {
@@ -133,10 +186,11 @@ func (formatter *defaultFormatter) Format(entry *Entry) []byte {
 	// Prints message, if it is not empty
 	if entry.Message != "" {
 		output = append(output, formatter.Separator()...)
+		message := formatter.Multiline.format(entry.Message, output)
 		if hasFields || hasCallerInfo {
-			output = append(output, fmt.Sprintf(fmt.Sprintf("%%-%ds", formatter.Width), entry.Message)...)
+			output = append(output, fmt.Sprintf(fmt.Sprintf("%%-%ds", formatter.Width), message)...)
 		} else {
-			output = append(output, entry.Message...)
+			output = append(output, message...)
 		}
 	}
 
@@ -144,5 +198,21 @@ func (formatter *defaultFormatter) Format(entry *Entry) []byte {
 		output = append(output, formatter.Separator()...)
 		output = append(output, formatter.formatFields(entry)...)
 	}
-	return append(output, '\n')
+	output = append(output, '\n')
+	return formatter.appendStack(output, entry)
+}
+
+// appendStack appends the stack trace, which follows the line, indented the
+// same way Go prints the stack of a panic.
+func (formatter *defaultFormatter) appendStack(output []byte, entry *Entry) []byte {
+	for _, frame := range entry.Stack {
+		output = append(output, '\t')
+		output = append(output, frame.Function...)
+		output = append(output, "\n\t\t"...)
+		output = append(output, frame.File...)
+		output = append(output, ':')
+		output = strconv.AppendInt(output, int64(frame.Line), 10)
+		output = append(output, '\n')
+	}
+	return output
 }
@@ -0,0 +1,156 @@
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GELFFormatter renders each entry as GELF 1.1 message, the JSON format of
+// Graylog. The first line of the message is the short_message, and if there's
+// more (or a stack trace), the whole of it is the full_message. The level is
+// mapped to the syslog severities and the fields of the entry, as well as the
+// caller info, are added as additional fields, prefixed with "_".
+type GELFFormatter struct {
+	// Host is sent as host. By default, it's the hostname.
+	Host string
+}
+
+// NewGELFFormatter creates a GELFFormatter for the local host.
+func NewGELFFormatter() *GELFFormatter {
+	host, _ := os.Hostname()
+	return &GELFFormatter{Host: host}
+}
+
+func (formatter *GELFFormatter) Format(entry *Entry) []byte {
+	output := AcquireOutput()
+	output = append(output, `{"version":"1.1","host":`...)
+	output = appendJSONString(output, formatter.Host)
+
+	short := entry.Message
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = short[:i]
+	}
+	if short == "" {
+		// GELF doesn't allow an empty short_message.
+		short = "-"
+	}
+	output = append(output, `,"short_message":`...)
+	output = appendJSONString(output, short)
+	if short != entry.Message || len(entry.Stack) > 0 {
+		full := entry.Message
+		if len(entry.Stack) > 0 {
+			full += "\n" + defaultTextFormatter.formatStack(entry.Stack)
+		}
+		output = append(output, `,"full_message":`...)
+		output = appendJSONString(output, full)
+	}
+
+	now := entryTime(entry)
+	output = append(output, `,"timestamp":`...)
+	output = strconv.AppendInt(output, now.Unix(), 10)
+	output = append(output, '.')
+	millis := now.Nanosecond() / int(time.Millisecond)
+	output = append(output, byte('0'+millis/100), byte('0'+millis/10%10), byte('0'+millis%10))
+
+	severity, ok := syslogSeverities[entry.Level]
+	if !ok {
+		severity = syslogSeverities[levelInfo]
+	}
+	output = append(output, `,"level":`...)
+	output = strconv.AppendInt(output, int64(severity), 10)
+
+	if entry.Level == levelTrace && entry.TraceLevel > notATrace {
+		output = append(output, `,"_trace_level":`...)
+		output = strconv.AppendInt(output, int64(entry.TraceLevel), 10)
+	}
+	if entry.CallerInfo.PID > 0 {
+		output = append(output, `,"_pid":`...)
+		output = strconv.AppendInt(output, int64(entry.CallerInfo.PID), 10)
+		if entry.CallerInfo.GID > 0 {
+			output = append(output, `,"_gid":`...)
+			output = strconv.AppendUint(output, entry.CallerInfo.GID, 10)
+		}
+		output = append(output, `,"_file":`...)
+		output = appendJSONString(output, entry.CallerInfo.FullPath)
+		output = append(output, `,"_line":`...)
+		output = strconv.AppendInt(output, int64(entry.CallerInfo.Line), 10)
+		output = append(output, `,"_function":`...)
+		output = appendJSONString(output, entry.CallerInfo.FunctionName)
+	}
+
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		output = append(output, ',')
+		output = formatter.appendField(output, entry.Fields[i], entry.Fields[i+1])
+	}
+	return append(output, "}\n"...)
+}
+
+func (formatter *GELFFormatter) appendField(output []byte, key interface{}, data interface{}) []byte {
+	k, ok := key.(string)
+	if !ok {
+		k = fmt.Sprint(key)
+	}
+	data = resolveValue(data)
+	if fields, ok := nestedFields(data); ok && len(fields) >= 2 {
+		// GELF has no nested values, so nested fields get dotted names.
+		for i := 0; i+1 < len(fields); i += 2 {
+			if i > 0 {
+				output = append(output, ',')
+			}
+			output = formatter.appendField(output, k+"."+fmt.Sprint(fields[i]), fields[i+1])
+		}
+		return output
+	}
+	output = appendJSONString(output, gelfFieldName(k))
+	output = append(output, ':')
+	// Graylog only knows strings and numbers as values of additional fields.
+	switch data.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return appendJSONValue(output, data)
+	case string:
+		return appendJSONString(output, data.(string))
+	}
+	value := appendJSONValue(nil, data)
+	if len(value) > 0 && value[0] == '"' {
+		return append(output, value...)
+	}
+	return appendJSONString(output, string(value))
+}
+
+func (formatter *GELFFormatter) FormatField(key string, data interface{}) string {
+	return string(formatter.appendField(nil, key, data))
+}
+
+func (formatter *GELFFormatter) FormatFields(fields FieldsArr) string {
+	s := make([]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		s[i/2] = string(formatter.appendField(nil, fields[i], fields[i+1]))
+	}
+	return strings.Join(s, formatter.Separator())
+}
+
+func (formatter *GELFFormatter) Separator() string {
+	return ","
+}
+
+// gelfFieldName turns a field name into the name of an additional field. Those
+// start with "_" and may only contain letters, digits, "_", "." and "-". The
+// name "_id" is reserved by Graylog, so it's renamed to "_id_".
+func gelfFieldName(name string) string {
+	b := make([]byte, 0, len(name)+1)
+	b = append(b, '_')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if string(b) == "_id" {
+		b = append(b, '_')
+	}
+	return string(b)
+}
@@ -0,0 +1,86 @@
+package rlog
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GELFFormatter", func() {
+	formatter := &GELFFormatter{Host: "web-1"}
+
+	format := func(entry *Entry) map[string]interface{} {
+		output := formatter.Format(entry)
+		Expect(output).To(HaveSuffix("}\n"))
+		var message map[string]interface{}
+		Expect(json.Unmarshal(output, &message)).To(Succeed())
+		return message
+	}
+
+	It("should render the required GELF fields", func() {
+		message := format(&Entry{Level: levelWarn, Message: "disk almost full"})
+		Expect(message).To(HaveKeyWithValue("version", "1.1"))
+		Expect(message).To(HaveKeyWithValue("host", "web-1"))
+		Expect(message).To(HaveKeyWithValue("short_message", "disk almost full"))
+		Expect(message).ToNot(HaveKey("full_message"))
+		Expect(message).To(HaveKeyWithValue("level", 4.0))
+		Expect(message["timestamp"]).To(BeNumerically("~", float64(time.Now().UnixNano())/1e9, 1))
+	})
+
+	It("should split multi line messages", func() {
+		message := format(&Entry{Level: levelErr, Message: "failed\nin detail"})
+		Expect(message).To(HaveKeyWithValue("short_message", "failed"))
+		Expect(message).To(HaveKeyWithValue("full_message", "failed\nin detail"))
+		Expect(message).To(HaveKeyWithValue("level", 3.0))
+	})
+
+	It("should add the stack trace to the full message", func() {
+		message := format(&Entry{
+			Level:   levelCrit,
+			Message: "crashed",
+			Stack:   []StackFrame{{Function: "main.main", File: "/src/main.go", Line: 7}},
+		})
+		Expect(message).To(HaveKeyWithValue("short_message", "crashed"))
+		Expect(message["full_message"]).To(HavePrefix("crashed\n"))
+		Expect(message["full_message"]).To(ContainSubstring("/src/main.go:7"))
+	})
+
+	It("should add the fields and caller info as additional fields", func() {
+		message := format(&Entry{
+			Level:      levelTrace,
+			TraceLevel: 3,
+			Message:    "request",
+			CallerInfo: EntryCallerInfo{PID: 10, FullPath: "/src/app.go", Line: 42, FunctionName: "main.handle"},
+			Fields: FieldsArr{
+				"user id", 10,
+				"id", "abc",
+				"ok", true,
+				"err", errors.New("timeout"),
+				"tags", []string{"a"},
+			},
+		})
+		Expect(message).To(HaveKeyWithValue("level", 7.0))
+		Expect(message).To(HaveKeyWithValue("_trace_level", 3.0))
+		Expect(message).To(HaveKeyWithValue("_pid", 10.0))
+		Expect(message).To(HaveKeyWithValue("_file", "/src/app.go"))
+		Expect(message).To(HaveKeyWithValue("_line", 42.0))
+		Expect(message).To(HaveKeyWithValue("_function", "main.handle"))
+		Expect(message).To(HaveKeyWithValue("_user_id", 10.0))
+		Expect(message).To(HaveKeyWithValue("_id_", "abc"))
+		Expect(message).To(HaveKeyWithValue("_ok", "true"))
+		Expect(message).To(HaveKeyWithValue("_err", "timeout"))
+		Expect(message).To(HaveKeyWithValue("_tags", `["a"]`))
+	})
+
+	It("should never send an empty short message", func() {
+		message := format(&Entry{Level: levelInfo})
+		Expect(message).To(HaveKeyWithValue("short_message", "-"))
+	})
+
+	It("should format the fields", func() {
+		Expect(formatter.FormatFields(FieldsArr{"a", 1, "b", "x"})).To(Equal(`"_a":1,"_b":"x"`))
+	})
+})
@@ -0,0 +1,291 @@
+package rlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONFormatter renders each entry as a single line JSON object. The fields of
+// the entry are added as keys of that object, next to "time", "level",
+// "trace_level", "msg", "caller" and "stack". Fields with one of these keys
+// are written with a "fields." prefix, e.g. "fields.level", so that the keys
+// of the object stay unique.
+type JSONFormatter struct {
+	// TimeFormat determines how the time is rendered. When not set the
+	// DefaultTimeFormat is used. Times since the Unix epoch are rendered as
+	// numbers, all others as strings.
+	TimeFormat *TimeFormat
+}
+
+var (
+	jsonFormatterTimeKey       = []byte(`"time":`)
+	jsonFormatterLevelKey      = []byte(`"level":`)
+	jsonFormatterTraceLevelKey = []byte(`"trace_level":`)
+	jsonFormatterMessageKey    = []byte(`"msg":`)
+	jsonFormatterCallerKey     = []byte(`"caller":{`)
+	jsonFormatterStackKey      = []byte(`"stack":[`)
+	jsonFormatterSeparator     = byte(',')
+	jsonFormatterColon         = byte(':')
+	jsonFormatterLineEnding    = []byte("}\n")
+)
+
+const jsonHex = "0123456789abcdef"
+
+// jsonReservedKeys are the keys the JSONFormatter writes for the entry itself.
+var jsonReservedKeys = map[string]bool{
+	"time":        true,
+	"level":       true,
+	"trace_level": true,
+	"msg":         true,
+	"caller":      true,
+	"stack":       true,
+}
+
+func (formatter *JSONFormatter) Format(entry *Entry) []byte {
+	output := AcquireOutput()
+	output = append(output, '{')
+
+	if !entry.Time.IsZero() {
+		output = append(output, jsonFormatterTimeKey...)
+		if formatter.TimeFormat.Numeric() {
+			output = formatter.TimeFormat.Append(output, entry.Time)
+		} else {
+			output = appendJSONTime(output, formatter.TimeFormat, entry.Time)
+		}
+		output = append(output, jsonFormatterSeparator)
+	}
+
+	output = append(output, jsonFormatterLevelKey...)
+	output = appendJSONString(output, entry.Level.String())
+	if entry.Level == levelTrace && entry.TraceLevel > notATrace {
+		output = append(output, jsonFormatterSeparator)
+		output = append(output, jsonFormatterTraceLevelKey...)
+		output = strconv.AppendInt(output, int64(entry.TraceLevel), 10)
+	}
+
+	output = append(output, jsonFormatterSeparator)
+	output = append(output, jsonFormatterMessageKey...)
+	output = appendJSONString(output, entry.Message)
+
+	if entry.CallerInfo.PID > 0 {
+		output = append(output, jsonFormatterSeparator)
+		output = append(output, jsonFormatterCallerKey...)
+		output = formatter.appendCallerInfo(output, &entry.CallerInfo)
+		output = append(output, '}')
+	}
+
+	if len(entry.Stack) > 0 {
+		output = append(output, jsonFormatterSeparator)
+		output = append(output, jsonFormatterStackKey...)
+		for i, frame := range entry.Stack {
+			if i > 0 {
+				output = append(output, jsonFormatterSeparator)
+			}
+			output = append(output, `{"function":`...)
+			output = appendJSONString(output, frame.Function)
+			output = append(output, `,"file":`...)
+			output = appendJSONString(output, frame.File)
+			output = append(output, `,"line":`...)
+			output = strconv.AppendInt(output, int64(frame.Line), 10)
+			output = append(output, '}')
+		}
+		output = append(output, ']')
+	}
+
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		output = append(output, jsonFormatterSeparator)
+		output = formatter.appendField(output, entry.Fields[i], entry.Fields[i+1])
+	}
+
+	return append(output, jsonFormatterLineEnding...)
+}
+
+// appendCallerInfo adds every part of the caller info as its own key.
+func (formatter *JSONFormatter) appendCallerInfo(output []byte, info *EntryCallerInfo) []byte {
+	output = append(output, `"pid":`...)
+	output = strconv.AppendInt(output, int64(info.PID), 10)
+	if info.GID > 0 {
+		output = append(output, `,"gid":`...)
+		output = strconv.AppendUint(output, info.GID, 10)
+	}
+	output = append(output, `,"file":`...)
+	output = appendJSONString(output, info.FileName)
+	output = append(output, `,"path":`...)
+	output = appendJSONString(output, info.FullPath)
+	output = append(output, `,"line":`...)
+	output = strconv.AppendInt(output, int64(info.Line), 10)
+	output = append(output, `,"package":`...)
+	output = appendJSONString(output, info.Package)
+	output = append(output, `,"function":`...)
+	output = appendJSONString(output, info.FunctionName)
+	output = append(output, `,"short_function":`...)
+	output = appendJSONString(output, info.ShortFunction)
+	return output
+}
+
+func (formatter *JSONFormatter) appendField(output []byte, key interface{}, data interface{}) []byte {
+	k, ok := key.(string)
+	if !ok {
+		k = fmt.Sprint(key)
+	}
+	if jsonReservedKeys[k] {
+		k = "fields." + k
+	}
+	output = appendJSONString(output, k)
+	output = append(output, jsonFormatterColon)
+	return appendJSONValue(output, data)
+}
+
+func (formatter *JSONFormatter) FormatField(key string, data interface{}) string {
+	return string(formatter.appendField(nil, key, data))
+}
+
+func (formatter *JSONFormatter) FormatFields(fields FieldsArr) string {
+	s := make([]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		s[i/2] = string(formatter.appendField(nil, fields[i], fields[i+1]))
+	}
+	return strings.Join(s, formatter.Separator())
+}
+
+func (formatter *JSONFormatter) Separator() string {
+	return ","
+}
+
+// appendJSONValue encodes a field value. Basic types are encoded directly,
+// Valuers as what they return, nested fields as objects, errors and Stringers
+// as their string representation and anything else is handed to
+// encoding/json. Values that can't be encoded end up as the string
+// fmt.Sprint produces for them.
+func appendJSONValue(output []byte, data interface{}) []byte {
+	switch v := data.(type) {
+	case nil:
+		return append(output, "null"...)
+	case string:
+		return appendJSONString(output, v)
+	case bool:
+		return strconv.AppendBool(output, v)
+	case int:
+		return strconv.AppendInt(output, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(output, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(output, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(output, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(output, v, 10)
+	case uint:
+		return strconv.AppendUint(output, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(output, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(output, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(output, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(output, v, 10)
+	case float32:
+		return appendJSONFloat(output, float64(v), 32)
+	case float64:
+		return appendJSONFloat(output, v, 64)
+	case Valuer:
+		return appendJSONValue(output, resolveValue(v))
+	case FieldsArr, Fields:
+		fields, _ := nestedFields(v)
+		output = append(output, '{')
+		for i := 0; i+1 < len(fields); i += 2 {
+			if i > 0 {
+				output = append(output, jsonFormatterSeparator)
+			}
+			key, ok := fields[i].(string)
+			if !ok {
+				key = fmt.Sprint(fields[i])
+			}
+			output = appendJSONString(output, key)
+			output = append(output, jsonFormatterColon)
+			output = appendJSONValue(output, fields[i+1])
+		}
+		return append(output, '}')
+	case error:
+		return appendJSONString(output, v.Error())
+	case fmt.Stringer:
+		return appendJSONString(output, v.String())
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return appendJSONString(output, fmt.Sprint(data))
+	}
+	return append(output, b...)
+}
+
+// appendJSONFloat encodes a float. NaN and infinities have no representation
+// in JSON, so they are encoded as strings.
+func appendJSONFloat(output []byte, f float64, bitSize int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendJSONString(output, strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+	return strconv.AppendFloat(output, f, 'g', -1, bitSize)
+}
+
+// appendJSONString encodes s as a JSON string, escaping quotes, backslashes
+// and control characters. Invalid UTF-8 is replaced by U+FFFD.
+func appendJSONString(output []byte, s string) []byte {
+	output = append(output, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			output = append(output, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				output = append(output, '\\', c)
+			case '\n':
+				output = append(output, '\\', 'n')
+			case '\r':
+				output = append(output, '\\', 'r')
+			case '\t':
+				output = append(output, '\\', 't')
+			default:
+				output = append(output, '\\', 'u', '0', '0', jsonHex[c>>4], jsonHex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			output = append(output, s[start:i]...)
+			output = append(output, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	output = append(output, s[start:]...)
+	return append(output, '"')
+}
+
+// appendJSONTime appends the timestamp t as JSON string. It is rendered in
+// place, and only escaped if the layout requires it.
+func appendJSONTime(output []byte, timeFormat *TimeFormat, t time.Time) []byte {
+	start := len(output)
+	output = append(output, '"')
+	output = timeFormat.Append(output, t)
+	for _, c := range output[start+1:] {
+		if c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			return appendJSONString(output[:start], string(output[start+1:]))
+		}
+	}
+	return append(output, '"')
+}
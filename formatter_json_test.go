@@ -0,0 +1,98 @@
+package rlog
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Formatter", func() {
+	Describe("JSONFormatter", func() {
+		It("should format a field", func() {
+			f := &JSONFormatter{}
+			Expect(f.FormatField("key", "value")).To(Equal(`"key":"value"`))
+			Expect(f.FormatField("key", 10)).To(Equal(`"key":10`))
+			Expect(f.FormatField("key", errors.New("failed"))).To(Equal(`"key":"failed"`))
+			Expect(f.FormatField("key", math.NaN())).To(Equal(`"key":"NaN"`))
+			Expect(f.FormatField("key", []int{1, 2})).To(Equal(`"key":[1,2]`))
+		})
+
+		It("should format a field escaping values", func() {
+			f := &JSONFormatter{}
+			Expect(f.FormatField("key", "value with \"quotes\"\n\x01")).To(Equal(`"key":"value with \"quotes\"\n\u0001"`))
+		})
+
+		It("should format fields", func() {
+			f := &JSONFormatter{}
+			Expect(f.FormatFields(FieldsArr{
+				"field1", "value1",
+				"field2", true,
+			})).To(Equal(`"field1":"value1","field2":true`))
+		})
+
+		It("should format an entry", func() {
+			f := &JSONFormatter{}
+			line := f.Format(&Entry{
+				Time:       time.Date(2019, 4, 12, 10, 0, 0, 0, time.UTC),
+				Level:      levelTrace,
+				TraceLevel: 3,
+				Message:    "this is a TRACE",
+				Fields:     FieldsArr{"var1", "value1"},
+			})
+			Expect(string(line)).To(Equal(`{"time":"2019-04-12T10:00:00Z","level":"TRACE","trace_level":3,"msg":"this is a TRACE","var1":"value1"}` + "\n"))
+		})
+
+		It("should prefix the fields named like the keys of the entry", func() {
+			f := &JSONFormatter{}
+			line := f.Format(&Entry{
+				Level:      levelInfo,
+				Message:    "this is a INFO",
+				CallerInfo: EntryCallerInfo{PID: 42},
+				Fields: FieldsArr{
+					"level", "high",
+					"msg", "user input",
+					"time", 10,
+					"caller", "api",
+					"user", Fields{"level": 3},
+				},
+			})
+			Expect(string(line)).To(HaveSuffix(`,"fields.level":"high","fields.msg":"user input","fields.time":10,"fields.caller":"api","user":{"level":3}}` + "\n"))
+			var entry map[string]interface{}
+			Expect(json.Unmarshal(line, &entry)).To(Succeed())
+			Expect(entry).To(HaveKeyWithValue("level", "INFO"))
+			Expect(entry).To(HaveKeyWithValue("msg", "this is a INFO"))
+		})
+
+		It("should carry each part of the caller info as its own key", func() {
+			f := &JSONFormatter{}
+			line := f.Format(&Entry{
+				Level:   levelInfo,
+				Message: "this is a INFO",
+				CallerInfo: EntryCallerInfo{
+					PID:           42,
+					FileName:      "rlog/logger.go",
+					FullPath:      "/src/rlog/logger.go",
+					Package:       "github.com/lab259/rlog/v2",
+					Line:          123,
+					FunctionName:  "github.com/lab259/rlog/v2.(*logger).Info",
+					ShortFunction: "(*logger).Info",
+				},
+			})
+			var decoded map[string]interface{}
+			Expect(json.Unmarshal(line, &decoded)).To(Succeed())
+			Expect(decoded).To(HaveKeyWithValue("caller", map[string]interface{}{
+				"pid":            float64(42),
+				"file":           "rlog/logger.go",
+				"path":           "/src/rlog/logger.go",
+				"line":           float64(123),
+				"package":        "github.com/lab259/rlog/v2",
+				"function":       "github.com/lab259/rlog/v2.(*logger).Info",
+				"short_function": "(*logger).Info",
+			}))
+		})
+	})
+})
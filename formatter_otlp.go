@@ -0,0 +1,263 @@
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The name under which rlog reports itself as instrumentation scope.
+const otlpScopeName = "github.com/lab259/rlog"
+
+// Translation from log level to OpenTelemetry severity number.
+var otlpSeverities = map[Level]int{
+	levelCrit:  21, // FATAL
+	levelErr:   17, // ERROR
+	levelWarn:  13, // WARN
+	levelInfo:  9,  // INFO
+	levelDebug: 5,  // DEBUG
+	levelTrace: 1,  // TRACE
+}
+
+// OTLPFormatter renders each entry as a line of OTLP-JSON, following the
+// OpenTelemetry logs data model: one ExportLogsServiceRequest holding a single
+// log record, as read by the otlpjsonfile receiver of the collector. The
+// message is the body, the fields of the entry are the attributes and the
+// trace_id and span_id fields (see WithContext) become the trace context of
+// the record.
+type OTLPFormatter struct {
+	// Resource holds the resource attributes as key/value pairs.
+	Resource FieldsArr
+}
+
+// NewOTLPFormatter creates an OTLPFormatter with the default resource
+// attributes service.name, host.name and process.pid, followed by the given
+// ones. Later attributes replace earlier ones with the same key.
+func NewOTLPFormatter(resource FieldsArr) *OTLPFormatter {
+	host, _ := os.Hostname()
+	return &OTLPFormatter{
+		Resource: mergeOTLPResource(FieldsArr{
+			"service.name", filepath.Base(os.Args[0]),
+			"host.name", host,
+			"process.pid", os.Getpid(),
+		}, resource),
+	}
+}
+
+// ParseOTLPResource translates the RLOG_OTLP_RESOURCE setting, a comma
+// separated list of key=value pairs, into resource attributes.
+func ParseOTLPResource(s string) (FieldsArr, error) {
+	var resource FieldsArr
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 || strings.TrimSpace(tokens[0]) == "" {
+			return nil, fmt.Errorf("resource attribute '%s' is malformed, expected <key>=<value>", pair)
+		}
+		resource = append(resource, strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1]))
+	}
+	return resource, nil
+}
+
+// mergeOTLPResource appends the attributes to the defaults, replacing the
+// defaults with the same key.
+func mergeOTLPResource(defaults FieldsArr, attributes FieldsArr) FieldsArr {
+	merged := append(FieldsArr{}, defaults...)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		replaced := false
+		for j := 0; j+1 < len(merged); j += 2 {
+			if merged[j] == attributes[i] {
+				merged[j+1] = attributes[i+1]
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, attributes[i], attributes[i+1])
+		}
+	}
+	return merged
+}
+
+func (formatter *OTLPFormatter) Format(entry *Entry) []byte {
+	output := AcquireOutput()
+	output = formatter.appendRequestStart(output)
+	output = formatter.appendRecord(output, entry, time.Now())
+	output = append(output, otlpRequestEnd...)
+	return append(output, '\n')
+}
+
+// The end of an ExportLogsServiceRequest, after the log records.
+const otlpRequestEnd = "]}]}]}"
+
+// appendRequestStart starts an ExportLogsServiceRequest with the resource and
+// scope, up to the list of log records.
+func (formatter *OTLPFormatter) appendRequestStart(output []byte) []byte {
+	output = append(output, `{"resourceLogs":[{"resource":{"attributes":[`...)
+	output = appendOTLPAttributes(output, formatter.Resource)
+	output = append(output, `]},"scopeLogs":[{"scope":{"name":`...)
+	output = appendJSONString(output, otlpScopeName)
+	return append(output, `},"logRecords":[`...)
+}
+
+// appendRecord renders the entry as LogRecord, observed at now.
+func (formatter *OTLPFormatter) appendRecord(output []byte, entry *Entry, now time.Time) []byte {
+	// 64 bit integers are strings in the JSON mapping of protobuf.
+	output = append(output, `{"timeUnixNano":"`...)
+	output = strconv.AppendInt(output, entryTime(entry).UnixNano(), 10)
+	output = append(output, `","observedTimeUnixNano":"`...)
+	output = strconv.AppendInt(output, now.UnixNano(), 10)
+	output = append(output, `","severityNumber":`...)
+	severity, ok := otlpSeverities[entry.Level]
+	if !ok {
+		severity = otlpSeverities[levelInfo]
+	}
+	output = strconv.AppendInt(output, int64(severity), 10)
+	output = append(output, `,"severityText":`...)
+	output = appendJSONString(output, entry.Level.String())
+	output = append(output, `,"body":{"stringValue":`...)
+	output = appendJSONString(output, entry.Message)
+	output = append(output, `},"attributes":[`...)
+
+	first := true
+	attribute := func(key string) {
+		if !first {
+			output = append(output, ',')
+		}
+		first = false
+		output = append(output, `{"key":`...)
+		output = appendJSONString(output, key)
+		output = append(output, `,"value":`...)
+	}
+	if entry.Level == levelTrace && entry.TraceLevel > notATrace {
+		attribute("rlog.trace_level")
+		output = appendOTLPValue(output, entry.TraceLevel)
+		output = append(output, '}')
+	}
+	if entry.CallerInfo.PID > 0 {
+		attribute("code.filepath")
+		output = appendOTLPValue(output, entry.CallerInfo.FullPath)
+		output = append(output, '}')
+		attribute("code.lineno")
+		output = appendOTLPValue(output, entry.CallerInfo.Line)
+		output = append(output, '}')
+		attribute("code.function")
+		output = appendOTLPValue(output, entry.CallerInfo.FunctionName)
+		output = append(output, '}')
+		if entry.CallerInfo.GID > 0 {
+			attribute("thread.id")
+			output = appendOTLPValue(output, entry.CallerInfo.GID)
+			output = append(output, '}')
+		}
+	}
+	if len(entry.Stack) > 0 {
+		attribute("exception.stacktrace")
+		output = appendOTLPValue(output, defaultTextFormatter.formatStack(entry.Stack))
+		output = append(output, '}')
+	}
+
+	var traceID, spanID string
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		key, ok := entry.Fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(entry.Fields[i])
+		}
+		switch key {
+		case TraceIDField:
+			traceID = fmt.Sprint(entry.Fields[i+1])
+			continue
+		case SpanIDField:
+			spanID = fmt.Sprint(entry.Fields[i+1])
+			continue
+		}
+		attribute(key)
+		output = appendOTLPValue(output, entry.Fields[i+1])
+		output = append(output, '}')
+	}
+	output = append(output, ']')
+
+	if traceID != "" {
+		output = append(output, `,"traceId":`...)
+		output = appendJSONString(output, traceID)
+	}
+	if spanID != "" {
+		output = append(output, `,"spanId":`...)
+		output = appendJSONString(output, spanID)
+	}
+	return append(output, '}')
+}
+
+// appendOTLPAttributes renders key/value pairs as KeyValue list.
+func appendOTLPAttributes(output []byte, fields FieldsArr) []byte {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if i > 0 {
+			output = append(output, ',')
+		}
+		output = append(output, `{"key":`...)
+		output = appendJSONString(output, fmt.Sprint(fields[i]))
+		output = append(output, `,"value":`...)
+		output = appendOTLPValue(output, fields[i+1])
+		output = append(output, '}')
+	}
+	return output
+}
+
+// appendOTLPValue renders a value as AnyValue. Values without a counterpart
+// are sent as strings.
+func appendOTLPValue(output []byte, data interface{}) []byte {
+	switch v := data.(type) {
+	case string:
+		output = append(output, `{"stringValue":`...)
+		output = appendJSONString(output, v)
+	case bool:
+		output = append(output, `{"boolValue":`...)
+		output = strconv.AppendBool(output, v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		output = append(output, `{"intValue":"`...)
+		output = appendJSONValue(output, v)
+		output = append(output, '"')
+	case uint64:
+		if v > 1<<63-1 {
+			return appendOTLPValue(output, strconv.FormatUint(v, 10))
+		}
+		output = append(output, `{"intValue":"`...)
+		output = strconv.AppendUint(output, v, 10)
+		output = append(output, '"')
+	case float32, float64:
+		output = append(output, `{"doubleValue":`...)
+		output = appendJSONValue(output, v)
+	case Valuer:
+		return appendOTLPValue(output, resolveValue(v))
+	case FieldsArr, Fields:
+		fields, _ := nestedFields(v)
+		output = append(output, `{"kvlistValue":{"values":[`...)
+		output = appendOTLPAttributes(output, fields)
+		output = append(output, "]}"...)
+	default:
+		output = append(output, `{"stringValue":`...)
+		value := appendJSONValue(nil, data)
+		if len(value) > 0 && value[0] == '"' {
+			output = append(output, value...)
+		} else {
+			output = appendJSONString(output, string(value))
+		}
+	}
+	return append(output, '}')
+}
+
+func (formatter *OTLPFormatter) FormatField(key string, data interface{}) string {
+	return string(appendOTLPAttributes(nil, FieldsArr{key, data}))
+}
+
+func (formatter *OTLPFormatter) FormatFields(fields FieldsArr) string {
+	return string(appendOTLPAttributes(nil, fields))
+}
+
+func (formatter *OTLPFormatter) Separator() string {
+	return ","
+}
@@ -0,0 +1,141 @@
+package rlog
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// otlpRequest mirrors the parts of an ExportLogsServiceRequest the tests look
+// at.
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []otlpRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpRecord struct {
+	TimeUnixNano   string                 `json:"timeUnixNano"`
+	SeverityNumber int                    `json:"severityNumber"`
+	SeverityText   string                 `json:"severityText"`
+	Body           map[string]interface{} `json:"body"`
+	Attributes     []otlpKeyValue         `json:"attributes"`
+	TraceID        string                 `json:"traceId"`
+	SpanID         string                 `json:"spanId"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// otlpAttributes turns a KeyValue list into a map.
+func otlpAttributes(list []otlpKeyValue) map[string]map[string]interface{} {
+	attributes := make(map[string]map[string]interface{})
+	for _, kv := range list {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+var _ = Describe("OTLPFormatter", func() {
+	formatter := NewOTLPFormatter(FieldsArr{"service.name", "api", "deployment.environment", "prod"})
+
+	format := func(entry *Entry) (otlpRequest, otlpRecord) {
+		output := formatter.Format(entry)
+		Expect(output).To(HaveSuffix("}\n"))
+		var request otlpRequest
+		Expect(json.Unmarshal(output, &request)).To(Succeed())
+		Expect(request.ResourceLogs).To(HaveLen(1))
+		Expect(request.ResourceLogs[0].ScopeLogs).To(HaveLen(1))
+		Expect(request.ResourceLogs[0].ScopeLogs[0].LogRecords).To(HaveLen(1))
+		return request, request.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	}
+
+	It("should render the resource and scope", func() {
+		request, _ := format(&Entry{Level: levelInfo, Message: "hello"})
+		resource := otlpAttributes(request.ResourceLogs[0].Resource.Attributes)
+		Expect(resource).To(HaveKeyWithValue("service.name", map[string]interface{}{"stringValue": "api"}))
+		Expect(resource).To(HaveKeyWithValue("deployment.environment", map[string]interface{}{"stringValue": "prod"}))
+		Expect(resource).To(HaveKeyWithValue("process.pid", map[string]interface{}{"intValue": strconv.Itoa(os.Getpid())}))
+		Expect(request.ResourceLogs[0].ScopeLogs[0].Scope.Name).To(Equal("github.com/lab259/rlog"))
+	})
+
+	It("should map the levels to severities", func() {
+		for level, severity := range map[Level]int{
+			levelCrit: 21, levelErr: 17, levelWarn: 13, levelInfo: 9, levelDebug: 5, levelTrace: 1,
+		} {
+			_, record := format(&Entry{Level: level, Message: "hello"})
+			Expect(record.SeverityNumber).To(Equal(severity))
+			Expect(record.SeverityText).To(Equal(level.String()))
+		}
+	})
+
+	It("should render the body, fields and trace context", func() {
+		_, record := format(&Entry{
+			Level:   levelWarn,
+			Message: "slow request",
+			Fields: FieldsArr{
+				"path", "/users",
+				"status", 200,
+				"ratio", 0.5,
+				"cached", false,
+				"err", errors.New("timeout"),
+				TraceIDField, "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDField, "00f067aa0ba902b7",
+			},
+		})
+		Expect(record.TimeUnixNano).To(MatchRegexp(`^[0-9]{19}$`))
+		Expect(record.Body).To(Equal(map[string]interface{}{"stringValue": "slow request"}))
+		attributes := otlpAttributes(record.Attributes)
+		Expect(attributes).To(HaveKeyWithValue("path", map[string]interface{}{"stringValue": "/users"}))
+		Expect(attributes).To(HaveKeyWithValue("status", map[string]interface{}{"intValue": "200"}))
+		Expect(attributes).To(HaveKeyWithValue("ratio", map[string]interface{}{"doubleValue": 0.5}))
+		Expect(attributes).To(HaveKeyWithValue("cached", map[string]interface{}{"boolValue": false}))
+		Expect(attributes).To(HaveKeyWithValue("err", map[string]interface{}{"stringValue": "timeout"}))
+		Expect(attributes).ToNot(HaveKey(TraceIDField))
+		Expect(record.TraceID).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(record.SpanID).To(Equal("00f067aa0ba902b7"))
+	})
+
+	It("should render the caller info and stack trace as attributes", func() {
+		_, record := format(&Entry{
+			Level:      levelErr,
+			Message:    "failed",
+			CallerInfo: EntryCallerInfo{PID: 10, GID: 3, FullPath: "/src/app.go", Line: 42, FunctionName: "main.handle"},
+			Stack:      []StackFrame{{Function: "main.main", File: "/src/main.go", Line: 7}},
+		})
+		attributes := otlpAttributes(record.Attributes)
+		Expect(attributes).To(HaveKeyWithValue("code.filepath", map[string]interface{}{"stringValue": "/src/app.go"}))
+		Expect(attributes).To(HaveKeyWithValue("code.lineno", map[string]interface{}{"intValue": "42"}))
+		Expect(attributes).To(HaveKeyWithValue("code.function", map[string]interface{}{"stringValue": "main.handle"}))
+		Expect(attributes).To(HaveKeyWithValue("thread.id", map[string]interface{}{"intValue": "3"}))
+		Expect(attributes["exception.stacktrace"]["stringValue"]).To(ContainSubstring("/src/main.go:7"))
+		Expect(record.TraceID).To(BeEmpty())
+	})
+
+	Describe("ParseOTLPResource", func() {
+		It("should parse key=value pairs", func() {
+			resource, err := ParseOTLPResource("service.name=api, deployment.environment=prod")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resource).To(Equal(FieldsArr{"service.name", "api", "deployment.environment", "prod"}))
+		})
+
+		It("should fail with a malformed pair", func() {
+			_, err := ParseOTLPResource("service.name")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
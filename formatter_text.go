@@ -8,12 +8,26 @@ import (
 	"sync"
 )
 
-type TextFormatter struct{}
+// TextFormatter renders entries as key=value pairs. Entries with caller info
+// get a caller field after the level.
+type TextFormatter struct {
+	// CallerFormat determines how the caller info of the caller field is
+	// rendered. When not set the LongCallerFormat is used.
+	CallerFormat *CallerFormat
+	// Multiline tells how line breaks in messages are written.
+	Multiline MultilineMode
+	// TimeFormat determines how the time is rendered. When not set the
+	// DefaultTimeFormat is used.
+	TimeFormat *TimeFormat
+}
 
 var (
 	textFormatterDatePrefix         = []byte(`date="`)
 	textFormatterLevelPrefix        = []byte(`level=`)
 	textFormatterMessagePrefix      = []byte(`msg="`)
+	textFormatterCallerPrefix       = []byte(`caller="`)
+	textFormatterStackPrefix        = []byte(`stack="`)
+	textFormatterStackReplacer      = strings.NewReplacer(`"`, `\"`, "\\", "\\\\", "\n", `\n`, "\t", `\t`)
 	textFormatterSeparator          = byte(' ')
 	textFormatterQuoteWithSeparator = []byte(`" `)
 	textFormatterQuote              = byte('"')
@@ -30,9 +44,9 @@ var (
 func (formatter *TextFormatter) Format(entry *Entry) []byte {
 	output := AcquireOutput()
 
-	if entry.Time != "" {
+	if !entry.Time.IsZero() {
 		output = append(output, textFormatterDatePrefix...)
-		output = append(output, entry.Time...)
+		output = formatter.TimeFormat.Append(output, entry.Time)
 		output = append(output, textFormatterQuoteWithSeparator...)
 	}
 	output = append(output, textFormatterLevelPrefix...)
@@ -47,20 +61,53 @@ func (formatter *TextFormatter) Format(entry *Entry) []byte {
 		output = append(output, ')')
 	}
 
+	if entry.CallerInfo.PID > 0 {
+		callerFormat := formatter.CallerFormat
+		if callerFormat == nil {
+			callerFormat = LongCallerFormat
+		}
+		output = append(output, textFormatterSeparator)
+		output = append(output, textFormatterCallerPrefix...)
+		output = callerFormat.Append(output, &entry.CallerInfo)
+		output = append(output, textFormatterQuote)
+	}
+
 	if entry.FieldsCache != "" {
 		output = append(output, textFormatterSeparator)
 		output = append(output, entry.FieldsCache...)
 	}
 
+	if len(entry.Stack) > 0 {
+		output = append(output, textFormatterSeparator)
+		output = append(output, textFormatterStackPrefix...)
+		output = append(output, textFormatterStackReplacer.Replace(formatter.formatStack(entry.Stack))...)
+		output = append(output, textFormatterQuote)
+	}
+
 	if entry.Message != "" {
 		output = append(output, textFormatterSeparator)
 		output = append(output, textFormatterMessagePrefix...)
-		output = append(output, bytes.Replace([]byte(entry.Message), textFormatterQuoteArr, textFormatterQuoteEscaped, -1)...)
+		message := formatter.Multiline.format(entry.Message, output)
+		output = append(output, bytes.Replace([]byte(message), textFormatterQuoteArr, textFormatterQuoteEscaped, -1)...)
 	}
 	return append(output, textFormatterQuote, textFormatterLineEnding)
 }
 
+// formatStack renders the stack trace with one frame per line. The caller
+// escapes the line breaks, so the entry stays on a single line.
+func (formatter *TextFormatter) formatStack(stack []StackFrame) string {
+	s := make([]string, len(stack))
+	for i, frame := range stack {
+		s[i] = fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+	}
+	return strings.Join(s, "\n")
+}
+
 func (formatter *TextFormatter) FormatField(key string, data interface{}) string {
+	data = resolveValue(data)
+	if fields, ok := nestedFields(data); ok && len(fields) >= 2 {
+		return formatDottedFields(key, fields, formatter.Separator(), formatter.FormatField)
+	}
 	s := fmt.Sprint(data)
 	if !(strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")) && strings.ContainsAny(s, `" `) {
 		replacer := strings.NewReplacer(`"`, `\"`, "\\", "\\\\")
@@ -0,0 +1,156 @@
+package rlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The limits of the chunked GELF transport.
+const (
+	defaultGELFAddress   = "localhost:12201"
+	defaultGELFChunkSize = 1420
+	gelfChunkHeaderSize  = 12
+	gelfMaxChunks        = 128
+)
+
+// GELFOptions configures a GELFWriter.
+type GELFOptions struct {
+	// Address of the Graylog GELF UDP input. By default, it's
+	// localhost:12201.
+	Address string
+	// Compression is "none" (default), "gzip" or "zlib".
+	Compression string
+	// ChunkSize is the maximum size of a datagram. Larger messages are split
+	// into chunks. By default, it's 1420 bytes, which fits into the usual
+	// MTU.
+	ChunkSize int
+	// Host is sent as host. By default, it's the hostname.
+	Host string
+}
+
+// GELFWriter sends entries as GELF messages to a Graylog UDP input, chunking
+// those which don't fit into a datagram. It implements EntryWriter, so it can
+// be used with SetOutput.
+type GELFWriter struct {
+	mutex     sync.Mutex
+	options   GELFOptions
+	formatter *GELFFormatter
+	conn      net.Conn
+	messageID uint64
+	buffer    bytes.Buffer
+	// compressor wraps the buffer to compress a message. It's nil without
+	// compression.
+	compressor func(io.Writer) io.WriteCloser
+}
+
+// NewGELFWriter prepares sending messages to the GELF input in the options.
+func NewGELFWriter(options GELFOptions) (*GELFWriter, error) {
+	if options.Address == "" {
+		options.Address = defaultGELFAddress
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = defaultGELFChunkSize
+	}
+	if options.ChunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("GELF chunk size %d is too small", options.ChunkSize)
+	}
+	w := &GELFWriter{
+		options:   options,
+		formatter: NewGELFFormatter(),
+		// Message IDs only need to be unique for a few seconds.
+		messageID: uint64(time.Now().UnixNano()),
+	}
+	if options.Host != "" {
+		w.formatter.Host = options.Host
+	}
+	switch strings.ToLower(options.Compression) {
+	case "", "none":
+	case "gzip":
+		w.compressor = func(out io.Writer) io.WriteCloser { return gzip.NewWriter(out) }
+	case "zlib":
+		w.compressor = func(out io.Writer) io.WriteCloser { return zlib.NewWriter(out) }
+	default:
+		return nil, fmt.Errorf("GELF compression '%s' is unknown", options.Compression)
+	}
+	var err error
+	w.conn, err = net.Dial("udp", options.Address)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteEntry sends the entry as GELF message.
+func (w *GELFWriter) WriteEntry(entry *Entry) error {
+	msg := w.formatter.Format(entry)
+	defer ReleaseOutput(msg)
+	return w.send(msg)
+}
+
+// Write sends p, which has to be a GELF message, for example formatted by the
+// GELFFormatter.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	if err := w.send(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the socket.
+func (w *GELFWriter) Close() error {
+	return w.conn.Close()
+}
+
+// send compresses the message and sends it in as many chunks as needed.
+func (w *GELFWriter) send(msg []byte) error {
+	msg = bytes.TrimRight(msg, "\n")
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.compressor != nil {
+		w.buffer.Reset()
+		compressor := w.compressor(&w.buffer)
+		if _, err := compressor.Write(msg); err != nil {
+			compressor.Close()
+			return err
+		}
+		if err := compressor.Close(); err != nil {
+			return err
+		}
+		msg = w.buffer.Bytes()
+	}
+
+	if len(msg) <= w.options.ChunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+
+	size := w.options.ChunkSize - gelfChunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > gelfMaxChunks {
+		return fmt.Errorf("GELF message of %d bytes needs more than %d chunks", len(msg), gelfMaxChunks)
+	}
+	w.messageID++
+	chunk := make([]byte, w.options.ChunkSize)
+	// Magic bytes, message ID, sequence number and count.
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	binary.BigEndian.PutUint64(chunk[2:10], w.messageID)
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		n := copy(chunk[gelfChunkHeaderSize:], msg[i*size:])
+		if _, err := w.conn.Write(chunk[:gelfChunkHeaderSize+n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
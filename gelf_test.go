@@ -0,0 +1,178 @@
+package rlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GELF", func() {
+	var conn net.PacketConn
+
+	BeforeEach(func() {
+		var err error
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		conn.Close()
+	})
+
+	// next receives the next message, putting it back together from its
+	// chunks and decompressing it.
+	next := func() map[string]interface{} {
+		var (
+			chunks [][]byte
+			count  int
+			id     []byte
+		)
+		buf := make([]byte, 65536)
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).ToNot(HaveOccurred())
+			datagram := append([]byte(nil), buf[:n]...)
+			if datagram[0] != 0x1e || datagram[1] != 0x0f {
+				chunks = [][]byte{datagram}
+				break
+			}
+			if id == nil {
+				id = datagram[2:10]
+				count = int(datagram[11])
+				chunks = make([][]byte, count)
+			}
+			Expect(datagram[2:10]).To(Equal(id))
+			Expect(int(datagram[11])).To(Equal(count))
+			chunks[datagram[10]] = datagram[12:]
+			received := 0
+			for _, chunk := range chunks {
+				if chunk != nil {
+					received++
+				}
+			}
+			if received == count {
+				break
+			}
+		}
+		msg := bytes.Join(chunks, nil)
+		switch {
+		case msg[0] == 0x1f && msg[1] == 0x8b:
+			r, err := gzip.NewReader(bytes.NewReader(msg))
+			Expect(err).ToNot(HaveOccurred())
+			msg, err = ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+		case msg[0] == 0x78:
+			r, err := zlib.NewReader(bytes.NewReader(msg))
+			Expect(err).ToNot(HaveOccurred())
+			msg, err = ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		var message map[string]interface{}
+		Expect(json.Unmarshal(msg, &message)).To(Succeed())
+		return message
+	}
+
+	newWriter := func(options GELFOptions) *GELFWriter {
+		options.Address = conn.LocalAddr().String()
+		options.Host = "web-1"
+		w, err := NewGELFWriter(options)
+		Expect(err).ToNot(HaveOccurred())
+		return w
+	}
+
+	It("should send small messages in a single datagram", func() {
+		w := newWriter(GELFOptions{})
+		defer w.Close()
+		Expect(w.WriteEntry(&Entry{Level: levelInfo, Message: "hello", Fields: FieldsArr{"a", 1}})).To(Succeed())
+		message := next()
+		Expect(message).To(HaveKeyWithValue("short_message", "hello"))
+		Expect(message).To(HaveKeyWithValue("host", "web-1"))
+		Expect(message).To(HaveKeyWithValue("_a", 1.0))
+	})
+
+	for _, compression := range []string{"none", "gzip", "zlib"} {
+		compression := compression
+
+		It("should chunk large messages with compression "+compression, func() {
+			w := newWriter(GELFOptions{Compression: compression, ChunkSize: 100})
+			defer w.Close()
+			// Random-ish text, so that it doesn't compress into one chunk.
+			var text strings.Builder
+			for i := 0; text.Len() < 2000; i++ {
+				text.WriteString(time.Duration(i * 7919).String())
+				text.WriteByte(' ')
+			}
+			Expect(w.WriteEntry(&Entry{Level: levelErr, Message: "big\n" + text.String()})).To(Succeed())
+			message := next()
+			Expect(message).To(HaveKeyWithValue("short_message", "big"))
+			Expect(message).To(HaveKeyWithValue("full_message", "big\n"+text.String()))
+		})
+
+		It("should send single datagrams with compression "+compression, func() {
+			w := newWriter(GELFOptions{Compression: compression})
+			defer w.Close()
+			Expect(w.WriteEntry(&Entry{Level: levelWarn, Message: "small"})).To(Succeed())
+			Expect(next()).To(HaveKeyWithValue("level", 4.0))
+		})
+	}
+
+	It("should fail if the message needs too many chunks", func() {
+		w := newWriter(GELFOptions{ChunkSize: 13})
+		defer w.Close()
+		Expect(w.WriteEntry(&Entry{Level: levelInfo, Message: strings.Repeat("x", 1000)})).ToNot(Succeed())
+	})
+
+	It("should fail if the message can't be compressed", func() {
+		w := newWriter(GELFOptions{Compression: "gzip"})
+		defer w.Close()
+		w.compressor = func(io.Writer) io.WriteCloser {
+			return failingCompressor{}
+		}
+		Expect(w.WriteEntry(&Entry{Level: levelInfo, Message: "hello"})).To(MatchError("compression failed"))
+		n, err := w.Write([]byte(`{"short_message":"hello"}`))
+		Expect(err).To(MatchError("compression failed"))
+		Expect(n).To(BeZero())
+	})
+
+	It("should fail with an unknown compression", func() {
+		_, err := NewGELFWriter(GELFOptions{Compression: "brotli"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should log through the gelf log stream", func() {
+		l, err := NewLogger(Config{
+			LogStream:   "GELF",
+			GELFAddress: conn.LocalAddr().String(),
+			LogLevel:    "INFO",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer l.logWriterStream.(*GELFWriter).Close()
+		l.WithFields(Fields{"user": "bob"}).Warn("hello graylog")
+		message := next()
+		Expect(message).To(HaveKeyWithValue("short_message", "hello graylog"))
+		Expect(message).To(HaveKeyWithValue("level", 4.0))
+		Expect(message).To(HaveKeyWithValue("_user", "bob"))
+	})
+})
+
+// failingCompressor fails writing, like a compressor whose output fails.
+type failingCompressor struct{}
+
+func (failingCompressor) Write(p []byte) (int, error) {
+	return 0, errors.New("compression failed")
+}
+
+func (failingCompressor) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package rlog
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// The goroutine ID is read directly from the runtime's g struct when possible.
+// Go doesn't export the ID, and the offset of the field within g changes
+// between Go versions, so the offset is determined on the first use: the words
+// at the start of g are compared with the ID reported by runtime.Stack, and
+// the candidates are verified in a few other goroutines. If the fast path is
+// not available on this platform, or no unique offset is found, rlog falls
+// back to parsing the output of runtime.Stack.
+//
+// With RLOG_GOID_CHECK set, and in the tests, every ID read from g is checked
+// against runtime.Stack, and a mismatch turns the fast path off.
+var (
+	goidOffset    uintptr
+	fastGIDActive uint32 // 1 while the fast path is used, accessed atomically
+	goidOnce      sync.Once
+	goidCheck     = os.Getenv("RLOG_GOID_CHECK") != ""
+)
+
+// goidScanLength is how many bytes at the start of g are searched for the
+// goroutine ID. The field has always been well within this range, and g is
+// larger than this on every supported Go version.
+const goidScanLength = 256
+
+// calibrateGID determines the offset of the goroutine ID, once. Programs which
+// never log the goroutine ID don't pay for it.
+func calibrateGID() {
+	goidOnce.Do(func() {
+		if offset, ok := findGoidOffset(); ok {
+			goidOffset = offset
+			atomic.StoreUint32(&fastGIDActive, 1)
+		}
+	})
+}
+
+// getGID returns the ID of the current goroutine.
+func getGID() uint64 {
+	calibrateGID()
+	if atomic.LoadUint32(&fastGIDActive) == 0 {
+		return slowGID()
+	}
+	gid := *(*uint64)(unsafe.Pointer(uintptr(getg()) + goidOffset))
+	if goidCheck {
+		if slow := slowGID(); slow != gid {
+			if atomic.CompareAndSwapUint32(&fastGIDActive, 1, 0) {
+				rlogIssue("Goroutine ID %d at offset %d doesn't match %d from runtime.Stack. Using runtime.Stack from now on.", gid, goidOffset, slow)
+			}
+			return slow
+		}
+	}
+	return gid
+}
+
+// slowGID gets the current goroutine ID (algorithm from
+// https://blog.sgmansfield.com/2015/12/goroutine-ids/) by
+// unwinding the stack. The first line of the output looks like
+// "goroutine 123 [running]:".
+func slowGID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	var n uint64
+	for _, c := range b[len("goroutine "):] {
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n
+}
+
+// findGoidOffset looks for the offset of the goroutine ID within the g
+// struct. The second return value is false if the fast path can't be used.
+func findGoidOffset() (uintptr, bool) {
+	if !fastGIDSupported {
+		return 0, false
+	}
+
+	candidates := goidCandidates(nil)
+	// A word of g might hold the same value by accident, so only offsets
+	// which match in other goroutines (with other IDs) as well are kept.
+	for i := 0; i < 3 && len(candidates) > 0; i++ {
+		done := make(chan []uintptr)
+		go func() {
+			done <- goidCandidates(candidates)
+		}()
+		candidates = <-done
+	}
+	if len(candidates) != 1 {
+		return 0, false
+	}
+	return candidates[0], true
+}
+
+// goidCandidates returns the offsets within the g struct of the calling
+// goroutine which hold its ID. If offsets is not nil only those are checked.
+func goidCandidates(offsets []uintptr) []uintptr {
+	gid := slowGID()
+	g := getg()
+	if offsets == nil {
+		for offset := uintptr(0); offset < goidScanLength; offset += 8 {
+			offsets = append(offsets, offset)
+		}
+	}
+	var matches []uintptr
+	for _, offset := range offsets {
+		if *(*uint64)(unsafe.Pointer(uintptr(g) + offset)) == gid {
+			matches = append(matches, offset)
+		}
+	}
+	return matches
+}
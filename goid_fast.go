@@ -0,0 +1,13 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package rlog
+
+import "unsafe"
+
+// fastGIDSupported tells whether getg is implemented for this platform.
+const fastGIDSupported = true
+
+// getg returns the runtime's g struct of the current goroutine. It's
+// implemented in assembly.
+func getg() unsafe.Pointer
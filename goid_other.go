@@ -0,0 +1,13 @@
+//go:build !amd64 && !arm64
+// +build !amd64,!arm64
+
+package rlog
+
+import "unsafe"
+
+// fastGIDSupported tells whether getg is implemented for this platform.
+const fastGIDSupported = false
+
+func getg() unsafe.Pointer {
+	return nil
+}
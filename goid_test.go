@@ -0,0 +1,111 @@
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func init() {
+	// Check every goroutine ID the tests read from g.
+	goidCheck = true
+}
+
+var _ = Describe("GoroutineID", func() {
+	It("should use the fast path on supported platforms", func() {
+		if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+			Skip("no fast path on " + runtime.GOARCH)
+		}
+		calibrateGID()
+		Expect(atomic.LoadUint32(&fastGIDActive)).To(BeEquivalentTo(1))
+	})
+
+	It("should agree with the goroutine ID reported by the runtime", func() {
+		Expect(getGID()).To(Equal(slowGID()))
+		Expect(getGID()).To(BeNumerically(">", 0))
+
+		ids := make(chan [2]uint64)
+		for i := 0; i < 10; i++ {
+			go func() {
+				ids <- [2]uint64{getGID(), slowGID()}
+			}()
+		}
+		seen := map[uint64]bool{}
+		for i := 0; i < 10; i++ {
+			id := <-ids
+			Expect(id[0]).To(Equal(id[1]))
+			seen[id[0]] = true
+		}
+		Expect(seen).To(HaveLen(10))
+	})
+
+	It("should turn the fast path off when the ID doesn't match", func() {
+		calibrateGID()
+		if atomic.LoadUint32(&fastGIDActive) == 0 {
+			Skip("fast path not available")
+		}
+		offset := goidOffset
+		defer func() {
+			goidOffset = offset
+			atomic.StoreUint32(&fastGIDActive, 1)
+		}()
+		// The word after the ID is another field of g.
+		goidOffset += 8
+
+		Expect(getGID()).To(Equal(slowGID()))
+		Expect(atomic.LoadUint32(&fastGIDActive)).To(BeZero())
+		Expect(getGID()).To(Equal(slowGID()))
+	})
+
+	It("should log the goroutine ID in the caller info", func() {
+		logger, err := NewLogger(Config{
+			Formatter:       "json",
+			LogNoTime:       true,
+			ShowCallerInfo:  true,
+			ShowGoroutineID: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		buff := bytes.NewBuffer(nil)
+		logger.SetOutput(buff)
+		logger.Info("this is a INFO")
+		Expect(buff.String()).To(ContainSubstring(`"gid":`))
+	})
+})
+
+func BenchmarkGetGID(b *testing.B) {
+	b.Run("fast", func(b *testing.B) {
+		calibrateGID()
+		if atomic.LoadUint32(&fastGIDActive) == 0 {
+			b.Skip("fast path not available")
+		}
+		for n := 0; n < b.N; n++ {
+			getGID()
+		}
+	})
+
+	b.Run("runtime.Stack", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			slowGID()
+		}
+	})
+}
+
+func BenchmarkWithGoroutineID(b *testing.B) {
+	logger, err := NewLogger(Config{
+		ShowCallerInfo:  true,
+		ShowGoroutineID: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	logger.SetOutput(ioutil.Discard)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		logger.Info("this is a test")
+	}
+}
@@ -0,0 +1,132 @@
+package rlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithGroup returns a sub-logger which nests the fields added later, through
+// its own sub-loggers, under name. The text formatters render them as dotted
+// keys (db.query=...), the JSON formatter as object ({"db":{"query":...}}).
+// Groups without fields are left out.
+func (l *logger) WithGroup(name string) Logger {
+	sl := newSubLogger(l, nil)
+	sl.group = name
+	return sl
+}
+
+// WithGroup returns a sub-logger which nests the fields added later under
+// name. See the WithGroup of the default logger.
+func (logger *subLogger) WithGroup(name string) Logger {
+	l := newSubLogger(logger, nil)
+	l.group = name
+	return l
+}
+
+// WithGroup returns a sub-logger of the default logger which nests the fields
+// added later under name.
+func WithGroup(name string) Logger {
+	return DefaultLogger.WithGroup(name)
+}
+
+// LoggerWithGroup returns a sub-logger of l which nests the fields added
+// later under name. Loggers without WithGroup are returned as they are, so
+// the fields aren't nested.
+func LoggerWithGroup(l Logger, name string) Logger {
+	if g, ok := l.(grouper); ok {
+		return g.WithGroup(name)
+	}
+	return l
+}
+
+// nest nests the fields of the entry under the group of the sub-logger. The
+// additional information was rendered without the group, so it's rendered
+// again, unless the fields hold Valuers, which are rendered after filtering.
+func (logger *subLogger) nest(additionalInformation string, fields FieldsArr) (string, FieldsArr) {
+	if logger.group == "" || len(fields) == 0 {
+		return additionalInformation, fields
+	}
+	fields = FieldsArr{logger.group, fields}
+	if hasValuer(fields) {
+		return "", fields
+	}
+	return logger.Formatter().FormatFields(fields), fields
+}
+
+// DuplicateKeys tells how the fields of an entry with the same key are
+// handled, e.g. when a sub-logger sets a key of its parent again.
+type DuplicateKeys int
+
+const (
+	// KeepDuplicateKeys keeps all the fields, in order.
+	KeepDuplicateKeys DuplicateKeys = iota
+	// LastKeyWins keeps only the last field of each key, at its position.
+	// Groups are deduplicated separately.
+	LastKeyWins
+)
+
+// parseDuplicateKeys translates the RLOG_DUPLICATE_KEYS setting.
+func parseDuplicateKeys(s string) (DuplicateKeys, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "keep":
+		return KeepDuplicateKeys, nil
+	case "last":
+		return LastKeyWins, nil
+	}
+	return KeepDuplicateKeys, fmt.Errorf("duplicate keys setting '%s' is unknown", s)
+}
+
+// SetDuplicateKeys sets how the logger handles fields with the same key.
+func (l *logger) SetDuplicateKeys(d DuplicateKeys) {
+	l.settingDuplicateKeys = d
+}
+
+// SetDuplicateKeys sets how the default logger handles fields with the same
+// key.
+func SetDuplicateKeys(d DuplicateKeys) {
+	DefaultLogger.SetDuplicateKeys(d)
+}
+
+// dedupFields keeps the last field of each key, also in nested fields. The
+// fields may be shared, so they are copied before a change.
+func dedupFields(fields FieldsArr) (FieldsArr, bool) {
+	var result FieldsArr
+	for i := 0; i+1 < len(fields); i += 2 {
+		value := fields[i+1]
+		nestedChanged := false
+		if nested, ok := value.(FieldsArr); ok {
+			value, nestedChanged = dedupFields(nested)
+		}
+		dropped := hasKeyAfter(fields, i)
+		if result == nil && (dropped || nestedChanged) {
+			result = make(FieldsArr, i, len(fields))
+			copy(result, fields[:i])
+		}
+		if result != nil && !dropped {
+			result = append(result, fields[i], value)
+		}
+	}
+	if result == nil {
+		return fields, false
+	}
+	return result, true
+}
+
+// hasKeyAfter tells whether the key at i is set again later.
+func hasKeyAfter(fields FieldsArr, i int) bool {
+	key := fieldKey(fields[i])
+	for j := i + 2; j+1 < len(fields); j += 2 {
+		if fieldKey(fields[j]) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKey returns a key of the fields as string.
+func fieldKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,84 @@
+package rlog
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Groups", func() {
+	It("should nest the fields of a group in JSON", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		db := LoggerWithGroup(logger.WithField("service", "api"), "db").WithField("query", "SELECT 1")
+		LoggerWithGroup(db, "pool").WithField("size", 4).Info("query")
+
+		entry := decodeEntry(buff)
+		Expect(entry).To(HaveKeyWithValue("service", "api"))
+		Expect(entry).To(HaveKeyWithValue("db", map[string]interface{}{
+			"query": "SELECT 1",
+			"pool":  map[string]interface{}{"size": 4.0},
+		}))
+	})
+
+	It("should render the fields of a group as dotted keys in text", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text"})
+		LoggerWithGroup(logger.WithField("service", "api"), "db").WithFieldsArr("query", "SELECT 1", "rows", 2).Info("query")
+
+		Expect(buff.String()).To(ContainSubstring(`service=api db.query="SELECT 1" db.rows=2 msg=`))
+	})
+
+	It("should render the fields of a group as dotted keys in the default formatter", func() {
+		logger, buff := newTestLogger(Config{})
+		logger.WithGroup("db").WithField("rows", 2).Info("query")
+
+		Expect(buff.String()).To(ContainSubstring("db.rows=2"))
+	})
+
+	It("should leave out groups without fields", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		LoggerWithGroup(logger.WithField("service", "api"), "db").Info("nothing")
+
+		Expect(decodeEntry(buff)).ToNot(HaveKey("db"))
+	})
+
+	It("should not nest the fields of loggers without WithGroup", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text"})
+		LoggerWithGroup(struct{ Logger }{logger}, "db").WithField("rows", 2).Info("query")
+
+		Expect(buff.String()).To(ContainSubstring(" rows=2 msg="))
+	})
+
+	It("should keep duplicate keys by default", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text"})
+		logger.WithField("user", "joe").WithField("user", "ann").Info("twice")
+
+		Expect(buff.String()).To(ContainSubstring("user=joe user=ann"))
+	})
+
+	It("should keep the last of duplicate keys if configured", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text", DuplicateKeys: "last"})
+		sub := logger.WithFieldsArr("user", "joe", "attempt", 1)
+		sub.WithField("user", "ann").Info("twice")
+
+		Expect(buff.String()).To(ContainSubstring("attempt=1 user=ann msg="))
+		Expect(buff.String()).ToNot(ContainSubstring("joe"))
+
+		buff.Reset()
+		sub.Info("once")
+		Expect(buff.String()).To(ContainSubstring("user=joe attempt=1"))
+	})
+
+	It("should deduplicate the keys of groups separately", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		logger.SetDuplicateKeys(LastKeyWins)
+		LoggerWithGroup(logger.WithField("id", 1), "db").WithField("id", 2).WithField("id", 3).Info("ids")
+
+		entry := decodeEntry(buff)
+		Expect(entry).To(HaveKeyWithValue("id", 1.0))
+		Expect(entry).To(HaveKeyWithValue("db", map[string]interface{}{"id": 3.0}))
+	})
+
+	It("should reject an unknown duplicate keys setting", func() {
+		_, err := NewLogger(Config{DuplicateKeys: "first"})
+		Expect(err).To(MatchError(ContainSubstring("first")))
+	})
+})
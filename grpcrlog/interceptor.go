@@ -0,0 +1,280 @@
+package grpcrlog
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/lab259/rlog/v2"
+	"github.com/lab259/rlog/v2/internal/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Options configures the interceptors.
+type Options struct {
+	// RequestIDMetadata is the metadata key holding the ID of the request.
+	// Servers take the ID from the incoming metadata, or generate one and
+	// send it back in the header. Clients send the ID along with the call.
+	// By default, it's x-request-id.
+	RequestIDMetadata string
+	// Level chooses the level of the completion line by the status code. By
+	// default, see DefaultLevel.
+	Level func(code codes.Code) rlog.Level
+	// PayloadTraceLevel, if greater than 0, is the trace level at which the
+	// messages sent and received are logged.
+	PayloadTraceLevel int
+}
+
+const defaultRequestIDMetadata = "x-request-id"
+
+// withDefaults returns a copy of the options with the defaults filled in.
+func (options *Options) withDefaults() Options {
+	var o Options
+	if options != nil {
+		o = *options
+	}
+	if o.RequestIDMetadata == "" {
+		o.RequestIDMetadata = defaultRequestIDMetadata
+	}
+	if o.Level == nil {
+		o.Level = DefaultLevel
+	}
+	return o
+}
+
+// DefaultLevel is the default of Options.Level. Like the status classes of
+// HTTP, OK is INFO, the codes caused by the caller are WARN and those caused
+// by the server (Unknown, Unimplemented, Internal, Unavailable and DataLoss)
+// are ERROR.
+func DefaultLevel(code codes.Code) rlog.Level {
+	switch code {
+	case codes.OK:
+		return rlog.LevelInfo
+	case codes.Unknown, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return rlog.LevelError
+	}
+	return rlog.LevelWarn
+}
+
+// UnaryServerInterceptor returns an interceptor which gives each call a
+// sub-logger, with the fields method, peer and request_id, and logs the
+// completion with code and duration. The options may be nil.
+func UnaryServerInterceptor(l rlog.Logger, options *Options) grpc.UnaryServerInterceptor {
+	o := options.withDefaults()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, callLogger, id := o.serverLogger(ctx, l, info.FullMethod)
+		if id != "" {
+			grpc.SetHeader(ctx, metadata.Pairs(o.RequestIDMetadata, id))
+		}
+		o.logPayload(callLogger, "received", req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			o.logPayload(callLogger, "sent", resp)
+		}
+		o.logDone(callLogger, err, start)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the counterpart of UnaryServerInterceptor for
+// streams. The context of the stream carries the sub-logger. The options may
+// be nil.
+func StreamServerInterceptor(l rlog.Logger, options *Options) grpc.StreamServerInterceptor {
+	o := options.withDefaults()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, callLogger, id := o.serverLogger(ss.Context(), l, info.FullMethod)
+		if id != "" {
+			ss.SetHeader(metadata.Pairs(o.RequestIDMetadata, id))
+		}
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, logger: callLogger, options: &o})
+		o.logDone(callLogger, err, start)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor which logs the completion of
+// each call with code and duration. The request ID is taken from the outgoing
+// metadata, or from the incoming metadata of the call being served, or
+// generated, and is sent along with the call. The options may be nil.
+func UnaryClientInterceptor(l rlog.Logger, options *Options) grpc.UnaryClientInterceptor {
+	o := options.withDefaults()
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, callLogger := o.clientLogger(ctx, l, method, cc.Target())
+		o.logPayload(callLogger, "sent", req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			o.logPayload(callLogger, "received", reply)
+		}
+		o.logDone(callLogger, err, start)
+		return err
+	}
+}
+
+// StreamClientInterceptor is the counterpart of UnaryClientInterceptor for
+// streams. The completion is logged when receiving from the stream fails,
+// io.EOF being a successful end, or, if the server answers with a single
+// message, once that message was received. The options may be nil.
+func StreamClientInterceptor(l rlog.Logger, options *Options) grpc.StreamClientInterceptor {
+	o := options.withDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, callLogger := o.clientLogger(ctx, l, method, cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			o.logDone(callLogger, err, start)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, logger: callLogger, options: &o, start: start, singleResponse: !desc.ServerStreams}, nil
+	}
+}
+
+// serverLogger creates the sub-logger of a call received by a server. The
+// returned ID is set if it was generated, and so has to be sent back.
+func (o *Options) serverLogger(ctx context.Context, l rlog.Logger, method string) (context.Context, rlog.Logger, string) {
+	var id, generated string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(o.RequestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = requestid.New()
+		generated = id
+	}
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	callLogger := rlog.LoggerWithContext(l, ctx).WithFieldsArr(
+		"method", method,
+		"peer", addr,
+		"request_id", id,
+	)
+	return rlog.NewContext(ctx, callLogger), callLogger, generated
+}
+
+// clientLogger creates the sub-logger of a call made by a client and adds the
+// request ID to the outgoing metadata.
+func (o *Options) clientLogger(ctx context.Context, l rlog.Logger, method string, target string) (context.Context, rlog.Logger) {
+	var id string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(o.RequestIDMetadata); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			// Pass on the ID of the call being served.
+			if values := md.Get(o.RequestIDMetadata); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if id == "" {
+			id = requestid.New()
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, o.RequestIDMetadata, id)
+	}
+	callLogger := rlog.LoggerWithContext(l, ctx).WithFieldsArr(
+		"method", method,
+		"peer", target,
+		"request_id", id,
+	)
+	return ctx, callLogger
+}
+
+// logPayload logs a message at the payload trace level, if that is enabled.
+func (o *Options) logPayload(l rlog.Logger, direction string, msg interface{}) {
+	if o.PayloadTraceLevel <= 0 || !rlog.Enabled(l, rlog.LevelTrace, o.PayloadTraceLevel) {
+		return
+	}
+	l.WithField("payload", msg).Trace(o.PayloadTraceLevel, "message "+direction)
+}
+
+// logDone logs the completion of the call at the level of its code.
+func (o *Options) logDone(l rlog.Logger, err error, start time.Time) {
+	code := status.Code(err)
+	fields := rlog.FieldsArr{
+		"code", code.String(),
+		"duration", time.Since(start),
+	}
+	if err != nil {
+		fields = append(fields, "error", status.Convert(err).Message())
+	}
+	// The trace level only counts for LevelTrace.
+	l.WithFieldsArr(fields...).BasicLog(o.Level(code), 1, "", nil, "", "call done")
+}
+
+// serverStream carries the context with the sub-logger and logs the
+// messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	logger  rlog.Logger
+	options *Options
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.options.logPayload(s.logger, "sent", m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.options.logPayload(s.logger, "received", m)
+	}
+	return err
+}
+
+// clientStream logs the messages and the completion of the stream.
+type clientStream struct {
+	grpc.ClientStream
+	logger         rlog.Logger
+	options        *Options
+	start          time.Time
+	singleResponse bool // the server answers with one message, the call is done then
+	done           bool
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.options.logPayload(s.logger, "sent", m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.options.logPayload(s.logger, "received", m)
+		if s.singleResponse && !s.done {
+			s.done = true
+			s.options.logDone(s.logger, nil, s.start)
+		}
+		return nil
+	}
+	if !s.done {
+		s.done = true
+		if err == io.EOF {
+			s.options.logDone(s.logger, nil, s.start)
+		} else {
+			s.options.logDone(s.logger, err, s.start)
+		}
+	}
+	return err
+}
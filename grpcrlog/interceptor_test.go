@@ -0,0 +1,244 @@
+package grpcrlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+
+	"github.com/lab259/rlog/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// loggingHealthServer logs through the logger of the call before answering.
+type loggingHealthServer struct {
+	*health.Server
+}
+
+func (s loggingHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	rlog.FromContext(ctx).Info("checking")
+	return s.Server.Check(ctx, req)
+}
+
+func (s loggingHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	rlog.FromContext(stream.Context()).Info("watching")
+	if req.Service == "unknown" {
+		return status.Error(codes.Internal, "broken")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+// collectServiceDesc describes a client-streaming service, which counts the
+// health check requests it receives.
+var collectServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Collector",
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Collect",
+		Handler:       collect,
+		ClientStreams: true,
+	}},
+}
+
+func collect(_ interface{}, stream grpc.ServerStream) error {
+	rlog.FromContext(stream.Context()).Info("collecting")
+	for {
+		var req healthpb.HealthCheckRequest
+		err := stream.RecvMsg(&req)
+		if err == io.EOF {
+			return stream.SendMsg(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+var _ = Describe("Interceptors", func() {
+	var (
+		listener   *bufconn.Listener
+		server     *grpc.Server
+		conn       *grpc.ClientConn
+		client     healthpb.HealthClient
+		serverBuff *bytes.Buffer
+		clientBuff *bytes.Buffer
+	)
+
+	start := func(options *Options) {
+		var serverLogger, clientLogger rlog.Logger
+		serverLogger, serverBuff = newLogger(rlog.Config{TraceLevel: "3"})
+		clientLogger, clientBuff = newLogger(rlog.Config{TraceLevel: "3"})
+
+		listener = bufconn.Listen(1 << 20)
+		server = grpc.NewServer(
+			grpc.UnaryInterceptor(UnaryServerInterceptor(serverLogger, options)),
+			grpc.StreamInterceptor(StreamServerInterceptor(serverLogger, options)),
+		)
+		hs := health.NewServer()
+		hs.SetServingStatus("db", healthpb.HealthCheckResponse_SERVING)
+		healthpb.RegisterHealthServer(server, loggingHealthServer{hs})
+		server.RegisterService(&collectServiceDesc, nil)
+		go server.Serve(listener)
+
+		var err error
+		conn, err = grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientLogger, options)),
+			grpc.WithStreamInterceptor(StreamClientInterceptor(clientLogger, options)),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		client = healthpb.NewHealthClient(conn)
+	}
+
+	AfterEach(func() {
+		conn.Close()
+		// Stop waits for the handlers, and so for the completion lines.
+		server.Stop()
+	})
+
+	It("should log unary calls on both sides", func() {
+		start(nil)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "abc")
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "db"})
+		Expect(err).ToNot(HaveOccurred())
+		server.Stop()
+
+		result := entries(serverBuff)
+		Expect(result).To(HaveLen(2))
+		for _, entry := range result {
+			Expect(entry).To(HaveKeyWithValue("method", "/grpc.health.v1.Health/Check"))
+			Expect(entry).To(HaveKeyWithValue("request_id", "abc"))
+			Expect(entry).To(HaveKeyWithValue("peer", "bufconn"))
+		}
+		Expect(result[0]).To(HaveKeyWithValue("msg", "checking"))
+		Expect(result[1]).To(HaveKeyWithValue("msg", "call done"))
+		Expect(result[1]).To(HaveKeyWithValue("level", "INFO"))
+		Expect(result[1]).To(HaveKeyWithValue("code", "OK"))
+		Expect(result[1]).To(HaveKey("duration"))
+
+		result = entries(clientBuff)
+		Expect(result).To(HaveLen(1))
+		Expect(result[0]).To(HaveKeyWithValue("method", "/grpc.health.v1.Health/Check"))
+		Expect(result[0]).To(HaveKeyWithValue("peer", "passthrough:///bufnet"))
+		Expect(result[0]).To(HaveKeyWithValue("request_id", "abc"))
+		Expect(result[0]).To(HaveKeyWithValue("code", "OK"))
+	})
+
+	It("should send a generated request ID to the server", func() {
+		start(nil)
+		var header metadata.MD
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "db"}, grpc.Header(&header))
+		Expect(err).ToNot(HaveOccurred())
+		server.Stop()
+
+		id := entries(clientBuff)[0]["request_id"]
+		Expect(id).To(HaveLen(32))
+		Expect(entries(serverBuff)[1]).To(HaveKeyWithValue("request_id", id))
+		// The server only sends back the IDs it generated.
+		Expect(header.Get("x-request-id")).To(BeEmpty())
+	})
+
+	It("should map the codes onto levels", func() {
+		start(nil)
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "cache"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		server.Stop()
+
+		entry := entries(serverBuff)[1]
+		Expect(entry).To(HaveKeyWithValue("level", "WARN"))
+		Expect(entry).To(HaveKeyWithValue("code", "NotFound"))
+		Expect(entry).To(HaveKeyWithValue("error", "unknown service"))
+		Expect(entries(clientBuff)[0]).To(HaveKeyWithValue("level", "WARN"))
+	})
+
+	It("should log streams", func() {
+		start(nil)
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "db"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(err).To(Equal(io.EOF))
+		server.Stop()
+
+		result := entries(serverBuff)
+		Expect(result).To(HaveLen(2))
+		Expect(result[0]).To(HaveKeyWithValue("msg", "watching"))
+		Expect(result[0]).To(HaveKeyWithValue("method", "/grpc.health.v1.Health/Watch"))
+		Expect(result[1]).To(HaveKeyWithValue("code", "OK"))
+		Expect(entries(clientBuff)).To(HaveLen(1))
+		Expect(entries(clientBuff)[0]).To(HaveKeyWithValue("code", "OK"))
+	})
+
+	It("should log client streams once the response was received", func() {
+		start(nil)
+		stream, err := conn.NewStream(context.Background(), &collectServiceDesc.Streams[0], "/test.Collector/Collect")
+		Expect(err).ToNot(HaveOccurred())
+		for _, service := range []string{"db", "cache"} {
+			Expect(stream.SendMsg(&healthpb.HealthCheckRequest{Service: service})).To(Succeed())
+		}
+		Expect(stream.CloseSend()).To(Succeed())
+		var resp healthpb.HealthCheckResponse
+		Expect(stream.RecvMsg(&resp)).To(Succeed())
+		Expect(resp.Status).To(Equal(healthpb.HealthCheckResponse_SERVING))
+		server.Stop()
+
+		result := entries(serverBuff)
+		Expect(result).To(HaveLen(2))
+		Expect(result[0]).To(HaveKeyWithValue("msg", "collecting"))
+		Expect(result[1]).To(HaveKeyWithValue("code", "OK"))
+		result = entries(clientBuff)
+		Expect(result).To(HaveLen(1))
+		Expect(result[0]).To(HaveKeyWithValue("method", "/test.Collector/Collect"))
+		Expect(result[0]).To(HaveKeyWithValue("code", "OK"))
+	})
+
+	It("should log failed streams", func() {
+		start(nil)
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+		server.Stop()
+
+		Expect(entries(serverBuff)[1]).To(HaveKeyWithValue("level", "ERROR"))
+		Expect(entries(clientBuff)[0]).To(HaveKeyWithValue("level", "ERROR"))
+		Expect(entries(clientBuff)[0]).To(HaveKeyWithValue("code", "Internal"))
+	})
+
+	It("should log the payloads at the trace level of the options", func() {
+		start(&Options{PayloadTraceLevel: 2})
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "db"})
+		Expect(err).ToNot(HaveOccurred())
+		server.Stop()
+
+		result := entries(serverBuff)
+		Expect(result).To(HaveLen(4))
+		Expect(result[0]).To(HaveKeyWithValue("level", "TRACE"))
+		Expect(result[0]).To(HaveKeyWithValue("trace_level", 2.0))
+		Expect(result[0]).To(HaveKeyWithValue("msg", "message received"))
+		Expect(result[0]["payload"]).To(ContainSubstring("db"))
+		Expect(result[2]).To(HaveKeyWithValue("msg", "message sent"))
+		Expect(result[2]["payload"]).To(ContainSubstring("SERVING"))
+		Expect(entries(clientBuff)).To(HaveLen(3))
+	})
+
+	It("should skip the payloads above the trace level", func() {
+		start(&Options{PayloadTraceLevel: 4})
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "db"})
+		Expect(err).ToNot(HaveOccurred())
+		server.Stop()
+		Expect(entries(serverBuff)).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,133 @@
+// Package grpcrlog connects gRPC to rlog. NewLoggerV2 routes the logs of the
+// gRPC library itself into a Logger:
+//
+//	grpclog.SetLoggerV2(grpcrlog.NewLoggerV2(rlog.WithField("component", "grpc")))
+//
+// The V levels of gRPC are mapped onto trace levels, so RLOG_TRACE_LEVEL
+// controls the verbosity of gRPC.
+package grpcrlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lab259/rlog/v2"
+	"google.golang.org/grpc/grpclog"
+)
+
+// exit ends the program after a fatal message.
+var exit = os.Exit
+
+// LoggerV2 implements grpclog.LoggerV2 and grpclog.DepthLoggerV2 on top of a
+// Logger. INFO, WARNING and ERROR are logged at the rlog levels of the same
+// name, FATAL at CRITICAL before the program exits. The caller info refers to
+// the code calling grpclog.
+type LoggerV2 struct {
+	logger rlog.Logger
+	// caller reports the code calling the grpclog function which called the
+	// LoggerV2.
+	caller rlog.Logger
+}
+
+var (
+	_ grpclog.LoggerV2      = (*LoggerV2)(nil)
+	_ grpclog.DepthLoggerV2 = (*LoggerV2)(nil)
+)
+
+// NewLoggerV2 creates a LoggerV2 logging to l.
+func NewLoggerV2(l rlog.Logger) *LoggerV2 {
+	return &LoggerV2{
+		logger: l,
+		caller: rlog.LoggerWithCallerSkip(l, 2),
+	}
+}
+
+func (g *LoggerV2) Info(args ...interface{}) {
+	g.caller.Info(args...)
+}
+
+func (g *LoggerV2) Infoln(args ...interface{}) {
+	g.caller.Info(sprintln(args))
+}
+
+func (g *LoggerV2) Infof(format string, args ...interface{}) {
+	g.caller.Infof(format, args...)
+}
+
+func (g *LoggerV2) Warning(args ...interface{}) {
+	g.caller.Warn(args...)
+}
+
+func (g *LoggerV2) Warningln(args ...interface{}) {
+	g.caller.Warn(sprintln(args))
+}
+
+func (g *LoggerV2) Warningf(format string, args ...interface{}) {
+	g.caller.Warnf(format, args...)
+}
+
+func (g *LoggerV2) Error(args ...interface{}) {
+	g.caller.Error(args...)
+}
+
+func (g *LoggerV2) Errorln(args ...interface{}) {
+	g.caller.Error(sprintln(args))
+}
+
+func (g *LoggerV2) Errorf(format string, args ...interface{}) {
+	g.caller.Errorf(format, args...)
+}
+
+func (g *LoggerV2) Fatal(args ...interface{}) {
+	g.caller.Critical(args...)
+	exit(1)
+}
+
+func (g *LoggerV2) Fatalln(args ...interface{}) {
+	g.caller.Critical(sprintln(args))
+	exit(1)
+}
+
+func (g *LoggerV2) Fatalf(format string, args ...interface{}) {
+	g.caller.Criticalf(format, args...)
+	exit(1)
+}
+
+// V tells whether trace level l is enabled.
+func (g *LoggerV2) V(l int) bool {
+	return rlog.Enabled(g.logger, rlog.LevelTrace, l)
+}
+
+// The depth is counted from the code calling the grpclog function, which in
+// turn calls these methods.
+
+func (g *LoggerV2) InfoDepth(depth int, args ...interface{}) {
+	g.depth(depth).Info(sprintln(args))
+}
+
+func (g *LoggerV2) WarningDepth(depth int, args ...interface{}) {
+	g.depth(depth).Warn(sprintln(args))
+}
+
+func (g *LoggerV2) ErrorDepth(depth int, args ...interface{}) {
+	g.depth(depth).Error(sprintln(args))
+}
+
+func (g *LoggerV2) FatalDepth(depth int, args ...interface{}) {
+	g.depth(depth).Critical(sprintln(args))
+	exit(1)
+}
+
+func (g *LoggerV2) depth(depth int) rlog.Logger {
+	if depth <= 0 {
+		return g.caller
+	}
+	return rlog.LoggerWithCallerSkip(g.logger, depth+2)
+}
+
+// sprintln formats the arguments in the manner of fmt.Println, without the
+// line break.
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
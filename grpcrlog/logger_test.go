@@ -0,0 +1,114 @@
+package grpcrlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/lab259/rlog/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGRPCRLog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gRPC Test Suite")
+}
+
+// newLogger creates a logger writing JSON lines into the returned buffer.
+func newLogger(config rlog.Config) (rlog.Logger, *bytes.Buffer) {
+	config.LogNoTime = true
+	config.Formatter = "json"
+	l, err := rlog.NewLogger(config)
+	Expect(err).ToNot(HaveOccurred())
+	buff := bytes.NewBuffer(nil)
+	l.SetOutput(buff)
+	return l, buff
+}
+
+// entries decodes the JSON lines in buff.
+func entries(buff *bytes.Buffer) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buff.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		Expect(json.Unmarshal(line, &entry)).To(Succeed())
+		result = append(result, entry)
+	}
+	return result
+}
+
+// The functions of grpclog, which call the LoggerV2 with the code calling
+// them at the given depth. grpclog.SetLoggerV2 can't be used in the tests, as
+// the connections of other tests may still be logging.
+func grpclogInfof(g *LoggerV2, format string, args ...interface{}) {
+	g.Infof(format, args...)
+}
+
+func grpclogInfoDepth(g *LoggerV2, depth int, args ...interface{}) {
+	g.InfoDepth(depth, args...)
+}
+
+func grpclogWarningDepth(g *LoggerV2, depth int, args ...interface{}) {
+	g.WarningDepth(depth, args...)
+}
+
+var _ = Describe("LoggerV2", func() {
+	It("should map the severities onto levels", func() {
+		l, buff := newLogger(rlog.Config{})
+		g := NewLoggerV2(l)
+		g.Info("info", 1)
+		g.Warningln("warning", 2)
+		g.Errorf("error %d", 3)
+		result := entries(buff)
+		Expect(result).To(HaveLen(3))
+		Expect(result[0]).To(HaveKeyWithValue("level", "INFO"))
+		Expect(result[0]).To(HaveKeyWithValue("msg", "info1"))
+		Expect(result[1]).To(HaveKeyWithValue("level", "WARN"))
+		Expect(result[1]).To(HaveKeyWithValue("msg", "warning 2"))
+		Expect(result[2]).To(HaveKeyWithValue("level", "ERROR"))
+		Expect(result[2]).To(HaveKeyWithValue("msg", "error 3"))
+	})
+
+	It("should log fatal messages as critical and exit", func() {
+		defer func(e func(int)) { exit = e }(exit)
+		code := -1
+		exit = func(c int) { code = c }
+
+		l, buff := newLogger(rlog.Config{})
+		NewLoggerV2(l).Fatalf("broken %s", "pipe")
+		Expect(code).To(Equal(1))
+		Expect(entries(buff)[0]).To(HaveKeyWithValue("level", "CRITICAL"))
+		Expect(entries(buff)[0]).To(HaveKeyWithValue("msg", "broken pipe"))
+	})
+
+	It("should map V onto the trace level", func() {
+		l, _ := newLogger(rlog.Config{TraceLevel: "2"})
+		Expect(NewLoggerV2(l).V(2)).To(BeTrue())
+		Expect(NewLoggerV2(l).V(3)).To(BeFalse())
+
+		l, _ = newLogger(rlog.Config{})
+		Expect(NewLoggerV2(l).V(0)).To(BeFalse())
+	})
+
+	It("should report the code calling grpclog", func() {
+		l, buff := newLogger(rlog.Config{ShowCallerInfo: true})
+		g := NewLoggerV2(l)
+		_, file, line, _ := runtime.Caller(0)
+		grpclogInfof(g, "first")
+		grpclogInfoDepth(g, 0, "second")
+		func() {
+			grpclogWarningDepth(g, 1, "third")
+		}()
+		result := entries(buff)
+		Expect(result).To(HaveLen(3))
+		for i, offset := range []int{1, 2, 5} {
+			caller := result[i]["caller"].(map[string]interface{})
+			Expect(caller).To(HaveKeyWithValue("path", file))
+			Expect(caller).To(HaveKeyWithValue("line", float64(line+offset)))
+		}
+	})
+})
@@ -0,0 +1,45 @@
+package rlog
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/gomega"
+)
+
+// newBufferLogger creates a logger which writes into the returned buffer.
+func newBufferLogger(config Config) (*logger, *bytes.Buffer) {
+	logger, err := NewLogger(config)
+	Expect(err).ToNot(HaveOccurred())
+	buff := bytes.NewBuffer(nil)
+	logger.SetOutput(buff)
+	return logger, buff
+}
+
+// newTestLogger creates a logger which logs no time and writes into the
+// returned buffer.
+func newTestLogger(config Config) (*logger, *bytes.Buffer) {
+	config.LogNoTime = true
+	return newBufferLogger(config)
+}
+
+// decodeEntries parses the JSON lines written to the buffer.
+func decodeEntries(buff *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buff.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		Expect(json.Unmarshal(line, &entry)).To(Succeed())
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// decodeEntry parses the only JSON line written to the buffer.
+func decodeEntry(buff *bytes.Buffer) map[string]interface{} {
+	entries := decodeEntries(buff)
+	Expect(entries).To(HaveLen(1))
+	return entries[0]
+}
@@ -0,0 +1,211 @@
+// Package httplog provides an HTTP middleware which gives each request a
+// sub-logger and writes an access line when the request is done:
+//
+//	handler = httplog.Middleware(rlog.DefaultLogger, nil)(handler)
+//	...
+//	func (w http.ResponseWriter, r *http.Request) {
+//		rlog.FromContext(r.Context()).Info("loading user")
+//	}
+//
+// The entries of the sub-logger carry the request_id, method, path and
+// remote_addr of the request, as well as the trace correlation of the span
+// active in the request context (see rlog.WithContext).
+package httplog
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lab259/rlog/v2"
+	"github.com/lab259/rlog/v2/internal/requestid"
+)
+
+// Redacted replaces the values of redacted headers and query parameters.
+const Redacted = "[REDACTED]"
+
+// Options configures the middleware.
+type Options struct {
+	// RequestIDHeader is the header holding the ID of the request. Requests
+	// without one get a random ID, which is also sent back in this header. By
+	// default, it's X-Request-Id.
+	RequestIDHeader string
+	// Headers lists the request headers added to the access line, as
+	// header.<name> fields.
+	Headers []string
+	// RedactHeaders lists the headers whose values are replaced by
+	// Redacted. By default, these are Authorization, Proxy-Authorization and
+	// Cookie.
+	RedactHeaders []string
+	// RedactQuery lists the query parameters whose values are replaced by
+	// Redacted in the query field of the access line.
+	RedactQuery []string
+	// Level chooses the level of the access line by the status code. By
+	// default, 5xx are ERROR, 4xx are WARN and everything else is INFO.
+	Level func(status int) rlog.Level
+}
+
+// Defaults of the options.
+var (
+	defaultRequestIDHeader = "X-Request-Id"
+	defaultRedactHeaders   = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+)
+
+// DefaultLevel is the default of Options.Level.
+func DefaultLevel(status int) rlog.Level {
+	switch {
+	case status >= 500:
+		return rlog.LevelError
+	case status >= 400:
+		return rlog.LevelWarn
+	}
+	return rlog.LevelInfo
+}
+
+// Middleware returns a middleware logging the requests to l. The handlers get
+// the sub-logger of the request with rlog.FromContext. The options may be nil.
+// A handler which panics is logged with status 500, and the panic goes on.
+func Middleware(l rlog.Logger, options *Options) func(http.Handler) http.Handler {
+	var o Options
+	if options != nil {
+		o = *options
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = defaultRequestIDHeader
+	}
+	if o.RedactHeaders == nil {
+		o.RedactHeaders = defaultRedactHeaders
+	}
+	if o.Level == nil {
+		o.Level = DefaultLevel
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(o.RequestIDHeader)
+			if id == "" {
+				id = requestid.New()
+				w.Header().Set(o.RequestIDHeader, id)
+			}
+			reqLogger := rlog.LoggerWithContext(l, r.Context()).WithFieldsArr(
+				"request_id", id,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote_addr", r.RemoteAddr,
+			)
+
+			sw := &statusWriter{ResponseWriter: w}
+			completed := false
+			defer func() {
+				status := sw.status
+				if !completed {
+					// The handler panicked, which the server answers with
+					// 500. The panic goes on after the access line.
+					status = http.StatusInternalServerError
+				} else if status == 0 {
+					status = http.StatusOK
+				}
+				o.logAccess(reqLogger, r, status, sw.bytes, start)
+			}()
+			next.ServeHTTP(sw, r.WithContext(rlog.NewContext(r.Context(), reqLogger)))
+			completed = true
+		})
+	}
+}
+
+// logAccess logs the access line of a request.
+func (o *Options) logAccess(l rlog.Logger, r *http.Request, status int, bytes int64, start time.Time) {
+	fields := rlog.FieldsArr{
+		"status", status,
+		"bytes", bytes,
+		"latency", time.Since(start),
+	}
+	if r.URL.RawQuery != "" {
+		fields = append(fields, "query", o.redactQuery(r.URL.RawQuery))
+	}
+	for _, name := range o.Headers {
+		if value := r.Header.Get(name); value != "" {
+			fields = append(fields, "header."+strings.ToLower(name), o.redactHeader(name, value))
+		}
+	}
+	// The trace level only counts for LevelTrace.
+	l.WithFieldsArr(fields...).BasicLog(o.Level(status), 1, "", nil, "", "request done")
+}
+
+func (o *Options) redactHeader(name string, value string) string {
+	for _, redacted := range o.RedactHeaders {
+		if strings.EqualFold(name, redacted) {
+			return Redacted
+		}
+	}
+	return value
+}
+
+// redactQuery replaces the values of the redacted parameters. A query which
+// can't be parsed is replaced as a whole, as it can't be redacted.
+func (o *Options) redactQuery(rawQuery string) string {
+	if len(o.RedactQuery) == 0 {
+		return rawQuery
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted
+	}
+	for _, name := range o.RedactQuery {
+		if values, ok := query[name]; ok {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return query.Encode()
+}
+
+// statusWriter records the status and the number of bytes of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher, if the underlying writer does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, if the underlying writer does.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("httplog: the response writer doesn't support hijacking")
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
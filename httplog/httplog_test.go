@@ -0,0 +1,178 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lab259/rlog/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTPLog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP Log Test Suite")
+}
+
+var _ = Describe("Middleware", func() {
+	var (
+		logger rlog.Logger
+		buff   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		l, err := rlog.NewLogger(rlog.Config{
+			LogNoTime: true,
+			Formatter: "json",
+			LogLevel:  "DEBUG",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		buff = bytes.NewBuffer(nil)
+		l.SetOutput(buff)
+		logger = l
+	})
+
+	// entries decodes the JSON lines written so far.
+	entries := func() []map[string]interface{} {
+		var result []map[string]interface{}
+		for _, line := range bytes.Split(bytes.TrimSpace(buff.Bytes()), []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			var entry map[string]interface{}
+			Expect(json.Unmarshal(line, &entry)).To(Succeed())
+			result = append(result, entry)
+		}
+		return result
+	}
+
+	serve := func(options *Options, handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		Middleware(logger, options)(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("should give the handler a logger for the request", func() {
+		req := httptest.NewRequest("GET", "/users/1", nil)
+		req.Header.Set("X-Request-Id", "abc")
+		serve(nil, func(w http.ResponseWriter, r *http.Request) {
+			rlog.FromContext(r.Context()).Debug("loading user")
+			w.Write([]byte("hello"))
+		}, req)
+
+		result := entries()
+		Expect(result).To(HaveLen(2))
+		for _, entry := range result {
+			Expect(entry).To(HaveKeyWithValue("request_id", "abc"))
+			Expect(entry).To(HaveKeyWithValue("method", "GET"))
+			Expect(entry).To(HaveKeyWithValue("path", "/users/1"))
+			Expect(entry).To(HaveKeyWithValue("remote_addr", req.RemoteAddr))
+		}
+		Expect(result[0]).To(HaveKeyWithValue("msg", "loading user"))
+	})
+
+	It("should log an access line", func() {
+		serve(nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}, httptest.NewRequest("GET", "/", nil))
+
+		entry := entries()[0]
+		Expect(entry).To(HaveKeyWithValue("level", "INFO"))
+		Expect(entry).To(HaveKeyWithValue("msg", "request done"))
+		Expect(entry).To(HaveKeyWithValue("status", 200.0))
+		Expect(entry).To(HaveKeyWithValue("bytes", 5.0))
+		Expect(entry).To(HaveKey("latency"))
+	})
+
+	It("should generate a request ID", func() {
+		rec := serve(nil, func(w http.ResponseWriter, r *http.Request) {}, httptest.NewRequest("GET", "/", nil))
+		id := rec.Header().Get("X-Request-Id")
+		Expect(id).To(HaveLen(32))
+		Expect(entries()[0]).To(HaveKeyWithValue("request_id", id))
+	})
+
+	It("should choose the level by the status class", func() {
+		for _, status := range []int{204, 302, 404, 503} {
+			status := status
+			serve(nil, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}, httptest.NewRequest("GET", "/", nil))
+		}
+		result := entries()
+		Expect(result).To(HaveLen(4))
+		levels := []string{"INFO", "INFO", "WARN", "ERROR"}
+		for i, level := range levels {
+			Expect(result[i]).To(HaveKeyWithValue("level", level))
+		}
+		Expect(result[3]).To(HaveKeyWithValue("status", 503.0))
+	})
+
+	It("should log the requests whose handler panics", func() {
+		Expect(func() {
+			serve(nil, func(w http.ResponseWriter, r *http.Request) {
+				panic("broken")
+			}, httptest.NewRequest("GET", "/", nil))
+		}).To(Panic())
+		result := entries()
+		Expect(result).To(HaveLen(1))
+		Expect(result[0]).To(HaveKeyWithValue("status", 500.0))
+		Expect(result[0]).To(HaveKeyWithValue("level", "ERROR"))
+	})
+
+	It("should log at trace level", func() {
+		l, err := rlog.NewLogger(rlog.Config{LogNoTime: true, Formatter: "json", TraceLevel: "1"})
+		Expect(err).ToNot(HaveOccurred())
+		l.SetOutput(buff)
+		logger = l
+		serve(&Options{
+			Level: func(status int) rlog.Level { return rlog.LevelTrace },
+		}, func(w http.ResponseWriter, r *http.Request) {}, httptest.NewRequest("GET", "/", nil))
+		Expect(entries()[0]).To(HaveKeyWithValue("level", "TRACE"))
+		Expect(entries()[0]).To(HaveKeyWithValue("trace_level", 1.0))
+	})
+
+	It("should use the level of the options", func() {
+		serve(&Options{
+			Level: func(status int) rlog.Level { return rlog.LevelDebug },
+		}, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+		}, httptest.NewRequest("GET", "/", nil))
+		Expect(entries()[0]).To(HaveKeyWithValue("level", "DEBUG"))
+	})
+
+	It("should redact headers and query parameters", func() {
+		req := httptest.NewRequest("GET", "/login?user=joe&token=secret", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("User-Agent", "test")
+		serve(&Options{
+			Headers:     []string{"Authorization", "User-Agent", "Accept"},
+			RedactQuery: []string{"token"},
+		}, func(w http.ResponseWriter, r *http.Request) {}, req)
+
+		entry := entries()[0]
+		Expect(entry).To(HaveKeyWithValue("header.authorization", Redacted))
+		Expect(entry).To(HaveKeyWithValue("header.user-agent", "test"))
+		Expect(entry).ToNot(HaveKey("header.accept"))
+		Expect(entry).To(HaveKeyWithValue("query", "token=%5BREDACTED%5D&user=joe"))
+	})
+
+	It("should work with a real server", func() {
+		server := httptest.NewServer(Middleware(logger, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+			w.Write([]byte("streamed"))
+		})))
+		resp, err := http.Get(server.URL + "/stream")
+		Expect(err).ToNot(HaveOccurred())
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(string(body)).To(Equal("streamed"))
+		// Close waits for the handler, and so the access line.
+		server.Close()
+		Expect(entries()[0]).To(HaveKeyWithValue("path", "/stream"))
+		Expect(entries()[0]).To(HaveKeyWithValue("bytes", 8.0))
+	})
+})
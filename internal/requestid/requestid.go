@@ -0,0 +1,15 @@
+// Package requestid generates the IDs httplog and grpcrlog give requests
+// which don't carry one.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// New generates a random ID of 16 bytes, hex encoded.
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,228 @@
+//go:build linux
+// +build linux
+
+package rlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The socket on which journald accepts its native protocol.
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journalReservedFields are the journal fields which the writer sends itself,
+// or which journald gives a meaning. Fields of entries with these names are
+// prefixed with RLOG_, so that they don't replace them.
+var journalReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"MESSAGE_ID":        true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"SYSLOG_FACILITY":   true,
+	"SYSLOG_PID":        true,
+	"SYSLOG_TIMESTAMP":  true,
+	"SYSLOG_RAW":        true,
+	"TRACE_LEVEL":       true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"STACK_TRACE":       true,
+	"ERRNO":             true,
+	"DOCUMENTATION":     true,
+	"TID":               true,
+}
+
+// JournaldOptions configures a JournaldWriter.
+type JournaldOptions struct {
+	// Socket is the path of the journald socket. By default, it's
+	// /run/systemd/journal/socket.
+	Socket string
+	// Identifier is sent as SYSLOG_IDENTIFIER. By default, it's the name of
+	// the executable.
+	Identifier string
+}
+
+// JournaldWriter sends entries to systemd-journald using its native protocol.
+// Each field of an entry becomes a journal field, the level is mapped to
+// PRIORITY and the caller info (if enabled) to CODE_FILE, CODE_LINE and
+// CODE_FUNC. Fields named like these are sent with an RLOG_ prefix. It
+// implements EntryWriter, so it can be used with SetOutput.
+type JournaldWriter struct {
+	mutex   sync.Mutex
+	options JournaldOptions
+	conn    *net.UnixConn
+	addr    *net.UnixAddr
+}
+
+// NewJournaldWriter prepares sending entries to the journald socket.
+func NewJournaldWriter(options JournaldOptions) (*JournaldWriter, error) {
+	if options.Socket == "" {
+		options.Socket = journaldSocket
+	}
+	if options.Identifier == "" {
+		options.Identifier = filepath.Base(os.Args[0])
+	}
+	if _, err := os.Stat(options.Socket); err != nil {
+		return nil, err
+	}
+	// The socket isn't connected, so that a restart of journald doesn't
+	// break it.
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{
+		options: options,
+		conn:    conn,
+		addr:    &net.UnixAddr{Name: options.Socket, Net: "unixgram"},
+	}, nil
+}
+
+// WriteEntry sends the entry to journald.
+func (w *JournaldWriter) WriteEntry(entry *Entry) error {
+	msg := w.format(entry)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, _, err := w.conn.WriteMsgUnix(msg, nil, w.addr)
+	if err == nil || !isMessageTooLarge(err) {
+		return err
+	}
+	return w.writeViaFile(msg)
+}
+
+// writeViaFile hands entries which don't fit into a datagram to journald as a
+// file descriptor, which is how journald expects them.
+func (w *JournaldWriter) writeViaFile(msg []byte) error {
+	file, err := ioutil.TempFile("/dev/shm", "rlog-journal-")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// journald only needs the descriptor, not the name.
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+	if _, err := file.Write(msg); err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(file.Fd()))
+	_, _, err = w.conn.WriteMsgUnix(nil, rights, w.addr)
+	return err
+}
+
+// Write sends an already formatted line at INFO level. It allows the writer
+// to be used wherever an io.Writer is expected.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	err := w.WriteEntry(&Entry{
+		Level:   levelInfo,
+		Message: strings.TrimRight(string(p), "\n"),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the socket.
+func (w *JournaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// format renders the entry in the native journal protocol.
+func (w *JournaldWriter) format(entry *Entry) []byte {
+	msg := make([]byte, 0, 256)
+	msg = appendJournalField(msg, "MESSAGE", entry.Message)
+	severity, ok := syslogSeverities[entry.Level]
+	if !ok {
+		severity = syslogSeverities[levelInfo]
+	}
+	msg = appendJournalField(msg, "PRIORITY", strconv.Itoa(severity))
+	msg = appendJournalField(msg, "SYSLOG_IDENTIFIER", w.options.Identifier)
+	if entry.Level == levelTrace && entry.TraceLevel > notATrace {
+		msg = appendJournalField(msg, "TRACE_LEVEL", strconv.Itoa(entry.TraceLevel))
+	}
+	if entry.CallerInfo.PID > 0 {
+		file := entry.CallerInfo.FullPath
+		if file == "" {
+			file = entry.CallerInfo.FileName
+		}
+		msg = appendJournalField(msg, "CODE_FILE", file)
+		msg = appendJournalField(msg, "CODE_LINE", strconv.Itoa(entry.CallerInfo.Line))
+		msg = appendJournalField(msg, "CODE_FUNC", entry.CallerInfo.FunctionName)
+	}
+	if len(entry.Stack) > 0 {
+		msg = appendJournalField(msg, "STACK_TRACE", defaultTextFormatter.formatStack(entry.Stack))
+	}
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		key := journalFieldName(fmt.Sprint(entry.Fields[i]))
+		if key == "" {
+			continue
+		}
+		if journalReservedFields[key] {
+			key = "RLOG_" + key
+		}
+		msg = appendJournalField(msg, key, fmt.Sprint(entry.Fields[i+1]))
+	}
+	return msg
+}
+
+// appendJournalField adds a field in the native protocol. Values without line
+// breaks are sent as KEY=value, others with their length in binary.
+func appendJournalField(msg []byte, key string, value string) []byte {
+	msg = append(msg, key...)
+	if strings.IndexByte(value, '\n') < 0 {
+		msg = append(msg, '=')
+		msg = append(msg, value...)
+		return append(msg, '\n')
+	}
+	msg = append(msg, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	msg = append(msg, size[:]...)
+	msg = append(msg, value...)
+	return append(msg, '\n')
+}
+
+// journalFieldName turns a field name into a valid journal field name: upper
+// case letters, digits and underscores, not starting with an underscore (those
+// are reserved for trusted fields) or a digit, and at most 64 characters long.
+func journalFieldName(name string) string {
+	name = strings.TrimLeft(strings.ToUpper(name), "_")
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		b = append([]byte("F_"), b...)
+	}
+	if len(b) > 64 {
+		b = b[:64]
+	}
+	return string(b)
+}
+
+// isMessageTooLarge checks whether sending a datagram failed because of its
+// size.
+func isMessageTooLarge(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE || err == syscall.ENOBUFS
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+// +build !linux
+
+package rlog
+
+import "errors"
+
+// JournaldOptions configures a JournaldWriter.
+type JournaldOptions struct {
+	// Socket is the path of the journald socket. By default, it's
+	// /run/systemd/journal/socket.
+	Socket string
+	// Identifier is sent as SYSLOG_IDENTIFIER. By default, it's the name of
+	// the executable.
+	Identifier string
+}
+
+// JournaldWriter sends entries to systemd-journald. journald only exists on
+// Linux, so on this platform it can't be created.
+type JournaldWriter struct{}
+
+// NewJournaldWriter always fails, since journald only exists on Linux.
+func NewJournaldWriter(options JournaldOptions) (*JournaldWriter, error) {
+	return nil, errors.New("journald is only available on linux")
+}
+
+// WriteEntry does nothing.
+func (w *JournaldWriter) WriteEntry(entry *Entry) error {
+	return nil
+}
+
+// Write does nothing.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close does nothing.
+func (w *JournaldWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,191 @@
+//go:build linux
+// +build linux
+
+package rlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// decodeJournalFields parses a message in the native journal protocol.
+func decodeJournalFields(msg []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(msg) > 0 {
+		nl := bytes.IndexByte(msg, '\n')
+		Expect(nl).To(BeNumerically(">=", 0))
+		line := msg[:nl]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			msg = msg[nl+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(msg[nl+1 : nl+9])
+		value := msg[nl+9 : nl+9+int(size)]
+		fields[string(line)] = string(value)
+		Expect(msg[nl+9+int(size)]).To(Equal(byte('\n')))
+		msg = msg[nl+10+int(size):]
+	}
+	return fields
+}
+
+var _ = Describe("Journald", func() {
+	var (
+		dir    string
+		socket *net.UnixConn
+		writer *JournaldWriter
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "rlog-journald")
+		Expect(err).ToNot(HaveOccurred())
+		path := filepath.Join(dir, "socket")
+		socket, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		Expect(err).ToNot(HaveOccurred())
+		writer, err = NewJournaldWriter(JournaldOptions{Socket: path, Identifier: "rlogtest"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		writer.Close()
+		socket.Close()
+		os.RemoveAll(dir)
+	})
+
+	// next returns the fields of the next message, reading them from the
+	// passed file descriptor if the message was sent as one.
+	next := func() map[string]string {
+		buf := make([]byte, 1<<16)
+		oob := make([]byte, 1024)
+		socket.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, oobn, _, _, err := socket.ReadMsgUnix(buf, oob)
+		Expect(err).ToNot(HaveOccurred())
+		if oobn == 0 {
+			return decodeJournalFields(buf[:n])
+		}
+		messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(messages).To(HaveLen(1))
+		fds, err := syscall.ParseUnixRights(&messages[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fds).To(HaveLen(1))
+		file := os.NewFile(uintptr(fds[0]), "journal")
+		defer file.Close()
+		file.Seek(0, 0)
+		content, err := ioutil.ReadAll(file)
+		Expect(err).ToNot(HaveOccurred())
+		return decodeJournalFields(content)
+	}
+
+	It("should send the message, priority and identifier", func() {
+		Expect(writer.WriteEntry(&Entry{Level: levelWarn, Message: "disk almost full"})).To(Succeed())
+		fields := next()
+		Expect(fields).To(HaveKeyWithValue("MESSAGE", "disk almost full"))
+		Expect(fields).To(HaveKeyWithValue("PRIORITY", "4"))
+		Expect(fields).To(HaveKeyWithValue("SYSLOG_IDENTIFIER", "rlogtest"))
+		Expect(fields).ToNot(HaveKey("CODE_FILE"))
+	})
+
+	It("should send the caller info, trace level and stack trace", func() {
+		Expect(writer.WriteEntry(&Entry{
+			Level:      levelTrace,
+			TraceLevel: 2,
+			Message:    "tracing",
+			CallerInfo: EntryCallerInfo{
+				PID:          10,
+				FileName:     "rlog/journald_test.go",
+				FullPath:     "/src/rlog/journald_test.go",
+				Line:         42,
+				FunctionName: "rlog.test",
+			},
+			Stack: []StackFrame{{Function: "main.main", File: "/src/main.go", Line: 7}},
+		})).To(Succeed())
+		fields := next()
+		Expect(fields).To(HaveKeyWithValue("PRIORITY", "7"))
+		Expect(fields).To(HaveKeyWithValue("TRACE_LEVEL", "2"))
+		Expect(fields).To(HaveKeyWithValue("CODE_FILE", "/src/rlog/journald_test.go"))
+		Expect(fields).To(HaveKeyWithValue("CODE_LINE", "42"))
+		Expect(fields).To(HaveKeyWithValue("CODE_FUNC", "rlog.test"))
+		Expect(fields["STACK_TRACE"]).To(ContainSubstring("main.main"))
+		Expect(fields["STACK_TRACE"]).To(ContainSubstring("/src/main.go:7"))
+	})
+
+	It("should send the fields with valid names", func() {
+		Expect(writer.WriteEntry(&Entry{
+			Level:   levelInfo,
+			Message: "request",
+			Fields: FieldsArr{
+				"user id", 10,
+				"_hidden", "x",
+				"2fa", true,
+				"query", "a\nb",
+				strings.Repeat("k", 80), "long",
+			},
+		})).To(Succeed())
+		fields := next()
+		Expect(fields).To(HaveKeyWithValue("USER_ID", "10"))
+		Expect(fields).To(HaveKeyWithValue("HIDDEN", "x"))
+		Expect(fields).To(HaveKeyWithValue("F_2FA", "true"))
+		Expect(fields).To(HaveKeyWithValue("QUERY", "a\nb"))
+		Expect(fields).To(HaveKeyWithValue(strings.Repeat("K", 64), "long"))
+	})
+
+	It("should prefix the fields named like the fields of the journal", func() {
+		Expect(writer.WriteEntry(&Entry{
+			Level:      levelErr,
+			Message:    "request",
+			CallerInfo: EntryCallerInfo{PID: 10, FullPath: "/src/server.go", Line: 42, FunctionName: "main.serve"},
+			Fields: FieldsArr{
+				"message", "user input",
+				"priority", "low",
+				"syslog_identifier", "other",
+				"code_file", "upload.go",
+			},
+		})).To(Succeed())
+		fields := next()
+		Expect(fields).To(HaveKeyWithValue("MESSAGE", "request"))
+		Expect(fields).To(HaveKeyWithValue("PRIORITY", "3"))
+		Expect(fields).To(HaveKeyWithValue("SYSLOG_IDENTIFIER", "rlogtest"))
+		Expect(fields).To(HaveKeyWithValue("CODE_FILE", "/src/server.go"))
+		Expect(fields).To(HaveKeyWithValue("RLOG_MESSAGE", "user input"))
+		Expect(fields).To(HaveKeyWithValue("RLOG_PRIORITY", "low"))
+		Expect(fields).To(HaveKeyWithValue("RLOG_SYSLOG_IDENTIFIER", "other"))
+		Expect(fields).To(HaveKeyWithValue("RLOG_CODE_FILE", "upload.go"))
+	})
+
+	It("should pass messages that are too large for a datagram as file", func() {
+		if _, err := os.Stat("/dev/shm"); err != nil {
+			Skip("/dev/shm is not available")
+		}
+		message := strings.Repeat("x", 1<<20)
+		Expect(writer.WriteEntry(&Entry{Level: levelErr, Message: message})).To(Succeed())
+		fields := next()
+		Expect(fields["MESSAGE"]).To(Equal(message))
+		Expect(fields).To(HaveKeyWithValue("PRIORITY", "3"))
+	})
+
+	It("should send written lines at INFO level", func() {
+		n, err := writer.Write([]byte("plain line\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(11))
+		fields := next()
+		Expect(fields).To(HaveKeyWithValue("MESSAGE", "plain line"))
+		Expect(fields).To(HaveKeyWithValue("PRIORITY", "6"))
+	})
+
+	It("should fail if journald isn't running", func() {
+		_, err := NewJournaldWriter(JournaldOptions{Socket: filepath.Join(dir, "missing")})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,306 @@
+package rlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// templateField identifies a part of an entry which can be used in a line
+// template.
+type templateField int
+
+const (
+	templateFieldLiteral templateField = iota
+	templateFieldTime
+	templateFieldLevel
+	templateFieldShortLevel
+	templateFieldTraceLevel
+	templateFieldCaller
+	templateFieldGID
+	templateFieldMessage
+	templateFieldFields
+)
+
+// Translation from template placeholder to template field.
+var templateFieldNames = map[string]templateField{
+	"time":       templateFieldTime,
+	"level":      templateFieldLevel,
+	"lvl":        templateFieldShortLevel,
+	"tracelevel": templateFieldTraceLevel,
+	"caller":     templateFieldCaller,
+	"gid":        templateFieldGID,
+	"msg":        templateFieldMessage,
+	"fields":     templateFieldFields,
+}
+
+type templatePart struct {
+	field   templateField
+	literal string
+	// width is the minimum width in characters. Values are right aligned,
+	// or left aligned if leftAlign is set, and padded with spaces, or with
+	// zeros if zeroPad is set.
+	width     int
+	leftAlign bool
+	zeroPad   bool
+	// maxWidth, if greater than 0, is the width at which values are cut.
+	maxWidth int
+	// section is the number of the optional section holding the part, or 0.
+	section int
+}
+
+// templateSection is a range of parts which is left out if one of its
+// placeholders is empty.
+type templateSection struct {
+	start, end int
+}
+
+// LineTemplate describes the layout of the lines of the default formatter. It
+// is compiled once from the RLOG_FORMAT_TEMPLATE setting, so rendering it
+// doesn't need to parse anything.
+type LineTemplate struct {
+	parts    []templatePart
+	sections []templateSection
+}
+
+// ParseLineTemplate compiles the value of the RLOG_FORMAT_TEMPLATE setting,
+// e.g. "{time} {level:-8} [{caller} ]{msg:-60} {fields}". The placeholders
+// are:
+//
+//   - {time}: the timestamp.
+//   - {level}: the name of the level, e.g. WARN or CRITICAL.
+//   - {lvl}: the first four letters of the name of the level, e.g. CRIT.
+//   - {tracelevel}: the trace level, empty for other entries.
+//   - {caller}: the caller info, rendered with the caller format.
+//   - {gid}: the goroutine ID.
+//   - {msg}: the message.
+//   - {fields}: the fields, as key=value pairs.
+//
+// A placeholder can have a format after a colon, like the verbs of package
+// fmt: {msg:-60} pads the message with spaces to 60 characters, left aligned,
+// {level:8} right aligned, {tracelevel:05} with zeros, and {caller:.30} cuts
+// the caller info at 30 characters. Both can be combined, e.g. {msg:-20.20}.
+//
+// The parts of the template in square brackets are optional: they are left
+// out if one of their placeholders is empty. Literal braces, brackets and
+// backslashes are escaped with a backslash.
+func ParseLineTemplate(s string) (*LineTemplate, error) {
+	template := &LineTemplate{}
+	section := 0
+	var literal []byte
+	flush := func() {
+		if len(literal) > 0 {
+			template.parts = append(template.parts, templatePart{literal: string(literal), section: section})
+			literal = literal[:0]
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("unterminated escape in format template '%s'", s)
+			}
+			i++
+			literal = append(literal, s[i])
+		case '[':
+			if section > 0 {
+				return nil, fmt.Errorf("nested optional part in format template '%s'", s)
+			}
+			flush()
+			template.sections = append(template.sections, templateSection{start: len(template.parts)})
+			section = len(template.sections)
+		case ']':
+			if section == 0 {
+				return nil, fmt.Errorf("unexpected ']' in format template '%s'", s)
+			}
+			flush()
+			template.sections[section-1].end = len(template.parts)
+			section = 0
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated placeholder in format template '%s'", s)
+			}
+			part, err := parseTemplatePlaceholder(s[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			part.section = section
+			template.parts = append(template.parts, part)
+			i += end
+		case '}':
+			return nil, fmt.Errorf("unexpected '}' in format template '%s'", s)
+		default:
+			literal = append(literal, c)
+		}
+	}
+	if section > 0 {
+		return nil, fmt.Errorf("unterminated optional part in format template '%s'", s)
+	}
+	flush()
+	return template, nil
+}
+
+// maxTemplateWidth is the largest width and maximum width a placeholder may
+// have, so that a typo in the template can't pad every line to gigabytes.
+const maxTemplateWidth = 1024
+
+// parseTemplatePlaceholder parses the name and format of a placeholder,
+// without the braces.
+func parseTemplatePlaceholder(s string) (templatePart, error) {
+	name, format := s, ""
+	if colon := strings.IndexByte(s, ':'); colon >= 0 {
+		name, format = s[:colon], s[colon+1:]
+	}
+	field, ok := templateFieldNames[name]
+	if !ok {
+		return templatePart{}, fmt.Errorf("unknown placeholder '{%s}' in format template", name)
+	}
+	part := templatePart{field: field}
+	if strings.HasPrefix(format, "-") {
+		part.leftAlign = true
+		format = format[1:]
+	} else if strings.HasPrefix(format, "0") {
+		part.zeroPad = true
+		format = format[1:]
+	}
+	width, maxWidth := format, ""
+	if dot := strings.IndexByte(format, '.'); dot >= 0 {
+		width, maxWidth = format[:dot], format[dot+1:]
+		if maxWidth == "" {
+			return templatePart{}, fmt.Errorf("malformed format '%s' of placeholder '{%s}'", s[len(name)+1:], name)
+		}
+	}
+	var err error
+	if width != "" {
+		if part.width, err = strconv.Atoi(width); err != nil || part.width < 0 || part.width > maxTemplateWidth {
+			return templatePart{}, fmt.Errorf("malformed format '%s' of placeholder '{%s}'", s[len(name)+1:], name)
+		}
+	}
+	if maxWidth != "" {
+		if part.maxWidth, err = strconv.Atoi(maxWidth); err != nil || part.maxWidth <= 0 || part.maxWidth > maxTemplateWidth {
+			return templatePart{}, fmt.Errorf("malformed format '%s' of placeholder '{%s}'", s[len(name)+1:], name)
+		}
+	}
+	return part, nil
+}
+
+// appendTemplate renders the line of the entry according to the template.
+func (formatter *defaultFormatter) appendTemplate(output []byte, template *LineTemplate, entry *Entry) []byte {
+	for i := 0; i < len(template.parts); {
+		part := &template.parts[i]
+		if part.section > 0 {
+			section := template.sections[part.section-1]
+			if i == section.start && !templateSectionPresent(template.parts[section.start:section.end], entry) {
+				i = section.end
+				continue
+			}
+		}
+		if part.field == templateFieldLiteral {
+			output = append(output, part.literal...)
+		} else {
+			value, cl := formatter.templateValue(part.field, entry)
+			if part.field == templateFieldMessage {
+				value = formatter.Multiline.format(value, output)
+			}
+			value = part.layout(value)
+			if cl != nil {
+				value = cl(value)
+			}
+			output = append(output, value...)
+		}
+		i++
+	}
+	return output
+}
+
+// templateSectionPresent tells whether all the placeholders of an optional
+// section have a value.
+func templateSectionPresent(parts []templatePart, entry *Entry) bool {
+	for i := range parts {
+		empty := false
+		switch parts[i].field {
+		case templateFieldTime:
+			empty = entry.Time.IsZero()
+		case templateFieldTraceLevel:
+			empty = !(entry.Level == levelTrace && entry.TraceLevel > notATrace)
+		case templateFieldCaller:
+			empty = entry.CallerInfo.PID <= 0
+		case templateFieldGID:
+			empty = entry.CallerInfo.GID == 0
+		case templateFieldMessage:
+			empty = entry.Message == ""
+		case templateFieldFields:
+			empty = len(entry.Fields) == 0
+		}
+		if empty {
+			return false
+		}
+	}
+	return true
+}
+
+// templateValue returns the value of a placeholder, along with the color it's
+// rendered in, if any. The fields are colored already.
+func (formatter *defaultFormatter) templateValue(field templateField, entry *Entry) (string, Color) {
+	switch field {
+	case templateFieldTime:
+		if !entry.Time.IsZero() {
+			return formatter.TimeFormat.Format(entry.Time), formatter.TimeColor
+		}
+	case templateFieldLevel:
+		return entry.Level.String(), formatter.Color(entry)
+	case templateFieldShortLevel:
+		return string(entry.Level.Bytes()[:4]), formatter.Color(entry)
+	case templateFieldTraceLevel:
+		if entry.Level == levelTrace && entry.TraceLevel > notATrace {
+			return strconv.Itoa(entry.TraceLevel), nil
+		}
+	case templateFieldCaller:
+		if entry.CallerInfo.PID > 0 {
+			callerFormat := formatter.CallerFormat
+			if callerFormat == nil {
+				callerFormat = LongCallerFormat
+			}
+			return callerFormat.String(&entry.CallerInfo), formatter.CallerColor
+		}
+	case templateFieldGID:
+		if entry.CallerInfo.GID > 0 {
+			return strconv.FormatUint(entry.CallerInfo.GID, 10), nil
+		}
+	case templateFieldMessage:
+		return entry.Message, nil
+	case templateFieldFields:
+		if len(entry.Fields) > 0 {
+			return formatter.formatFields(entry), nil
+		}
+	}
+	return "", nil
+}
+
+// layout cuts and pads the value according to the format of the part.
+func (part *templatePart) layout(value string) string {
+	if part.maxWidth > 0 && utf8.RuneCountInString(value) > part.maxWidth {
+		n := 0
+		for i := range value {
+			if n == part.maxWidth {
+				value = value[:i]
+				break
+			}
+			n++
+		}
+	}
+	padding := part.width - utf8.RuneCountInString(value)
+	if padding <= 0 {
+		return value
+	}
+	switch {
+	case part.leftAlign:
+		return value + strings.Repeat(" ", padding)
+	case part.zeroPad:
+		return strings.Repeat("0", padding) + value
+	}
+	return strings.Repeat(" ", padding) + value
+}
@@ -0,0 +1,140 @@
+package rlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LineTemplate", func() {
+	format := func(template string, entry *Entry) string {
+		t, err := ParseLineTemplate(template)
+		Expect(err).ToNot(HaveOccurred())
+		f := NewDefaultFormatterWithColors(nil, ColorNever, DefaultColorScheme())
+		f.CallerFormat = ShortCallerFormat
+		f.Template = t
+		return string(f.Format(entry))
+	}
+
+	entry := func() *Entry {
+		return &Entry{
+			Time:    time.Date(2019, 1, 3, 1, 3, 4, 0, time.UTC),
+			Level:   levelWarn,
+			Message: "disk almost full",
+			Fields:  FieldsArr{"free", "5%"},
+			CallerInfo: EntryCallerInfo{
+				PID:      42,
+				FileName: "app/disk.go",
+				Line:     12,
+			},
+		}
+	}
+
+	It("should render the placeholders", func() {
+		Expect(format("{time} {level} {lvl} {caller} {msg} {fields}", entry())).
+			To(Equal("2019-01-03T01:03:04Z WARN WARN app/disk.go:12 disk almost full free=5%\n"))
+	})
+
+	It("should render full and abbreviated level names", func() {
+		e := entry()
+		e.Level = levelCrit
+		Expect(format("{level}|{lvl}", e)).To(Equal("CRITICAL|CRIT\n"))
+	})
+
+	It("should pad, align and cut the values", func() {
+		e := entry()
+		e.Level = levelTrace
+		e.TraceLevel = 7
+		Expect(format("{level:-8}|{level:8}|{tracelevel:05}|{msg:.4}|{msg:-6.4}|", e)).
+			To(Equal("TRACE   |   TRACE|00007|disk|disk  |\n"))
+	})
+
+	It("should count characters instead of bytes", func() {
+		e := entry()
+		e.Message = "größe"
+		Expect(format("{msg:.3}|{msg:-6}|", e)).To(Equal("grö|größe |\n"))
+	})
+
+	It("should leave out optional parts with empty placeholders", func() {
+		e := entry()
+		e.CallerInfo = EntryCallerInfo{}
+		e.Fields = nil
+		Expect(format("{lvl}[ \\[{caller}\\]][({tracelevel})] {msg}[ {fields}]", e)).
+			To(Equal("WARN disk almost full\n"))
+		Expect(format("{lvl}[ \\[{caller}\\]] {msg}[ {fields}]", entry())).
+			To(Equal("WARN [app/disk.go:12] disk almost full free=5%\n"))
+	})
+
+	It("should append the stack trace", func() {
+		e := entry()
+		e.Stack = []StackFrame{{Function: "main.main", File: "/app/main.go", Line: 3}}
+		Expect(format("{msg}", e)).To(Equal("disk almost full\n\tmain.main\n\t\t/app/main.go:3\n"))
+	})
+
+	It("should color the level", func() {
+		t, err := ParseLineTemplate("{lvl:-5}|")
+		Expect(err).ToNot(HaveOccurred())
+		f := NewDefaultFormatterWithColors(nil, ColorAlways, DefaultColorScheme())
+		f.Template = t
+		Expect(string(f.Format(entry()))).To(Equal("\x1b[33mWARN \x1b[0m|\n"))
+	})
+
+	It("should fail with malformed templates", func() {
+		for _, template := range []string{
+			"{unknown}",
+			"{msg",
+			"msg}",
+			"{msg:x}",
+			"{msg:5.}",
+			"{msg:.0}",
+			"{msg:99999999999}",
+			"{msg:1025}",
+			"{msg:10.2000}",
+			"[{msg}",
+			"{msg}]",
+			"[[{msg}]]",
+			"{msg}\\",
+		} {
+			_, err := ParseLineTemplate(template)
+			Expect(err).To(HaveOccurred(), template)
+		}
+		_, err := ParseLineTemplate("{msg:-1024.1024}")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should be used by the logger", func() {
+		logger, err := NewLogger(Config{
+			LogNoTime: true,
+			Template:  "{level:-5} {msg}[ {fields}]",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		buff := bytes.NewBuffer(nil)
+		logger.SetOutput(buff)
+		logger.WithField("a", 1).Info("hello")
+		logger.Error("bye")
+		Expect(buff.String()).To(Equal("INFO  hello a=1\nERROR bye\n"))
+
+		_, err = NewLogger(Config{Template: "{oops}"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func BenchmarkLineTemplate(b *testing.B) {
+	buff := bytes.NewBuffer(nil)
+	logger, err := NewLogger(Config{
+		Template: "{time} {level:-8} [{caller} ]{msg:-60} {fields}",
+	})
+	if err != nil {
+		panic(err)
+	}
+	logger.SetOutput(buff)
+	sub := logger.WithField("var1", "value1")
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sub.Info("this is a test")
+		buff.Reset()
+	}
+}
@@ -1,10 +1,17 @@
 package rlog
 
+import "context"
+
 type Fields map[string]interface{}
 
 type FieldsArr []interface{}
 
 // Logger is the interface that represents a logging unit.
+//
+// The loggers of this package also have the methods WithCallerSkip,
+// WithGroup and WithContext. They aren't part of the interface, so that
+// implementations written before them keep working; LoggerWithCallerSkip,
+// LoggerWithGroup and LoggerWithContext use them if a logger has them.
 type Logger interface {
 	WithPrefix(prefix string) Logger
 	WithField(name string, value interface{}) Logger
@@ -28,6 +35,46 @@ type Logger interface {
 	Criticalf(format string, a ...interface{})
 }
 
+// callerLogger is implemented by the loggers of this package. It allows a
+// subLogger to hand an entry to its parent while keeping track of how deep
+// in the stack the original call site is.
+type callerLogger interface {
+	log(skip int, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{})
+	logPC(pc uintptr, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{})
+}
+
+// levelChecker is implemented by the loggers of this package. It allows
+// adapters to check cheaply whether a level is enabled at all.
+type levelChecker interface {
+	mayLog(logLevel Level, traceLevel int) bool
+}
+
+// callerSkipper, grouper and contextLogger are implemented by the loggers
+// of this package, and may be by others. See Logger.
+type callerSkipper interface {
+	WithCallerSkip(skip int) Logger
+}
+
+type grouper interface {
+	WithGroup(name string) Logger
+}
+
+type contextLogger interface {
+	WithContext(ctx context.Context) Logger
+}
+
+// LoggerWithCallerSkip returns a sub-logger of l which reports the caller
+// `skip` stack frames further up. It is meant for packages wrapping a Logger,
+// so that the caller info and the per-file filters refer to the code calling
+// the wrapper instead of the wrapper itself. Loggers without WithCallerSkip
+// are returned as they are.
+func LoggerWithCallerSkip(l Logger, skip int) Logger {
+	if cs, ok := l.(callerSkipper); ok {
+		return cs.WithCallerSkip(skip)
+	}
+	return l
+}
+
 // subLogger is a cheap struct that works on top of a `Logger` for aggregation
 // additional information to the entries triggered by it.
 type subLogger struct {
@@ -35,14 +82,21 @@ type subLogger struct {
 	prefix                string
 	additionalInformation string
 	additionalFields      FieldsArr
+	callerSkip            int
+	group                 string
 }
 
 func newSubLogger(logger Logger, fields FieldsArr) *subLogger {
-	return &subLogger{
-		logger:                logger,
-		additionalInformation: logger.Formatter().FormatFields(fields),
-		additionalFields:      fields,
+	l := &subLogger{
+		logger:           logger,
+		additionalFields: fields,
+	}
+	// Valuers are resolved only when an entry is logged, which renders the
+	// fields again.
+	if !hasValuer(fields) {
+		l.additionalInformation = logger.Formatter().FormatFields(fields)
 	}
+	return l
 }
 
 func (logger *subLogger) WithPrefix(prefix string) Logger {
@@ -74,11 +128,60 @@ func (logger *subLogger) WithFieldsArr(fields ...interface{}) Logger {
 	return newSubLogger(logger, fields)
 }
 
+// WithCallerSkip returns a sub-logger which reports the caller `skip` stack
+// frames further up. See LoggerWithCallerSkip.
+func (logger *subLogger) WithCallerSkip(skip int) Logger {
+	l := newSubLogger(logger, nil)
+	l.callerSkip = skip
+	return l
+}
+
 func (logger *subLogger) Formatter() LogFormatter {
 	return logger.logger.Formatter()
 }
 
 func (logger *subLogger) BasicLog(logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	if logLevel != levelTrace {
+		traceLevel = notATrace
+	}
+	logger.log(1, logLevel, traceLevel, additionalInformation, fields, format, a...)
+}
+
+// log adds the information of this sub-logger to the entry and passes it on
+// to the parent logger. Each sub-logger in the chain adds its own frame to the
+// stack, so skip is incremented on the way (plus any skip requested through
+// WithCallerSkip).
+func (logger *subLogger) log(skip int, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	ai, fields, format, a := logger.decorate(additionalInformation, fields, format, a)
+	if parent, ok := logger.logger.(callerLogger); ok {
+		parent.log(skip+1+logger.callerSkip, logLevel, traceLevel, ai, fields, format, a...)
+	} else {
+		logger.logger.BasicLog(logLevel, traceLevel, ai, fields, format, a...)
+	}
+}
+
+// logPC adds the information of this sub-logger to the entry and passes it on
+// to the parent logger, along with the program counter of the call site.
+func (logger *subLogger) logPC(pc uintptr, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	ai, fields, format, a := logger.decorate(additionalInformation, fields, format, a)
+	if parent, ok := logger.logger.(callerLogger); ok {
+		parent.logPC(pc, logLevel, traceLevel, ai, fields, format, a...)
+	} else {
+		logger.logger.BasicLog(logLevel, traceLevel, ai, fields, format, a...)
+	}
+}
+
+func (logger *subLogger) mayLog(logLevel Level, traceLevel int) bool {
+	if parent, ok := logger.logger.(levelChecker); ok {
+		return parent.mayLog(logLevel, traceLevel)
+	}
+	return true
+}
+
+// decorate adds the prefix, fields and additional information of this
+// sub-logger to those of the entry.
+func (logger *subLogger) decorate(additionalInformation string, fields FieldsArr, format string, a []interface{}) (string, FieldsArr, string, []interface{}) {
+	additionalInformation, fields = logger.nest(additionalInformation, fields)
 	ai := logger.additionalInformation
 	if len(ai) > 0 {
 		if len(additionalInformation) > 0 {
@@ -94,11 +197,11 @@ func (logger *subLogger) BasicLog(logLevel Level, traceLevel int, additionalInfo
 			format = logger.prefix + format
 		}
 	}
-	logger.logger.BasicLog(logLevel, traceLevel, ai, append(logger.additionalFields, fields...), format, a...)
+	return ai, append(logger.additionalFields, fields...), format, a
 }
 
 func (logger *subLogger) internalLog(logLevel Level, traceLevel int, format string, a ...interface{}) {
-	logger.BasicLog(logLevel, traceLevel, "", nil, format, a...)
+	logger.log(2, logLevel, traceLevel, "", nil, format, a...)
 }
 
 // Trace is for low level tracing of activities. It takes an additional 'level'
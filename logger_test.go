@@ -2,6 +2,8 @@ package rlog
 
 import (
 	"bytes"
+	"fmt"
+	"runtime"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
@@ -149,4 +151,113 @@ level=INFO var1=value1 var2=value2 msg="this is in a sublogger"`))
 			Expect(msgs[1]).To(Equal(`INFO[00000] prefix1prefix2this is in a sublogger                         var1=value1`))
 		})
 	})
+
+	Describe("CallerInfo", func() {
+		// nextLine returns the line following the call to nextLine.
+		nextLine := func() int {
+			_, _, line, _ := runtime.Caller(1)
+			return line + 1
+		}
+
+		// takeOutput returns the output written so far and clears the buffer.
+		takeOutput := func(buff *bytes.Buffer) string {
+			out := buff.String()
+			buff.Reset()
+			return out
+		}
+
+		newCallerLogger := func(config Config) (*logger, *bytes.Buffer) {
+			config.ShowCallerInfo = true
+			return newTestLogger(config)
+		}
+
+		It("should report the call site of the logger", func() {
+			logger, buff := newCallerLogger(Config{})
+			line := nextLine()
+			logger.Info("this is a INFO")
+			Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)))
+
+			line = nextLine()
+			logger.BasicLog(levelInfo, notATrace, "", nil, "", "this is a INFO")
+			Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)))
+		})
+
+		It("should report the call site at every sub-logger nesting level", func() {
+			logger, buff := newCallerLogger(Config{TraceLevel: "10"})
+			var l Logger = logger
+			for level := 0; level < 4; level++ {
+				line := nextLine()
+				l.Info("this is a INFO")
+				Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)), "nesting level %d", level)
+
+				line = nextLine()
+				l.Tracef(1, "this is a TRACE %d", level)
+				Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)), "nesting level %d", level)
+
+				line = nextLine()
+				l.BasicLog(levelInfo, notATrace, "", nil, "", "this is a INFO")
+				Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)), "nesting level %d", level)
+
+				l = l.WithField("level", level).WithPrefix("prefix")
+			}
+		})
+
+		It("should skip the frames requested with WithCallerSkip", func() {
+			logger, buff := newCallerLogger(Config{})
+			wrapper := func(l Logger, msg string) {
+				LoggerWithCallerSkip(l, 1).Info(msg)
+			}
+			line := nextLine()
+			wrapper(logger, "this is a INFO")
+			Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)))
+
+			line = nextLine()
+			wrapper(logger.WithField("var1", "value1"), "this is a INFO")
+			Expect(takeOutput(buff)).To(ContainSubstring(fmt.Sprintf("/logger_test.go:%d ", line)))
+		})
+
+		It("should report the caller of a BasicLog wrapper skipping its frame", func() {
+			// Traces are off, so the trace level passed to BasicLog must
+			// not filter the WARN lines.
+			logger, buff := newCallerLogger(Config{})
+			wrapper := func(l Logger, level Level, msg string) {
+				l.BasicLog(level, 1, "", nil, "", msg)
+			}
+			line := nextLine()
+			wrapper(logger, LevelWarn, "this is a WARN")
+			Expect(takeOutput(buff)).To(MatchRegexp(`^WARN\[.*/logger_test.go:%d .*this is a WARN`, line-3))
+
+			line = nextLine()
+			wrapper(LoggerWithCallerSkip(logger, 1), LevelWarn, "this is a WARN")
+			Expect(takeOutput(buff)).To(MatchRegexp(`^WARN\[.*/logger_test.go:%d .*this is a WARN`, line))
+		})
+
+		It("should render the caller info in the configured format", func() {
+			logger, buff := newCallerLogger(Config{CallerFormat: "{basename}:{line} {shortfunction}"})
+			line := nextLine()
+			logger.Info("this is a INFO")
+			Expect(takeOutput(buff)).To(MatchRegexp(`^INFO\[00000\] \[logger_test.go:%d [^ .]+\.func[0-9.]+\]  this is a INFO`, line))
+
+			logger, buff = newCallerLogger(Config{Formatter: "text", CallerFormat: "short"})
+			line = nextLine()
+			logger.Info("this is a INFO")
+			Expect(takeOutput(buff)).To(MatchRegexp(`^level=INFO caller="[^/"]+/logger_test.go:%d" msg="this is a INFO"\n$`, line))
+		})
+
+		It("should fail creating a logger with an unknown caller format", func() {
+			_, err := NewLogger(Config{CallerFormat: "medium"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should apply per-file filters to the call site of a sub-logger", func() {
+			logger, buff := newCallerLogger(Config{
+				LogLevel: "logger.go=DEBUG,logger_test.go=WARN,INFO",
+			})
+			sublogger := logger.WithField("var1", "value1").WithField("var2", "value2")
+			sublogger.Info("this is a INFO")
+			Expect(buff.String()).To(BeEmpty())
+			sublogger.Warn("this is a WARN")
+			Expect(buff.String()).To(ContainSubstring("this is a WARN"))
+		})
+	})
 })
@@ -0,0 +1,113 @@
+package rlog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// MultilineMode tells how messages spanning several lines are written.
+type MultilineMode int
+
+const (
+	// MultilineRaw writes the line breaks as they are. This is the default.
+	MultilineRaw MultilineMode = iota
+	// MultilineEscape writes the line breaks as \n (and \r), so each entry
+	// stays on a single line.
+	MultilineEscape
+	// MultilineIndent indents the continuation lines under the message.
+	MultilineIndent
+	// MultilineSplit writes each line as its own entry. The entries share the
+	// multiline_id field and carry their line number in multiline_line.
+	MultilineSplit
+)
+
+// The fields added to the entries of split messages.
+const (
+	MultilineIDField   = "multiline_id"
+	MultilineLineField = "multiline_line"
+)
+
+// multilineSequence numbers the split messages.
+var multilineSequence uint64
+
+// ParseMultilineMode translates the RLOG_MULTILINE setting: raw (default),
+// escape, indent or split.
+func ParseMultilineMode(s string) (MultilineMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "raw":
+		return MultilineRaw, nil
+	case "escape":
+		return MultilineEscape, nil
+	case "indent":
+		return MultilineIndent, nil
+	case "split":
+		return MultilineSplit, nil
+	}
+	return MultilineRaw, fmt.Errorf("multiline mode '%s' is unknown", s)
+}
+
+var multilineEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+
+// format escapes or indents the line breaks of a message which is appended to
+// the output. The JSON based formatters always escape line breaks, so only the
+// text formatters use it.
+func (mode MultilineMode) format(msg string, output []byte) string {
+	if (mode != MultilineEscape && mode != MultilineIndent) || !strings.ContainsAny(msg, "\r\n") {
+		return msg
+	}
+	if mode == MultilineEscape {
+		return multilineEscaper.Replace(msg)
+	}
+	msg = strings.Replace(msg, "\r\n", "\n", -1)
+	return strings.Replace(msg, "\n", "\n"+strings.Repeat(" ", visibleWidth(output)), -1)
+}
+
+// visibleWidth returns the number of characters of the last line of the
+// output, without ANSI escape sequences.
+func visibleWidth(output []byte) int {
+	if i := bytes.LastIndexByte(output, '\n'); i >= 0 {
+		output = output[i+1:]
+	}
+	width := 0
+	for i := 0; i < len(output); {
+		if output[i] == '\x1b' && i+1 < len(output) && output[i+1] == '[' {
+			// Skip the parameters up to the final byte of the sequence.
+			i += 2
+			for i < len(output) && (output[i] < 0x40 || output[i] > 0x7e) {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRune(output[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// writeLines writes each line of the message of the entry as its own entry.
+// The stack trace is only attached to the first one.
+func (l *logger) writeLines(entry *Entry) {
+	message, fields, fieldsCache, stack := entry.Message, entry.Fields, entry.FieldsCache, entry.Stack
+	id := atomic.AddUint64(&multilineSequence, 1)
+	lines := strings.Split(strings.TrimSuffix(message, "\n"), "\n")
+	for i, line := range lines {
+		extra := FieldsArr{MultilineIDField, id, MultilineLineField, i + 1}
+		entry.Message = strings.TrimSuffix(line, "\r")
+		// The fields may be shared, so they are never appended to in place.
+		entry.Fields = append(fields[:len(fields):len(fields)], extra...)
+		entry.FieldsCache = l.formatter.FormatFields(extra)
+		if fieldsCache != "" {
+			entry.FieldsCache = fieldsCache + l.formatter.Separator() + entry.FieldsCache
+		}
+		if i > 0 {
+			entry.Stack = nil
+		}
+		l.write(entry)
+	}
+	entry.Message, entry.Fields, entry.FieldsCache, entry.Stack = message, fields, fieldsCache, stack
+}
@@ -0,0 +1,97 @@
+package rlog
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multiline", func() {
+	const query = "SELECT *\nFROM users\r\nWHERE id = 1"
+
+	It("should parse the modes", func() {
+		Expect(ParseMultilineMode("")).To(Equal(MultilineRaw))
+		Expect(ParseMultilineMode("Escape")).To(Equal(MultilineEscape))
+		Expect(ParseMultilineMode("indent")).To(Equal(MultilineIndent))
+		Expect(ParseMultilineMode("split")).To(Equal(MultilineSplit))
+		_, err := ParseMultilineMode("wrap")
+		Expect(err).To(HaveOccurred())
+		_, err = NewLogger(Config{Multiline: "wrap"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should write the line breaks as they are by default", func() {
+		logger, buff := newTestLogger(Config{})
+		logger.Info(query)
+		Expect(buff.String()).To(Equal("INFO[00000] " + query + "\n"))
+	})
+
+	It("should escape the line breaks in the default formatter", func() {
+		logger, buff := newTestLogger(Config{Multiline: "escape"})
+		logger.Info(query)
+		Expect(buff.String()).To(Equal(`INFO[00000] SELECT *\nFROM users\r\nWHERE id = 1` + "\n"))
+	})
+
+	It("should escape the line breaks in the text formatter", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text", Multiline: "escape"})
+		logger.Info(query)
+		Expect(buff.String()).To(Equal(`level=INFO msg="SELECT *\nFROM users\r\nWHERE id = 1"` + "\n"))
+	})
+
+	It("should indent the continuation lines under the message", func() {
+		logger, buff := newTestLogger(Config{Multiline: "indent", Color: "always"})
+		logger.Info(query)
+		Expect(buff.String()).To(HaveSuffix("SELECT *\n            FROM users\n            WHERE id = 1\n"))
+
+		logger, buff = newTestLogger(Config{Formatter: "text", Multiline: "indent"})
+		logger.Info(query)
+		Expect(buff.String()).To(Equal("level=INFO msg=\"SELECT *\n" +
+			"                FROM users\n" +
+			"                WHERE id = 1\"\n"))
+	})
+
+	It("should indent the continuation lines of templates", func() {
+		logger, buff := newTestLogger(Config{Multiline: "indent", Template: "{level:-5} | {msg}"})
+		logger.Info("a\nb")
+		Expect(buff.String()).To(Equal("INFO  | a\n        b\n"))
+	})
+
+	It("should split the lines into entries", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json", Multiline: "split"})
+		logger.WithField("db", "main").Info(query + "\n")
+		logger.Info("a\nb")
+
+		entries := decodeEntries(buff)
+		Expect(entries).To(HaveLen(5))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "SELECT *"))
+		Expect(entries[1]).To(HaveKeyWithValue("msg", "FROM users"))
+		Expect(entries[2]).To(HaveKeyWithValue("msg", "WHERE id = 1"))
+		for i, entry := range entries[:3] {
+			Expect(entry).To(HaveKeyWithValue("db", "main"))
+			Expect(entry).To(HaveKeyWithValue(MultilineIDField, entries[0][MultilineIDField]))
+			Expect(entry).To(HaveKeyWithValue(MultilineLineField, float64(i+1)))
+		}
+		Expect(entries[3][MultilineIDField]).ToNot(Equal(entries[0][MultilineIDField]))
+	})
+
+	It("should split the lines in the text formatter", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text", Multiline: "split"})
+		logger.WithField("db", "main").Info("a\nb")
+
+		lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(MatchRegexp(`db=main multiline_id=\d+ multiline_line=1 msg="a"`))
+		Expect(lines[1]).To(MatchRegexp(`db=main multiline_id=\d+ multiline_line=2 msg="b"`))
+	})
+
+	It("should attach the stack trace to the first line only", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json", Multiline: "split", StacktraceLevel: "ERROR"})
+		logger.Error("a\nb")
+
+		lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`"stack":`))
+		Expect(lines[1]).ToNot(ContainSubstring(`"stack":`))
+	})
+})
@@ -0,0 +1,475 @@
+package rlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Defaults of the NetworkOptions.
+const (
+	defaultNetworkBufferSize   = 1024
+	defaultNetworkMaxLineSize  = 64 * 1024
+	defaultNetworkMaxSpillSize = 100 * 1024 * 1024
+	// The largest UDP payload, without the line break.
+	defaultDatagramMaxLineSize = 65507 - 1
+	defaultNetworkMinBackoff   = 100 * time.Millisecond
+	defaultNetworkMaxBackoff   = 30 * time.Second
+	networkWriteTimeout        = 10 * time.Second
+)
+
+// NetworkOptions configures a NetworkWriter.
+type NetworkOptions struct {
+	// Network is "tcp", "udp", "unix" or "unixgram" (including the 4 and 6
+	// variants of tcp and udp).
+	Network string
+	// Address is a host:port for tcp and udp, or the path of the socket.
+	Address string
+	// BufferSize is the number of lines kept in memory while the connection
+	// is down. By default, it's 1024.
+	BufferSize int
+	// MaxLineSize is the maximum length of a line in bytes, without the line
+	// break. Longer lines are truncated. By default, it's 64 KiB, or 65506
+	// bytes for udp and unixgram, so that a line fits into a datagram.
+	MaxLineSize int
+	// SpillFile is the path of a file which takes the lines that don't fit
+	// into the buffer any more. If it is empty, the oldest lines are dropped
+	// instead. Lines which are still in the file when the program ends are
+	// sent the next time the file is used.
+	SpillFile string
+	// MaxSpillSize is the maximum size of the spill file in bytes. Once it's
+	// reached, new lines are dropped until the file was sent. By default,
+	// it's 100 MiB.
+	MaxSpillSize int64
+	// MinBackoff and MaxBackoff limit the time waited between two attempts
+	// to connect. The time doubles with each failed attempt. By default,
+	// it's between 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NetworkWriter ships formatted lines to a TCP, UDP or unix socket, for
+// example to a local log agent. Lines are sent in the background, so that
+// logging never waits for the network. While the connection is down, the
+// writer tries to reconnect with exponential backoff and keeps the lines in a
+// bounded buffer, which overflows into the spill file (if there is one).
+type NetworkWriter struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	options NetworkOptions
+	queue   [][]byte
+	dropped int
+	closing bool
+	stop    chan struct{}
+	done    chan struct{}
+
+	// The spill file holds the lines between spillRead and spillSize. As
+	// long as there are some, new lines go there as well, so that the order
+	// is kept.
+	spill     *os.File
+	spillRead int64
+	spillSize int64
+
+	// Only used by the sending goroutine, except for the closing of conn.
+	connMutex sync.Mutex
+	conn      net.Conn
+}
+
+// ParseNetworkURL translates a RLOG_LOG_STREAM value like
+// "tcp://localhost:5170" or "unix:///run/agent.sock" into NetworkOptions. The
+// query parameters "buffer", "max_line", "spill" and "max_spill" set
+// BufferSize, MaxLineSize, SpillFile and MaxSpillSize, for example
+// "udp://localhost:5170?buffer=10000&spill=/var/spool/app.log".
+func ParseNetworkURL(s string) (NetworkOptions, error) {
+	var options NetworkOptions
+	u, err := url.Parse(s)
+	if err != nil {
+		return options, fmt.Errorf("log stream '%s' is malformed: %s", s, err)
+	}
+	options.Network = u.Scheme
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		options.Address = u.Host
+	case "unix", "unixgram":
+		options.Address = u.Host + u.Path
+	default:
+		return options, fmt.Errorf("log stream network '%s' is not supported", u.Scheme)
+	}
+	if options.Address == "" {
+		return options, fmt.Errorf("log stream '%s' has no address", s)
+	}
+	query := u.Query()
+	for name := range query {
+		value := query.Get(name)
+		switch name {
+		case "buffer":
+			options.BufferSize, err = strconv.Atoi(value)
+		case "max_line":
+			options.MaxLineSize, err = strconv.Atoi(value)
+		case "spill":
+			options.SpillFile = value
+		case "max_spill":
+			options.MaxSpillSize, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return options, fmt.Errorf("log stream parameter '%s' is unknown", name)
+		}
+		if err != nil {
+			return options, fmt.Errorf("log stream parameter '%s' is malformed: %s", name, err)
+		}
+	}
+	return options, nil
+}
+
+// NewNetworkWriter starts shipping lines to the address in the options. It
+// doesn't wait for the connection, so it only fails if the spill file can't
+// be opened.
+func NewNetworkWriter(options NetworkOptions) (*NetworkWriter, error) {
+	if options.BufferSize <= 0 {
+		options.BufferSize = defaultNetworkBufferSize
+	}
+	if options.MaxLineSize <= 0 {
+		options.MaxLineSize = defaultNetworkMaxLineSize
+		if isDatagramNetwork(options.Network) {
+			options.MaxLineSize = defaultDatagramMaxLineSize
+		}
+	}
+	if options.MaxSpillSize <= 0 {
+		options.MaxSpillSize = defaultNetworkMaxSpillSize
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = defaultNetworkMinBackoff
+	}
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = defaultNetworkMaxBackoff
+		if options.MaxBackoff < options.MinBackoff {
+			options.MaxBackoff = options.MinBackoff
+		}
+	}
+	w := &NetworkWriter{
+		options: options,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mutex)
+	if options.SpillFile != "" {
+		spill, err := os.OpenFile(options.SpillFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		info, err := spill.Stat()
+		if err != nil {
+			spill.Close()
+			return nil, err
+		}
+		w.spill = spill
+		w.spillSize = info.Size()
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write queues a line for sending. It doesn't block on the network and
+// doesn't fail if the connection is down.
+func (w *NetworkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	line := bytes.TrimRight(p, "\n")
+	if len(line) > w.options.MaxLineSize {
+		line = line[:w.options.MaxLineSize]
+	}
+	// The caller may reuse p, so the line is copied.
+	line = append(append(make([]byte, 0, len(line)+1), line...), '\n')
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closing {
+		return 0, io.ErrClosedPipe
+	}
+	switch {
+	case w.spillSize > w.spillRead:
+		if err := w.spillLine(line); err != nil {
+			return 0, err
+		}
+	case len(w.queue) < w.options.BufferSize:
+		w.queue = append(w.queue, line)
+	case w.spill != nil:
+		if err := w.spillLine(line); err != nil {
+			return 0, err
+		}
+	default:
+		// Without a spill file, the oldest line makes room.
+		copy(w.queue, w.queue[1:])
+		w.queue[len(w.queue)-1] = line
+		w.dropped++
+	}
+	w.cond.Signal()
+	return n, nil
+}
+
+// Close sends what is left, as long as the connection is up, and stops the
+// writer. Lines which can't be sent are kept in the spill file, if there is
+// one, and lost otherwise.
+func (w *NetworkWriter) Close() error {
+	w.mutex.Lock()
+	if w.closing {
+		w.mutex.Unlock()
+		<-w.done
+		return nil
+	}
+	w.closing = true
+	close(w.stop)
+	w.cond.Broadcast()
+	w.mutex.Unlock()
+	<-w.done
+
+	var err error
+	w.mutex.Lock()
+	if w.spill != nil {
+		err = w.saveToSpill()
+		if closeErr := w.spill.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	w.mutex.Unlock()
+	return err
+}
+
+// spillLine writes a line to the spill file, or drops it if the file would
+// grow beyond MaxSpillSize. The mutex has to be held.
+func (w *NetworkWriter) spillLine(line []byte) error {
+	if w.spillSize+int64(len(line)) > w.options.MaxSpillSize {
+		w.dropped++
+		return nil
+	}
+	return w.appendToSpill(line)
+}
+
+// appendToSpill writes a line to the spill file. The mutex has to be held.
+func (w *NetworkWriter) appendToSpill(line []byte) error {
+	if _, err := w.spill.Write(line); err != nil {
+		return err
+	}
+	w.spillSize += int64(len(line))
+	return nil
+}
+
+// saveToSpill rewrites the spill file with the lines which weren't sent yet,
+// the ones from the memory first, so that they are sent the next time the
+// file is used. The mutex has to be held.
+func (w *NetworkWriter) saveToSpill() error {
+	rest := make([]byte, w.spillSize-w.spillRead)
+	if _, err := w.spill.ReadAt(rest, w.spillRead); err != nil && err != io.EOF {
+		return err
+	}
+	if err := w.spill.Truncate(0); err != nil {
+		return err
+	}
+	w.spillRead, w.spillSize = 0, 0
+	for _, line := range w.queue {
+		if err := w.appendToSpill(line); err != nil {
+			return err
+		}
+	}
+	w.queue = nil
+	if len(rest) == 0 {
+		return nil
+	}
+	return w.appendToSpill(rest)
+}
+
+// refillFromSpill moves lines from the spill file into the empty queue. The
+// mutex has to be held.
+func (w *NetworkWriter) refillFromSpill() error {
+	size := w.spillSize - w.spillRead
+	if limit := int64(w.options.MaxLineSize+1) * 16; size > limit {
+		size = limit
+	}
+	buf := make([]byte, size)
+	n, err := w.spill.ReadAt(buf, w.spillRead)
+	if n == 0 && err != nil {
+		return err
+	}
+	buf = buf[:n]
+	for len(buf) > 0 && len(w.queue) < w.options.BufferSize {
+		end := bytes.IndexByte(buf, '\n')
+		if end < 0 {
+			if len(w.queue) == 0 {
+				// A line without line break, e.g. cut off by a crash.
+				end = len(buf) - 1
+			} else {
+				break
+			}
+		}
+		w.queue = append(w.queue, buf[:end+1])
+		w.spillRead += int64(end + 1)
+		buf = buf[end+1:]
+	}
+	if w.spillRead >= w.spillSize {
+		// Everything was read, so the file can start over.
+		w.spillRead, w.spillSize = 0, 0
+		return w.spill.Truncate(0)
+	}
+	return nil
+}
+
+// next waits for the oldest line which wasn't sent yet and takes it out of
+// the queue, so that Write doesn't drop it while it's sent. It returns nil
+// once the writer is closed.
+func (w *NetworkWriter) next() []byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for {
+		if len(w.queue) == 0 && w.spillSize > w.spillRead {
+			if err := w.refillFromSpill(); err != nil {
+				rlogIssue("Cannot read the spill file '%s': %s", w.options.SpillFile, err)
+				w.spillRead, w.spillSize = 0, 0
+			}
+		}
+		if len(w.queue) > 0 {
+			line := w.queue[0]
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			return line
+		}
+		if w.closing {
+			return nil
+		}
+		w.cond.Wait()
+	}
+}
+
+// putBack returns a line which couldn't be sent to the front of the queue,
+// so that Close keeps it in the spill file.
+func (w *NetworkWriter) putBack(line []byte) {
+	w.mutex.Lock()
+	w.queue = append([][]byte{line}, w.queue...)
+	w.mutex.Unlock()
+}
+
+// run sends the queued lines, (re-)connecting as needed.
+func (w *NetworkWriter) run() {
+	defer close(w.done)
+	defer w.disconnect(nil)
+	backoff := w.options.MinBackoff
+	for {
+		line := w.next()
+		if line == nil {
+			return
+		}
+		for {
+			conn := w.connection()
+			if conn == nil {
+				var err error
+				conn, err = w.connect()
+				if err != nil {
+					if !w.wait(backoff) {
+						w.putBack(line)
+						return
+					}
+					if backoff *= 2; backoff > w.options.MaxBackoff {
+						backoff = w.options.MaxBackoff
+					}
+					continue
+				}
+				backoff = w.options.MinBackoff
+			}
+			conn.SetWriteDeadline(time.Now().Add(networkWriteTimeout))
+			if _, err := conn.Write(line); err != nil {
+				if lineRejected(err) {
+					// Sending the line again would fail as well.
+					rlogIssue("A log line of %d bytes was dropped: %s", len(line), err)
+					break
+				}
+				w.disconnect(conn)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// wait sleeps for the backoff. It returns false if the writer was closed in
+// the meantime.
+func (w *NetworkWriter) wait(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+// connect dials the address and, for stream sockets, watches the connection,
+// so that a closed peer is noticed before the next line is lost on it.
+func (w *NetworkWriter) connect() (net.Conn, error) {
+	conn, err := net.DialTimeout(w.options.Network, w.options.Address, networkWriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w.connMutex.Lock()
+	w.conn = conn
+	w.connMutex.Unlock()
+
+	w.mutex.Lock()
+	if w.dropped > 0 {
+		rlogIssue("%d log lines were dropped while %s://%s was unreachable",
+			w.dropped, w.options.Network, w.options.Address)
+		w.dropped = 0
+	}
+	w.mutex.Unlock()
+
+	if !isDatagramNetwork(w.options.Network) {
+		go func() {
+			// Log agents don't talk back, so reading only returns once the
+			// connection is gone.
+			io.Copy(ioutil.Discard, conn)
+			w.disconnect(conn)
+		}()
+	}
+	return conn, nil
+}
+
+// connection returns the current connection, or nil if there is none.
+func (w *NetworkWriter) connection() net.Conn {
+	w.connMutex.Lock()
+	defer w.connMutex.Unlock()
+	return w.conn
+}
+
+// disconnect closes the given connection, if it is still the current one. A
+// nil connection closes any.
+func (w *NetworkWriter) disconnect(conn net.Conn) {
+	w.connMutex.Lock()
+	defer w.connMutex.Unlock()
+	if w.conn != nil && (conn == nil || conn == w.conn) {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// isDatagramNetwork tells whether each line is sent as a datagram of its own.
+func isDatagramNetwork(network string) bool {
+	return strings.HasPrefix(network, "udp") || network == "unixgram"
+}
+
+// lineRejected tells whether a write failed because of the line itself, e.g.
+// a datagram too large for the socket, rather than because of the connection.
+func lineRejected(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE
+}
@@ -0,0 +1,375 @@
+package rlog
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// lineListener is a TCP server that can be killed and restarted on the same
+// address, collecting the lines it receives.
+type lineListener struct {
+	address  string
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    []net.Conn
+	lines    chan string
+}
+
+func newLineListener() *lineListener {
+	l := &lineListener{address: "127.0.0.1:0", lines: make(chan string, 100)}
+	l.start()
+	l.address = l.listener.Addr().String()
+	return l
+}
+
+func (l *lineListener) start() {
+	listener, err := net.Listen("tcp", l.address)
+	Expect(err).ToNot(HaveOccurred())
+	l.mutex.Lock()
+	l.listener = listener
+	l.mutex.Unlock()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			l.mutex.Lock()
+			l.conns = append(l.conns, conn)
+			l.mutex.Unlock()
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					l.lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+}
+
+func (l *lineListener) kill() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.listener.Close()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+func (l *lineListener) next() string {
+	select {
+	case line := <-l.lines:
+		return line
+	case <-time.After(5 * time.Second):
+		Fail("no line received")
+	}
+	return ""
+}
+
+var _ = Describe("Network", func() {
+	var (
+		listener *lineListener
+		writer   *NetworkWriter
+		dir      string
+	)
+
+	BeforeEach(func() {
+		listener = newLineListener()
+		var err error
+		dir, err = ioutil.TempDir("", "rlog-network")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if writer != nil {
+			writer.Close()
+			writer = nil
+		}
+		listener.kill()
+		os.RemoveAll(dir)
+	})
+
+	newWriter := func(options NetworkOptions) {
+		options.Network = "tcp"
+		options.Address = listener.address
+		options.MinBackoff = 5 * time.Millisecond
+		options.MaxBackoff = 20 * time.Millisecond
+		var err error
+		writer, err = NewNetworkWriter(options)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// queued returns the number of lines in the queue of the writer.
+	queued := func() int {
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+		return len(writer.queue)
+	}
+
+	// dropped returns the number of lines the writer dropped.
+	dropped := func() int {
+		writer.mutex.Lock()
+		defer writer.mutex.Unlock()
+		return writer.dropped
+	}
+
+	// killListener stops the listener and waits until the writer noticed.
+	killListener := func() {
+		listener.kill()
+		Eventually(writer.connection).Should(BeNil())
+	}
+
+	It("should send the lines", func() {
+		newWriter(NetworkOptions{})
+		writer.Write([]byte("line 1\n"))
+		writer.Write([]byte("line 2\n"))
+		Expect(listener.next()).To(Equal("line 1"))
+		Expect(listener.next()).To(Equal("line 2"))
+	})
+
+	It("should reconnect and send the buffered lines", func() {
+		newWriter(NetworkOptions{})
+		writer.Write([]byte("before\n"))
+		Expect(listener.next()).To(Equal("before"))
+
+		killListener()
+		writer.Write([]byte("during 1\n"))
+		writer.Write([]byte("during 2\n"))
+		listener.start()
+		writer.Write([]byte("after\n"))
+
+		Expect(listener.next()).To(Equal("during 1"))
+		Expect(listener.next()).To(Equal("during 2"))
+		Expect(listener.next()).To(Equal("after"))
+	})
+
+	It("should drop the oldest lines when the buffer is full", func() {
+		listener.kill()
+		newWriter(NetworkOptions{BufferSize: 2})
+		writer.Write([]byte("1\n"))
+		// The line being sent isn't dropped.
+		Eventually(queued).Should(BeZero())
+		for _, line := range []string{"2", "3", "4"} {
+			writer.Write([]byte(line + "\n"))
+		}
+		Expect(dropped()).To(Equal(1))
+		listener.start()
+		Expect(listener.next()).To(Equal("1"))
+		Expect(listener.next()).To(Equal("3"))
+		Expect(listener.next()).To(Equal("4"))
+	})
+
+	It("should not drop the line being sent when the buffer is full", func() {
+		server, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		writer, err = NewNetworkWriter(NetworkOptions{
+			Network:     "tcp",
+			Address:     server.Addr().String(),
+			BufferSize:  2,
+			MaxLineSize: 16 * 1024 * 1024,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		// The line is larger than the socket buffers, so sending it blocks
+		// until the server reads.
+		writer.Write(bytes.Repeat([]byte("x"), 16*1024*1024))
+		conn, err := server.Accept()
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		_, err = reader.Peek(1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(queued()).To(BeZero())
+		for _, line := range []string{"1", "2", "3"} {
+			writer.Write([]byte(line + "\n"))
+		}
+
+		var lines []string
+		for len(lines) < 3 {
+			line, err := reader.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+		Expect(lines[0]).To(HaveLen(16 * 1024 * 1024))
+		Expect(lines[1:]).To(Equal([]string{"2", "3"}))
+		Expect(dropped()).To(Equal(1))
+	})
+
+	It("should spill the lines that don't fit into the buffer to disk", func() {
+		listener.kill()
+		newWriter(NetworkOptions{BufferSize: 2, SpillFile: filepath.Join(dir, "spill")})
+		for _, line := range []string{"1", "2", "3", "4", "5"} {
+			writer.Write([]byte(line + "\n"))
+		}
+		listener.start()
+		for _, line := range []string{"1", "2", "3", "4", "5"} {
+			Expect(listener.next()).To(Equal(line))
+		}
+		writer.Write([]byte("6\n"))
+		Expect(listener.next()).To(Equal("6"))
+	})
+
+	It("should drop the lines that don't fit into the spill file", func() {
+		listener.kill()
+		newWriter(NetworkOptions{BufferSize: 1, SpillFile: filepath.Join(dir, "spill"), MaxSpillSize: 4})
+		writer.Write([]byte("1\n"))
+		Eventually(queued).Should(BeZero())
+		for _, line := range []string{"2", "3", "4", "5"} {
+			writer.Write([]byte(line + "\n"))
+		}
+		Expect(dropped()).To(Equal(1))
+		info, err := os.Stat(filepath.Join(dir, "spill"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(4)))
+
+		listener.start()
+		for _, line := range []string{"1", "2", "3", "4"} {
+			Expect(listener.next()).To(Equal(line))
+		}
+	})
+
+	It("should keep unsent lines in the spill file for the next run", func() {
+		spill := filepath.Join(dir, "spill")
+		listener.kill()
+		newWriter(NetworkOptions{BufferSize: 1, SpillFile: spill})
+		writer.Write([]byte("1\n"))
+		writer.Write([]byte("2\n"))
+		Expect(writer.Close()).To(Succeed())
+		content, err := ioutil.ReadFile(spill)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("1\n2\n"))
+
+		listener.start()
+		newWriter(NetworkOptions{SpillFile: spill})
+		Expect(listener.next()).To(Equal("1"))
+		Expect(listener.next()).To(Equal("2"))
+	})
+
+	It("should truncate long lines", func() {
+		newWriter(NetworkOptions{MaxLineSize: 5})
+		writer.Write([]byte("0123456789\n"))
+		writer.Write([]byte("short\n"))
+		Expect(listener.next()).To(Equal("01234"))
+		Expect(listener.next()).To(Equal("short"))
+	})
+
+	It("should send the lines over UDP", func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		writer, err = NewNetworkWriter(NetworkOptions{Network: "udp", Address: conn.LocalAddr().String()})
+		Expect(err).ToNot(HaveOccurred())
+		writer.Write([]byte("datagram\n"))
+		buf := make([]byte, 100)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf[:n])).To(Equal("datagram\n"))
+	})
+
+	It("should fit the lines into UDP datagrams", func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		writer, err = NewNetworkWriter(NetworkOptions{Network: "udp", Address: conn.LocalAddr().String()})
+		Expect(err).ToNot(HaveOccurred())
+		writer.Write(bytes.Repeat([]byte("x"), 70000))
+		buf := make([]byte, 70000)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(65507))
+	})
+
+	It("should drop the lines the socket refuses", func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		writer, err = NewNetworkWriter(NetworkOptions{
+			Network:     "udp",
+			Address:     conn.LocalAddr().String(),
+			MaxLineSize: 70000,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		writer.Write(bytes.Repeat([]byte("x"), 70000))
+		writer.Write([]byte("datagram\n"))
+		buf := make([]byte, 100)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf[:n])).To(Equal("datagram\n"))
+	})
+
+	It("should log to the URL given as log stream", func() {
+		l, err := NewLogger(Config{
+			LogStream: "tcp://" + listener.address,
+			LogLevel:  "INFO",
+			LogNoTime: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		writer = l.logWriterStream.(*NetworkWriter)
+		l.Info("hello network")
+		Expect(listener.next()).To(HaveSuffix("hello network"))
+	})
+
+	It("should stop the writer if the logger can't be created", func() {
+		before := runtime.NumGoroutine()
+		for i := 0; i < 10; i++ {
+			_, err := NewLogger(Config{
+				LogStream:       "tcp://" + listener.address,
+				StacktraceLevel: "LOUD",
+			})
+			Expect(err).To(HaveOccurred())
+		}
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+	})
+
+	Describe("ParseNetworkURL", func() {
+		It("should parse a TCP URL with parameters", func() {
+			options, err := ParseNetworkURL("tcp://localhost:5170?buffer=10&max_line=100&spill=/tmp/spill&max_spill=4096")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.Network).To(Equal("tcp"))
+			Expect(options.Address).To(Equal("localhost:5170"))
+			Expect(options.BufferSize).To(Equal(10))
+			Expect(options.MaxLineSize).To(Equal(100))
+			Expect(options.SpillFile).To(Equal("/tmp/spill"))
+			Expect(options.MaxSpillSize).To(Equal(int64(4096)))
+		})
+
+		It("should parse a unix URL", func() {
+			options, err := ParseNetworkURL("unix:///run/Agent.sock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(options.Network).To(Equal("unix"))
+			Expect(options.Address).To(Equal("/run/Agent.sock"))
+		})
+
+		It("should fail with an unknown network", func() {
+			_, err := ParseNetworkURL("http://localhost")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail with an unknown parameter", func() {
+			_, err := ParseNetworkURL("udp://localhost:5170?size=10")
+			Expect(err).To(HaveOccurred())
+			Expect(strings.Contains(err.Error(), "size")).To(BeTrue())
+		})
+
+		It("should fail with a malformed parameter", func() {
+			_, err := ParseNetworkURL("udp://localhost:5170?buffer=many")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
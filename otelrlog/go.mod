@@ -0,0 +1,12 @@
+module github.com/lab259/rlog/v2/otelrlog
+
+go 1.16
+
+require (
+	github.com/onsi/ginkgo v1.8.0
+	github.com/onsi/gomega v1.5.0
+	go.opentelemetry.io/otel/trace v1.4.1
+	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3 // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
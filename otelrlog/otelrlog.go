@@ -0,0 +1,24 @@
+// Package otelrlog connects rlog to OpenTelemetry tracing, so that the entries
+// of a logger created with WithContext carry the trace_id and span_id of the
+// active span:
+//
+//	rlog.SetSpanContextFunc(otelrlog.SpanContext)
+//	...
+//	rlog.WithContext(ctx).Info("handled request")
+package otelrlog
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanContext returns the trace and span ID of the OpenTelemetry span active
+// in ctx. It is meant to be passed to rlog.SetSpanContextFunc.
+func SpanContext(ctx context.Context) (string, string, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+	return sc.TraceID().String(), sc.SpanID().String(), true
+}
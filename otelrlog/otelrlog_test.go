@@ -0,0 +1,34 @@
+package otelrlog
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOtelRLog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OpenTelemetry Test Suite")
+}
+
+var _ = Describe("SpanContext", func() {
+	It("should return the IDs of the active span", func() {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+		traceID, spanID, ok := SpanContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(traceID).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(spanID).To(Equal("00f067aa0ba902b7"))
+	})
+
+	It("should report that there is no span", func() {
+		_, _, ok := SpanContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
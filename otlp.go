@@ -0,0 +1,198 @@
+package rlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of the OTLPOptions.
+const (
+	defaultOTLPEndpoint      = "http://localhost:4318/v1/logs"
+	defaultOTLPBatchSize     = 512
+	defaultOTLPFlushInterval = time.Second
+	defaultOTLPTimeout       = 10 * time.Second
+)
+
+// OTLPOptions configures an OTLPExporter.
+type OTLPOptions struct {
+	// Endpoint is the URL of the OTLP/HTTP logs endpoint of the collector.
+	// By default, it's http://localhost:4318/v1/logs.
+	Endpoint string
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string
+	// Resource holds resource attributes, in addition to the defaults of
+	// NewOTLPFormatter.
+	Resource FieldsArr
+	// BatchSize is the number of records after which a batch is sent. By
+	// default, it's 512.
+	BatchSize int
+	// FlushInterval is the longest time a record waits for its batch to be
+	// sent. By default, it's a second.
+	FlushInterval time.Duration
+	// Client sends the requests. By default, it's a client with a timeout of
+	// 10 seconds.
+	Client *http.Client
+}
+
+// OTLPExporter sends entries as OpenTelemetry log records to a collector,
+// using OTLP/HTTP with JSON encoding. The records are sent in batches in the
+// background. Batches which the collector doesn't accept are dropped, the
+// following ones are still sent. It
+// implements EntryWriter, so it can be used with SetOutput.
+type OTLPExporter struct {
+	mutex     sync.Mutex
+	options   OTLPOptions
+	formatter *OTLPFormatter
+	records   [][]byte
+	flushing  sync.Mutex
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewOTLPExporter starts an exporter for the endpoint in the options.
+func NewOTLPExporter(options OTLPOptions) *OTLPExporter {
+	if options.Endpoint == "" {
+		options.Endpoint = defaultOTLPEndpoint
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultOTLPBatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaultOTLPFlushInterval
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: defaultOTLPTimeout}
+	}
+	e := &OTLPExporter{
+		options:   options,
+		formatter: NewOTLPFormatter(options.Resource),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// WriteEntry adds the entry to the current batch.
+func (e *OTLPExporter) WriteEntry(entry *Entry) error {
+	record := e.formatter.appendRecord(nil, entry, time.Now())
+
+	e.mutex.Lock()
+	e.records = append(e.records, record)
+	full := len(e.records) >= e.options.BatchSize
+	e.mutex.Unlock()
+
+	if full {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Write adds an already formatted line, as INFO record, to the current batch.
+// It allows the exporter to be used wherever an io.Writer is expected.
+func (e *OTLPExporter) Write(p []byte) (int, error) {
+	err := e.WriteEntry(&Entry{
+		Level:   levelInfo,
+		Message: string(bytes.TrimRight(p, "\n")),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends the current batch and waits for the collector to answer. It
+// returns the first error, after trying to send all the records.
+func (e *OTLPExporter) Flush() error {
+	e.flushing.Lock()
+	defer e.flushing.Unlock()
+
+	e.mutex.Lock()
+	records := e.records
+	e.records = nil
+	e.mutex.Unlock()
+
+	var firstErr error
+	for len(records) > 0 {
+		n := len(records)
+		if n > e.options.BatchSize {
+			n = e.options.BatchSize
+		}
+		if err := e.send(records[:n]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		records = records[n:]
+	}
+	return firstErr
+}
+
+// Close sends the current batch and stops the exporter.
+func (e *OTLPExporter) Close() error {
+	select {
+	case <-e.stop:
+	default:
+		close(e.stop)
+	}
+	<-e.done
+	return e.Flush()
+}
+
+// run sends the batches when they are full or the flush interval passed.
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.wake:
+		case <-e.stop:
+			return
+		}
+		if err := e.Flush(); err != nil {
+			rlogIssue("Cannot export log records to '%s': %s", e.options.Endpoint, err)
+		}
+	}
+}
+
+// send posts the records as one ExportLogsServiceRequest.
+func (e *OTLPExporter) send(records [][]byte) error {
+	body := e.formatter.appendRequestStart(nil)
+	for i, record := range records {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, record...)
+	}
+	body = append(body, otlpRequestEnd...)
+
+	req, err := http.NewRequest(http.MethodPost, e.options.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.options.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.options.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body, so that the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package rlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OTLPExporter", func() {
+	var (
+		server   *httptest.Server
+		mutex    sync.Mutex
+		requests []otlpRequest
+		headers  []http.Header
+		status   int
+		refused  int
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		headers = nil
+		status = http.StatusOK
+		refused = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/v1/logs"))
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			var request otlpRequest
+			Expect(json.Unmarshal(body, &request)).To(Succeed())
+			mutex.Lock()
+			requests = append(requests, request)
+			headers = append(headers, r.Header)
+			if refused > 0 {
+				refused--
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				w.WriteHeader(status)
+			}
+			mutex.Unlock()
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	received := func() []otlpRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]otlpRequest(nil), requests...)
+	}
+
+	records := func(request otlpRequest) []otlpRecord {
+		return request.ResourceLogs[0].ScopeLogs[0].LogRecords
+	}
+
+	It("should send the batch on flush", func() {
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			Headers:       map[string]string{"Authorization": "Bearer token"},
+			Resource:      FieldsArr{"service.name", "api"},
+			FlushInterval: time.Hour,
+		})
+		defer exporter.Close()
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "first"})
+		exporter.WriteEntry(&Entry{Level: levelErr, Message: "second"})
+		Expect(exporter.Flush()).To(Succeed())
+
+		Expect(received()).To(HaveLen(1))
+		request := received()[0]
+		Expect(headers[0].Get("Content-Type")).To(Equal("application/json"))
+		Expect(headers[0].Get("Authorization")).To(Equal("Bearer token"))
+		Expect(otlpAttributes(request.ResourceLogs[0].Resource.Attributes)).To(HaveKeyWithValue("service.name", map[string]interface{}{"stringValue": "api"}))
+		Expect(records(request)).To(HaveLen(2))
+		Expect(records(request)[0].Body).To(Equal(map[string]interface{}{"stringValue": "first"}))
+		Expect(records(request)[1].SeverityNumber).To(Equal(17))
+	})
+
+	It("should send full batches right away", func() {
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+		})
+		defer exporter.Close()
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "first"})
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "second"})
+		Eventually(received).Should(HaveLen(1))
+		Expect(records(received()[0])).To(HaveLen(2))
+	})
+
+	It("should send the batch after the flush interval", func() {
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			FlushInterval: 10 * time.Millisecond,
+		})
+		defer exporter.Close()
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "hello"})
+		Eventually(received).Should(HaveLen(1))
+	})
+
+	It("should send what is left on close", func() {
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			FlushInterval: time.Hour,
+		})
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "last words"})
+		Expect(exporter.Close()).To(Succeed())
+		Expect(received()).To(HaveLen(1))
+	})
+
+	It("should report the collector refusing the batch", func() {
+		status = http.StatusBadRequest
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			FlushInterval: time.Hour,
+		})
+		defer exporter.Close()
+		exporter.WriteEntry(&Entry{Level: levelInfo, Message: "hello"})
+		Expect(exporter.Flush()).To(MatchError(ContainSubstring("400")))
+	})
+
+	It("should send the batches following a refused one", func() {
+		refused = 1
+		exporter := NewOTLPExporter(OTLPOptions{
+			Endpoint:      server.URL + "/v1/logs",
+			FlushInterval: time.Hour,
+		})
+		defer exporter.Close()
+		for _, msg := range []string{"first", "second", "third"} {
+			exporter.WriteEntry(&Entry{Level: levelInfo, Message: msg})
+		}
+		// One record per batch.
+		exporter.options.BatchSize = 1
+		Expect(exporter.Flush()).To(MatchError(ContainSubstring("503")))
+		result := received()
+		Expect(result).To(HaveLen(3))
+		Expect(records(result[2])[0].Body).To(HaveKeyWithValue("stringValue", "third"))
+	})
+
+	It("should export through the otlp log stream", func() {
+		SetSpanContextFunc(fakeSpanContext)
+		defer SetSpanContextFunc(nil)
+
+		l, err := NewLogger(Config{
+			LogStream:    "OTLP",
+			OTLPEndpoint: server.URL + "/v1/logs",
+			OTLPResource: "service.name=checkout",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		exporter := l.logWriterStream.(*OTLPExporter)
+		defer exporter.Close()
+
+		l.WithContext(spanCtx).WithField("order", 7).Warn("payment retried")
+		Expect(exporter.Flush()).To(Succeed())
+
+		Expect(received()).To(HaveLen(1))
+		request := received()[0]
+		Expect(otlpAttributes(request.ResourceLogs[0].Resource.Attributes)).To(HaveKeyWithValue("service.name", map[string]interface{}{"stringValue": "checkout"}))
+		record := records(request)[0]
+		Expect(record.Body).To(Equal(map[string]interface{}{"stringValue": "payment retried"}))
+		Expect(record.SeverityText).To(Equal("WARN"))
+		Expect(record.TraceID).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(record.SpanID).To(Equal("00f067aa0ba902b7"))
+		Expect(otlpAttributes(record.Attributes)).To(HaveKeyWithValue("order", map[string]interface{}{"intValue": "7"}))
+	})
+})
@@ -0,0 +1,75 @@
+package rlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enabled tells whether l may log messages of the given level, and for
+// LevelTrace of the given trace level. The per-file filters are only applied
+// when logging, so a message may still be dropped. Adapters use it to skip
+// work for levels which are off.
+func Enabled(l Logger, level Level, traceLevel int) bool {
+	if level != levelTrace {
+		traceLevel = notATrace
+	}
+	if checker, ok := l.(levelChecker); ok {
+		return checker.mayLog(level, traceLevel)
+	}
+	return true
+}
+
+// PrintfLogger adapts a Logger to the Print, Printf and Println methods many
+// libraries expect of a logger. Everything is logged at one level. The caller
+// info refers to the code calling the PrintfLogger.
+type PrintfLogger struct {
+	logger     Logger
+	level      Level
+	traceLevel int
+}
+
+// NewPrintfLogger creates a PrintfLogger logging to l at the given level
+// (trace level 1 for LevelTrace).
+func NewPrintfLogger(l Logger, level Level) *PrintfLogger {
+	traceLevel := notATrace
+	if level == levelTrace {
+		traceLevel = 1
+	}
+	return &PrintfLogger{logger: l, level: level, traceLevel: traceLevel}
+}
+
+// V returns a PrintfLogger logging to the same Logger at trace level n, so
+// that the verbosity of libraries using V levels is controlled by
+// RLOG_TRACE_LEVEL.
+func (p *PrintfLogger) V(n int) *PrintfLogger {
+	return &PrintfLogger{logger: p.logger, level: levelTrace, traceLevel: n}
+}
+
+// Enabled tells whether the messages of this PrintfLogger may be logged.
+func (p *PrintfLogger) Enabled() bool {
+	return Enabled(p.logger, p.level, p.traceLevel)
+}
+
+// Print logs the arguments in the manner of fmt.Print.
+func (p *PrintfLogger) Print(a ...interface{}) {
+	p.log("", a...)
+}
+
+// Printf logs the arguments in the manner of fmt.Printf.
+func (p *PrintfLogger) Printf(format string, a ...interface{}) {
+	p.log(format, a...)
+}
+
+// Println logs the arguments in the manner of fmt.Println.
+func (p *PrintfLogger) Println(a ...interface{}) {
+	p.log("", strings.TrimSuffix(fmt.Sprintln(a...), "\n"))
+}
+
+func (p *PrintfLogger) log(format string, a ...interface{}) {
+	if cl, ok := p.logger.(callerLogger); ok {
+		// Skip this function and the Print method.
+		cl.log(2, p.level, p.traceLevel, "", nil, format, a...)
+	} else {
+		p.logger.BasicLog(p.level, p.traceLevel, "", nil, format, a...)
+	}
+}
@@ -0,0 +1,69 @@
+package rlog
+
+import (
+	"bytes"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrintfLogger", func() {
+	var buff *bytes.Buffer
+
+	newLogger := func(config Config) *logger {
+		config.Formatter = "json"
+		var l *logger
+		l, buff = newTestLogger(config)
+		return l
+	}
+
+	// entries decodes the JSON lines written so far.
+	entries := func() []map[string]interface{} {
+		return decodeEntries(buff)
+	}
+
+	It("should log at the given level", func() {
+		p := NewPrintfLogger(newLogger(Config{}), LevelWarn)
+		p.Printf("retry %d", 3)
+		p.Print("a", "b")
+		p.Println("a", "b")
+		result := entries()
+		Expect(result).To(HaveLen(3))
+		Expect(result[0]).To(HaveKeyWithValue("level", "WARN"))
+		Expect(result[0]).To(HaveKeyWithValue("msg", "retry 3"))
+		Expect(result[1]).To(HaveKeyWithValue("msg", "ab"))
+		Expect(result[2]).To(HaveKeyWithValue("msg", "a b"))
+	})
+
+	It("should map V levels onto trace levels", func() {
+		p := NewPrintfLogger(newLogger(Config{TraceLevel: "2"}), LevelInfo)
+		Expect(p.V(2).Enabled()).To(BeTrue())
+		Expect(p.V(3).Enabled()).To(BeFalse())
+		p.V(2).Printf("level %d", 2)
+		p.V(3).Printf("level %d", 3)
+		Expect(entries()).To(HaveLen(1))
+		Expect(entries()[0]).To(HaveKeyWithValue("level", "TRACE"))
+		Expect(entries()[0]).To(HaveKeyWithValue("trace_level", 2.0))
+	})
+
+	It("should report the code calling it", func() {
+		p := NewPrintfLogger(newLogger(Config{ShowCallerInfo: true}).WithField("lib", "cron"), LevelInfo)
+		_, file, line, _ := runtime.Caller(0)
+		p.Printf("tick")
+		caller := entries()[0]["caller"].(map[string]interface{})
+		Expect(caller).To(HaveKeyWithValue("path", file))
+		Expect(caller).To(HaveKeyWithValue("line", float64(line+1)))
+	})
+
+	Describe("Enabled", func() {
+		It("should check the log and trace levels", func() {
+			l := newLogger(Config{LogLevel: "WARN", TraceLevel: "1"})
+			Expect(Enabled(l, LevelError, 0)).To(BeTrue())
+			Expect(Enabled(l, LevelInfo, 0)).To(BeFalse())
+			Expect(Enabled(l.WithField("a", 1), LevelInfo, 0)).To(BeFalse())
+			Expect(Enabled(l, LevelTrace, 1)).To(BeTrue())
+			Expect(Enabled(l, LevelTrace, 2)).To(BeFalse())
+		})
+	})
+})
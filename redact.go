@@ -0,0 +1,308 @@
+package rlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RedactStrategy tells what happens to redacted values.
+type RedactStrategy int
+
+const (
+	// RedactMask replaces the value by RedactedValue.
+	RedactMask RedactStrategy = iota
+	// RedactHash replaces the value by a hash of it, so that equal values
+	// can still be correlated.
+	RedactHash
+	// RedactDrop removes the field, or the matched text.
+	RedactDrop
+)
+
+// RedactedValue replaces masked values.
+const RedactedValue = "[REDACTED]"
+
+// Predefined patterns for RedactRule.Pattern, also available as email, jwt and
+// credit_card in RLOG_REDACT_PATTERNS.
+var (
+	EmailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	JWTPattern        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	CreditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// redactPatterns are the predefined rules by their name in
+// RLOG_REDACT_PATTERNS.
+var redactPatterns = map[string]RedactRule{
+	"email":       {Pattern: EmailPattern},
+	"jwt":         {Pattern: JWTPattern},
+	"credit_card": {Pattern: CreditCardPattern, Validate: ValidCreditCard},
+}
+
+// RedactRule selects what is redacted:
+//
+//   - With Keys only, the values of the fields with these keys.
+//   - With Pattern only, the matches in the message and in the values of all
+//     fields, which are turned into strings for that.
+//   - With both, the matches in the values of the fields with these keys.
+type RedactRule struct {
+	// Keys are glob patterns, as for path.Match, of field keys. They are
+	// matched case-insensitively.
+	Keys []string
+	// Pattern matches the text to redact.
+	Pattern *regexp.Regexp
+	// Validate, if set, confirms the matches of the pattern, to rule out
+	// false positives.
+	Validate func(match string) bool
+	// Strategy is what happens to the redacted values.
+	Strategy RedactStrategy
+}
+
+// Redactor removes sensitive data from the entries before they are formatted.
+// It is set with SetRedactor, or through the RLOG_REDACT_* settings.
+type Redactor struct {
+	rules []RedactRule
+}
+
+// NewRedactor creates a Redactor applying the rules in order.
+func NewRedactor(rules ...RedactRule) (*Redactor, error) {
+	r := &Redactor{}
+	for _, rule := range rules {
+		if len(rule.Keys) == 0 && rule.Pattern == nil {
+			return nil, fmt.Errorf("redact rule has neither keys nor a pattern")
+		}
+		keys := make([]string, len(rule.Keys))
+		for i, key := range rule.Keys {
+			keys[i] = strings.ToLower(key)
+			if _, err := path.Match(keys[i], ""); err != nil {
+				return nil, fmt.Errorf("redact key pattern '%s' is malformed", key)
+			}
+		}
+		rule.Keys = keys
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// redactorFromConfig creates the Redactor of the RLOG_REDACT_* settings, or
+// nil if there are no rules.
+func redactorFromConfig(config Config) (*Redactor, error) {
+	strategy := RedactMask
+	switch strings.ToLower(strings.TrimSpace(config.RedactStrategy)) {
+	case "", "mask":
+	case "hash":
+		strategy = RedactHash
+	case "drop":
+		strategy = RedactDrop
+	default:
+		return nil, fmt.Errorf("redact strategy '%s' is unknown", config.RedactStrategy)
+	}
+
+	var rules []RedactRule
+	var keys []string
+	for _, key := range strings.Split(config.RedactKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		rules = append(rules, RedactRule{Keys: keys, Strategy: strategy})
+	}
+	for _, name := range strings.Split(config.RedactPatterns, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		rule, ok := redactPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("redact pattern '%s' is unknown", name)
+		}
+		rule.Strategy = strategy
+		rules = append(rules, rule)
+	}
+	if config.RedactRegex != "" {
+		pattern, err := regexp.Compile(config.RedactRegex)
+		if err != nil {
+			return nil, fmt.Errorf("redact regex '%s' is malformed: %s", config.RedactRegex, err)
+		}
+		rules = append(rules, RedactRule{Pattern: pattern, Strategy: strategy})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return NewRedactor(rules...)
+}
+
+// RedactString applies the rules without keys to s.
+func (r *Redactor) RedactString(s string) string {
+	for i := range r.rules {
+		if len(r.rules[i].Keys) == 0 {
+			s = r.rules[i].replace(s)
+		}
+	}
+	return s
+}
+
+// redact applies the rules to the message and the fields of the entry. The
+// fields are shared with the sub-loggers, so they are copied before a change.
+// The rendered fields of the entry are renewed with the formatter.
+func (r *Redactor) redact(entry *Entry, formatter LogFormatter) {
+	entry.Message = r.RedactString(entry.Message)
+
+	if fields, changed := r.redactFields(entry.Fields); changed {
+		entry.Fields = fields
+		entry.FieldsCache = formatter.FormatFields(fields)
+	}
+}
+
+// redactFields applies the rules to the fields, returning a copy if any of
+// them changed.
+func (r *Redactor) redactFields(fields FieldsArr) (FieldsArr, bool) {
+	var result FieldsArr
+	for i := 0; i+1 < len(fields); i += 2 {
+		value, keep, changed := r.redactField(fields[i], fields[i+1])
+		if changed && result == nil {
+			result = make(FieldsArr, i, len(fields))
+			copy(result, fields[:i])
+		}
+		if result != nil && keep {
+			result = append(result, fields[i], value)
+		}
+	}
+	if result == nil {
+		return fields, false
+	}
+	return result, true
+}
+
+// redactField applies the rules to a field. The fields of groups are
+// redacted by their own keys; the patterns only apply to the leaf values.
+func (r *Redactor) redactField(k interface{}, value interface{}) (result interface{}, keep bool, changed bool) {
+	key, ok := k.(string)
+	if !ok {
+		key = fmt.Sprint(k)
+	}
+	key = strings.ToLower(key)
+
+	if nested, ok := nestedFields(value); ok {
+		for i := range r.rules {
+			rule := &r.rules[i]
+			if rule.Pattern != nil || len(rule.Keys) == 0 || !rule.matchesKey(key) {
+				continue
+			}
+			switch rule.Strategy {
+			case RedactDrop:
+				return nil, false, true
+			case RedactHash:
+				return redactHash(fmt.Sprint(value)), true, true
+			}
+			return RedactedValue, true, true
+		}
+		if fields, changed := r.redactFields(nested); changed {
+			return fields, true, true
+		}
+		return value, true, false
+	}
+
+	var s string
+	stringified := false
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if len(rule.Keys) > 0 && !rule.matchesKey(key) {
+			continue
+		}
+		if rule.Pattern == nil {
+			switch rule.Strategy {
+			case RedactDrop:
+				return nil, false, true
+			case RedactHash:
+				return redactHash(fmt.Sprint(value)), true, true
+			}
+			return RedactedValue, true, true
+		}
+		if !stringified {
+			if str, ok := value.(string); ok {
+				s = str
+			} else {
+				s = fmt.Sprint(value)
+			}
+			stringified = true
+		}
+		if redacted := rule.replace(s); redacted != s {
+			s = redacted
+			value = s
+			changed = true
+		}
+	}
+	return value, true, changed
+}
+
+func (rule *RedactRule) matchesKey(key string) bool {
+	for _, pattern := range rule.Keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// replace redacts the matches of the pattern in s.
+func (rule *RedactRule) replace(s string) string {
+	return rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+		if rule.Validate != nil && !rule.Validate(match) {
+			return match
+		}
+		switch rule.Strategy {
+		case RedactDrop:
+			return ""
+		case RedactHash:
+			return redactHash(match)
+		}
+		return RedactedValue
+	})
+}
+
+// redactHash returns a short SHA-256 of s.
+func redactHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// ValidCreditCard checks the Luhn checksum of a card number, which may contain
+// spaces and dashes. It's the Validate of the credit_card pattern.
+func ValidCreditCard(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// SetRedactor sets the Redactor applied to the entries of the logger, or
+// removes it if r is nil.
+func (l *logger) SetRedactor(r *Redactor) {
+	l.redactor = r
+}
+
+// SetRedactor sets the Redactor applied to the entries of the default logger,
+// or removes it if r is nil.
+func SetRedactor(r *Redactor) {
+	DefaultLogger.SetRedactor(r)
+}
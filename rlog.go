@@ -16,17 +16,15 @@
 package rlog
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -217,19 +215,22 @@ func (spec *filterSpec) fromString(s string, isTraceLevels bool, globalLevelDefa
 // matchfilters checks if given filename and trace level are accepted
 // by any of the filters
 func (spec *filterSpec) matchfilters(filename string, level int) bool {
-	// If there are no filters then we don't match anything.
-	if len(spec.filters) == 0 {
-		return false
+	if filterLevel, matched := spec.levelFor(filename); matched {
+		return level <= int(filterLevel)
 	}
+	return false
+}
 
-	// If at least one filter matches.
+// levelFor returns the level of the first filter matching the given filename.
+// The second return value is false if no filter matched at all, in which case
+// nothing should be logged.
+func (spec *filterSpec) levelFor(filename string) (Level, bool) {
 	for _, filter := range spec.filters {
-		if matched, loggit := filter.match(filename, level); matched {
-			return loggit
+		if filter.matchesFile(filename) {
+			return filter.Level, true
 		}
 	}
-
-	return false
+	return 0, false
 }
 
 // match checks if given filename and level are matched by
@@ -237,19 +238,23 @@ func (spec *filterSpec) matchfilters(filename string, level int) bool {
 // made, and the second to indicate whether the message should be logged
 // (matched the level).
 func (f filter) match(filename string, level int) (bool, bool) {
-	var match bool
-	if f.Pattern != "" {
-		match, _ = filepath.Match(f.Pattern, filepath.Base(filename))
-	} else {
-		match = true
-	}
-	if match {
+	if f.matchesFile(filename) {
 		return true, level <= int(f.Level)
 	}
 
 	return false, false
 }
 
+// matchesFile checks if the pattern of this filter matches the given
+// filename. A filter without pattern matches every file.
+func (f filter) matchesFile(filename string) bool {
+	if f.Pattern == "" {
+		return true
+	}
+	match, _ := filepath.Match(f.Pattern, filepath.Base(filename))
+	return match
+}
+
 // updateIfNeeded returns a new value for an existing config item. The priority
 // flag indicates whether the new value should always override the old value.
 // Otherwise, the new value will not be used in case the old value is already
@@ -272,61 +277,64 @@ func init() {
 	}
 }
 
-// getTimeFormat returns the time format we should use for time stamps in log
-// lines, or nothing if "no time logging" has been requested.
-func getTimeFormat(config Config) string {
-	format := ""
-	if !config.LogNoTime {
-		// Store the format string for date/time logging. Allowed values are
-		// all the constants specified in
-		// https://golang.org/src/time/format.go.
-		switch strings.ToUpper(config.logTimeFormat) {
-		case "ANSIC":
-			format = time.ANSIC
-		case "UNIXDATE":
-			format = time.UnixDate
-		case "RUBYDATE":
-			format = time.RubyDate
-		case "RFC822":
-			format = time.RFC822
-		case "RFC822Z":
-			format = time.RFC822Z
-		case "RFC1123":
-			format = time.RFC1123
-		case "RFC1123Z":
-			format = time.RFC1123Z
-		case "RFC3339":
-			format = time.RFC3339
-		case "RFC3339NANO":
-			format = time.RFC3339Nano
-		case "KITCHEN":
-			format = time.Kitchen
-		default:
-			if config.logTimeFormat != "" {
-				format = config.logTimeFormat
-			} else {
-				format = time.RFC3339
-			}
-		}
-	}
-	return format
-}
-
 // SetOutput re-wires the log output to a new io.Writer. By default rlog
 // logs to os.Stderr, but this function can be used to direct the output
 // somewhere else. If output to two destinations was specified via environment
-// variables then this will change it back to just one output.
+// variables then this will change it back to just one output. In the "auto"
+// color mode, the default formatter only colors the new output if it's a
+// terminal.
 func (l *logger) SetOutput(writer io.Writer) {
 	// Use the stored date/time flag settings
 	l.logWriterStream = writer
 	// l.logWriterStream = log.New(writer, "", 0)
 	l.logWriterFile = nil
+	// Whether the output is colored depends on the writer.
+	if formatter, ok := l.formatter.(*defaultFormatter); ok {
+		file, _ := writer.(*os.File)
+		l.formatter = formatter.withColors(file, l.settingColorMode, l.settingColorScheme)
+	}
 	if l.currentLogFile != nil {
 		l.currentLogFile.Close()
 		l.currentLogFileName = ""
 	}
 }
 
+// SetLogLevel replaces the log level filters of the logger. The spec has the
+// same format as the RLOG_LOG_LEVEL setting.
+func (l *logger) SetLogLevel(spec string) {
+	newLogFilterSpec := new(filterSpec)
+	newLogFilterSpec.fromString(spec, false, levelInfo)
+	l.filtersMutex.Lock()
+	f := *l.loadFilters()
+	f.log = newLogFilterSpec
+	f.generation++
+	l.filters.Store(&f)
+	l.filtersMutex.Unlock()
+}
+
+// SetTraceLevel replaces the trace level filters of the logger. The spec has
+// the same format as the RLOG_TRACE_LEVEL setting.
+func (l *logger) SetTraceLevel(spec string) {
+	newTraceFilterSpec := new(filterSpec)
+	newTraceFilterSpec.fromString(spec, true, noTraceOutput)
+	l.filtersMutex.Lock()
+	f := *l.loadFilters()
+	f.trace = newTraceFilterSpec
+	f.generation++
+	l.filters.Store(&f)
+	l.filtersMutex.Unlock()
+}
+
+// SetLogLevel replaces the log level filters of the default logger.
+func SetLogLevel(spec string) {
+	DefaultLogger.SetLogLevel(spec)
+}
+
+// SetTraceLevel replaces the trace level filters of the default logger.
+func SetTraceLevel(spec string) {
+	DefaultLogger.SetTraceLevel(spec)
+}
+
 // SetOutput re-wires the log output to a new io.Writer. By default rlog
 // logs to os.Stderr, but this function can be used to direct the output
 // somewhere else. If output to two destinations was specified via environment
@@ -358,18 +366,29 @@ func rlogIssue(prefix string, a ...interface{}) {
 	fmt.Fprintf(os.Stderr, fmtStr, a...)
 }
 
+// filters holds the log and trace filters of a logger. They are replaced as a
+// whole, so that concurrent log calls always see a consistent pair.
+type filters struct {
+	log        *filterSpec
+	trace      *filterSpec
+	generation uint32 // incremented whenever the filters change
+}
+
 type logger struct {
 	mutex                 sync.Mutex
-	logFilterSpec         *filterSpec
-	traceFilterSpec       *filterSpec
+	filters               atomic.Value // *filters
+	filtersMutex          sync.Mutex   // serializes the changes of the filters
 	formatter             LogFormatter
 	additionalInformation string
 	additionalFields      FieldsArr
 
-	settingShowCallerInfo  bool   // whether we log caller info
-	settingShowGoroutineID bool   // whether we show goroutine ID in caller info
-	settingDateTimeFormat  string // flags for date/time output
-	settingConfFile        string // config file name
+	settingShowCallerInfo  bool        // whether we log caller info
+	settingShowGoroutineID bool        // whether we show goroutine ID in caller info
+	settingStacktraceLevel Level       // entries at or above this level get a stack trace
+	settingTimeFormat      *TimeFormat // how the formatters render the time
+	settingColorMode       ColorMode   // when the default formatter colors its output
+	settingColorScheme     ColorScheme // the colors of the default formatter
+	settingConfFile        string      // config file name
 
 	settingCheckInterval time.Duration
 
@@ -380,17 +399,37 @@ type logger struct {
 	currentLogFileName  string
 	logNoTime           bool
 	// name of current log file
+
+	callersMutex sync.RWMutex
+	callers      map[uintptr]*callerInfo // cached caller info per program counter
+
+	redactor             *Redactor     // removes sensitive data before formatting, if set
+	settingDuplicateKeys DuplicateKeys // how fields with the same key are handled
+	settingMultiline     MultilineMode // how messages with line breaks are written
+	clock                Clock         // tells the time of the entries, time.Now if nil
 }
 
 var DefaultLogger *logger
 
+// loadFilters returns the current log and trace filters of the logger.
+func (l *logger) loadFilters() *filters {
+	return l.filters.Load().(*filters)
+}
+
+// closeWriter closes the writer, unless it's one of the standard streams.
+func closeWriter(writer io.Writer) {
+	if closer, ok := writer.(io.Closer); ok && writer != os.Stdout && writer != os.Stderr {
+		closer.Close()
+	}
+}
+
 func (l *logger) Formatter() LogFormatter {
 	return l.formatter
 }
 
 // NewLogger initializes a new instance loading all configuration set in the `config`
 // argument. Then, the new instance is returned ready for use.
-func NewLogger(config Config) (*logger, error) {
+func NewLogger(config Config) (_ *logger, err error) {
 	// initialize filters for trace (by default no trace output) and log levels
 	// (by default INFO level).
 	newTraceFilterSpec := new(filterSpec)
@@ -399,13 +438,19 @@ func NewLogger(config Config) (*logger, error) {
 	newLogFilterSpec := new(filterSpec)
 	newLogFilterSpec.fromString(config.LogLevel, false, levelInfo)
 
-	l := &logger{
-		traceFilterSpec: newTraceFilterSpec,
-		logFilterSpec:   newLogFilterSpec,
-	}
+	l := &logger{}
+	l.filters.Store(&filters{log: newLogFilterSpec, trace: newTraceFilterSpec})
+
+	// The writer of the log stream is started before all the settings are
+	// checked, so it's closed again if one of them is wrong.
+	defer func() {
+		if err != nil {
+			closeWriter(l.logWriterStream)
+		}
+	}()
 
 	var checkTime int
-	checkTime, err := strconv.Atoi(config.confCheckInterv)
+	checkTime, err = strconv.Atoi(config.confCheckInterv)
 	if err == nil {
 		l.settingCheckInterval = time.Duration(checkTime) * time.Second
 	} else {
@@ -418,7 +463,10 @@ func NewLogger(config Config) (*logger, error) {
 	l.settingShowGoroutineID = config.ShowGoroutineID
 
 	// Evaluate the specified date/time format
-	l.settingDateTimeFormat = getTimeFormat(config)
+	l.settingTimeFormat, err = ParseTimeFormat(config.logTimeFormat, config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
 
 	// By default we log to stderr...
 	// Evaluating whether a different log stream should be used.
@@ -428,6 +476,46 @@ func NewLogger(config Config) (*logger, error) {
 	if config.LogStream == "STDOUT" {
 		// l.logWriterStream = log.New(os.Stdout, "", 0)
 		l.logWriterStream = os.Stdout
+	} else if config.LogStream == "SYSLOG" {
+		options, err := syslogOptionsFromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		l.logWriterStream, err = NewSyslogWriter(options)
+		if err != nil {
+			return nil, err
+		}
+	} else if config.LogStream == "JOURNALD" {
+		l.logWriterStream, err = NewJournaldWriter(JournaldOptions{})
+		if err != nil {
+			return nil, err
+		}
+	} else if config.LogStream == "GELF" {
+		l.logWriterStream, err = NewGELFWriter(GELFOptions{
+			Address:     config.GELFAddress,
+			Compression: config.GELFCompression,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if config.LogStream == "OTLP" {
+		resource, err := ParseOTLPResource(config.OTLPResource)
+		if err != nil {
+			return nil, err
+		}
+		l.logWriterStream = NewOTLPExporter(OTLPOptions{
+			Endpoint: config.OTLPEndpoint,
+			Resource: resource,
+		})
+	} else if strings.Contains(config.LogStream, "://") {
+		options, err := ParseNetworkURL(config.LogStream)
+		if err != nil {
+			return nil, err
+		}
+		l.logWriterStream, err = NewNetworkWriter(options)
+		if err != nil {
+			return nil, err
+		}
 	} else if config.LogStream == "NONE" {
 		l.logWriterStream = nil
 	} else {
@@ -435,15 +523,75 @@ func NewLogger(config Config) (*logger, error) {
 		l.logWriterStream = os.Stderr
 	}
 
+	l.settingStacktraceLevel, err = parseStacktraceLevel(config.StacktraceLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	callerFormat, err := ParseCallerFormat(config.CallerFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	l.settingMultiline, err = ParseMultilineMode(config.Multiline)
+	if err != nil {
+		return nil, err
+	}
+
+	l.redactor, err = redactorFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	l.settingDuplicateKeys, err = parseDuplicateKeys(config.DuplicateKeys)
+	if err != nil {
+		return nil, err
+	}
+
 	switch config.Formatter {
 	case "", "default":
+		colorMode, err := ParseColorMode(config.Color)
+		if err != nil {
+			return nil, err
+		}
+		colorScheme, err := ParseColorScheme(config.Colors)
+		if err != nil {
+			return nil, err
+		}
+		l.settingColorMode = colorMode
+		l.settingColorScheme = colorScheme
+		var formatter *defaultFormatter
 		if f, ok := l.logWriterStream.(*os.File); ok {
-			l.formatter = NewDefaultFormatter(f)
+			formatter = NewDefaultFormatterWithColors(f, colorMode, colorScheme)
 		} else {
-			l.formatter = NewDefaultFormatter(nil)
+			formatter = NewDefaultFormatterWithColors(nil, colorMode, colorScheme)
 		}
+		formatter.CallerFormat = callerFormat
+		formatter.Multiline = l.settingMultiline
+		formatter.TimeFormat = l.settingTimeFormat
+		if config.Template != "" {
+			formatter.Template, err = ParseLineTemplate(config.Template)
+			if err != nil {
+				return nil, err
+			}
+		}
+		l.formatter = formatter
 	case "text":
-		l.formatter = &TextFormatter{}
+		l.formatter = &TextFormatter{
+			CallerFormat: callerFormat,
+			Multiline:    l.settingMultiline,
+			TimeFormat:   l.settingTimeFormat,
+		}
+	case "json":
+		l.formatter = &JSONFormatter{TimeFormat: l.settingTimeFormat}
+	case "gelf":
+		l.formatter = NewGELFFormatter()
+	case "otlp":
+		resource, err := ParseOTLPResource(config.OTLPResource)
+		if err != nil {
+			return nil, err
+		}
+		l.formatter = NewOTLPFormatter(resource)
 	default:
 		return nil, fmt.Errorf("formatter '%s' is unknown", config.Formatter)
 	}
@@ -492,11 +640,98 @@ var (
 	}
 )
 
-// basicLog is called by all the 'level' log functions.
+// BasicLog logs a message with the given log and trace level. It is the
+// function all the 'level' log functions end up in, and can be used by
+// wrappers which decide about the level themselves. The trace level only
+// counts for LevelTrace.
+//
+// Like the 'level' log functions, BasicLog reports the code calling it as the
+// caller. Earlier versions reported the function one frame further up, and
+// applied the trace level to all levels. Wrappers skip their own frame with
+// LoggerWithCallerSkip.
+func (l *logger) BasicLog(logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	if logLevel != levelTrace {
+		traceLevel = notATrace
+	}
+	l.log(1, logLevel, traceLevel, additionalInformation, fields, format, a...)
+}
+
+// log is called by all the 'level' log functions.
 // It checks what is configured to be included in the log message, decorates it
 // accordingly and assembles the entire line. It then uses the standard log
 // package to finally output the message.
-func (l *logger) BasicLog(logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+//
+// The skip argument is the number of stack frames between the function calling
+// log and the call site that should be reported, as for runtime.Caller.
+func (l *logger) log(skip int, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	l.logAt(skip+1, 0, logLevel, traceLevel, additionalInformation, fields, format, a...)
+}
+
+// mayLog tells whether any filter lets messages of the given log or trace
+// level through. Adapters use it to skip building entries which would be
+// dropped anyway. The per-file filters are only applied when logging.
+func (l *logger) mayLog(logLevel Level, traceLevel int) bool {
+	f := l.loadFilters()
+	spec := f.log
+	level := int(logLevel)
+	if traceLevel != notATrace {
+		spec = f.trace
+		level = traceLevel
+	}
+	if len(f.log.filters) == 0 && len(f.trace.filters) == 0 {
+		return true
+	}
+	for _, filter := range spec.filters {
+		if level <= int(filter.Level) {
+			return true
+		}
+	}
+	return false
+}
+
+// logPC is like log, but the call site is given by its program counter, as
+// returned by runtime.Callers. It's used by adapters which receive the call
+// site along with the message, like the slog handler.
+func (l *logger) logPC(pc uintptr, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	l.logAt(0, pc, logLevel, traceLevel, additionalInformation, fields, format, a...)
+}
+
+// logAt writes the entry. The call site is given by pc, or if that is 0, by
+// skip (with the same meaning as for runtime.Caller, seen from the function
+// calling logAt).
+func (l *logger) logAt(skip int, pc uintptr, logLevel Level, traceLevel int, additionalInformation string, fields FieldsArr, format string, a ...interface{}) {
+	var ci *callerInfo
+	current := l.loadFilters()
+	if l.settingShowCallerInfo || l.settingShowGoroutineID || current.log.hasAnyFilterAPattern || current.trace.hasAnyFilterAPattern {
+		// Extract information about the caller of the log function. The
+		// lookup, including the decision of the filters, is cached per call
+		// site, so that per-file filtering costs about the same as global
+		// filtering.
+		if pc != 0 {
+			ci = l.callerForPC(pc, current)
+		} else {
+			ci = l.caller(skip+1, current)
+		}
+		if !ci.allows(logLevel, traceLevel) {
+			return
+		}
+	} else if len(current.log.filters) > 0 || len(current.trace.filters) > 0 {
+		// Perform tests to see if we should log this message.
+		var allowLog bool
+		if traceLevel == notATrace {
+			if current.log.matchfilters("", int(logLevel)) {
+				allowLog = true
+			}
+		} else {
+			if current.trace.matchfilters("", traceLevel) {
+				allowLog = true
+			}
+		}
+		if !allowLog {
+			return
+		}
+	}
+
 	entry := entryPool.Get().(*Entry)
 	defer func() {
 		entry.Reset()
@@ -513,70 +748,49 @@ func (l *logger) BasicLog(logLevel Level, traceLevel int, additionalInformation
 	} else {
 		entry.Message = fmt.Sprint(a...)
 	}
+	// Resolving the Valuers and dropping duplicate keys changes the fields,
+	// which are then rendered again.
+	fieldsChanged := false
+	if resolved, ok := resolveFields(entry.Fields); ok {
+		entry.Fields = resolved
+		fieldsChanged = true
+	}
+	if l.settingDuplicateKeys == LastKeyWins {
+		if deduped, ok := dedupFields(entry.Fields); ok {
+			entry.Fields = deduped
+			fieldsChanged = true
+		}
+	}
+	if fieldsChanged {
+		entry.FieldsCache = l.formatter.FormatFields(entry.Fields)
+	}
+	if l.redactor != nil {
+		l.redactor.redact(entry, l.formatter)
+	}
 	if l.logNoTime {
-		entry.Time = ""
+		entry.Time = time.Time{}
 	} else {
-		entry.Time = time.Now().UTC().Format(l.settingDateTimeFormat)
-	}
-
-	if l.settingShowCallerInfo || l.settingShowGoroutineID || (l.logFilterSpec.hasAnyFilterAPattern && len(l.logFilterSpec.filters) > 0) || (l.traceFilterSpec.hasAnyFilterAPattern && len(l.traceFilterSpec.filters) > 0) {
-		// Extract information about the caller of the log function, if requested.
-		var callingFuncName string
-		var moduleAndFileName string
-		pc, fullFilePath, line, ok := runtime.Caller(2)
-		if ok {
-			callingFuncName = runtime.FuncForPC(pc).Name()
-			// We only want to print or examine file and package name, so use the
-			// last two elements of the full path. The path package deals with
-			// different path formats on different systems, so we use that instead
-			// of just string-split.
-			dirPath, fileName := path.Split(fullFilePath)
-			var moduleName string
-			if dirPath != "" {
-				dirPath = dirPath[:len(dirPath)-1]
-				_, moduleName = path.Split(dirPath)
-			}
-			moduleAndFileName = moduleName + "/" + fileName
-		}
+		entry.Time = l.now()
+	}
 
-		// Perform tests to see if we should log this message.
-		var allowLog bool
-		if traceLevel == notATrace {
-			if l.logFilterSpec.matchfilters(moduleAndFileName, int(logLevel)) {
-				allowLog = true
-			}
-		} else {
-			if l.traceFilterSpec.matchfilters(moduleAndFileName, traceLevel) {
-				allowLog = true
-			}
-		}
-		if !allowLog {
-			return
+	if l.settingShowCallerInfo {
+		entry.CallerInfo.PID = os.Getpid()
+		entry.CallerInfo.FileName = ci.moduleAndFileName
+		entry.CallerInfo.FullPath = ci.fullPath
+		entry.CallerInfo.Package = ci.packageName
+		entry.CallerInfo.Line = ci.line
+		entry.CallerInfo.FunctionName = ci.functionName
+		entry.CallerInfo.ShortFunction = ci.shortFunction
+		if l.settingShowGoroutineID {
+			entry.CallerInfo.GID = getGID()
 		}
+	}
 
-		if l.settingShowCallerInfo {
-			entry.CallerInfo.PID = os.Getpid()
-			entry.CallerInfo.FileName = moduleAndFileName
-			entry.CallerInfo.Line = line
-			entry.CallerInfo.FunctionName = callingFuncName
-			if l.settingShowGoroutineID {
-				entry.CallerInfo.GID = getGID()
-			}
-		}
-	} else if len(l.logFilterSpec.filters) > 0 || len(l.traceFilterSpec.filters) > 0 {
-		// Perform tests to see if we should log this message.
-		var allowLog bool
-		if traceLevel == notATrace {
-			if l.logFilterSpec.matchfilters("", int(logLevel)) {
-				allowLog = true
-			}
+	if logLevel <= l.settingStacktraceLevel {
+		if pc != 0 {
+			entry.Stack = appendStackAt(entry.Stack, pc)
 		} else {
-			if l.traceFilterSpec.matchfilters("", traceLevel) {
-				allowLog = true
-			}
-		}
-		if !allowLog {
-			return
+			entry.Stack = appendStack(entry.Stack, skip+1)
 		}
 	}
 
@@ -588,18 +802,34 @@ func (l *logger) BasicLog(logLevel Level, traceLevel int, additionalInformation
 		msgCapacity++
 	}
 
-	line := l.Formatter().Format(entry)
-	if l.logWriterStream != nil {
-		func() {
-			l.mutex.Lock()
-			l.mutex.Unlock()
-			l.logWriterStream.Write(line)
-		}()
+	if l.settingMultiline == MultilineSplit && strings.IndexByte(entry.Message, '\n') >= 0 {
+		l.writeLines(entry)
+		return
+	}
+	l.write(entry)
+}
+
+// write sends the entry to the outputs. The line is only formatted if an
+// output needs it. Outputs implementing EntryWriter get the entry itself.
+func (l *logger) write(entry *Entry) {
+	var line []byte
+	if entryWriter, ok := l.logWriterStream.(EntryWriter); ok {
+		entryWriter.WriteEntry(entry)
+	} else if l.logWriterStream != nil {
+		line = l.Formatter().Format(entry)
+		l.mutex.Lock()
+		l.logWriterStream.Write(line)
+		l.mutex.Unlock()
 	}
 	if l.logWriterFile != nil {
+		if line == nil {
+			line = l.Formatter().Format(entry)
+		}
 		l.logWriterFile.Print(string(line))
 	}
-	ReleaseOutput(line)
+	if line != nil {
+		ReleaseOutput(line)
+	}
 }
 
 func (l *logger) WithPrefix(prefix string) Logger {
@@ -620,16 +850,12 @@ func (l *logger) WithFieldsArr(fields ...interface{}) Logger {
 	return newSubLogger(l, fields)
 }
 
-// getGID gets the current goroutine ID (algorithm from
-// https://blog.sgmansfield.com/2015/12/goroutine-ids/) by
-// unwinding the stack.
-func getGID() uint64 {
-	b := make([]byte, 64)
-	b = b[:runtime.Stack(b, false)]
-	b = bytes.TrimPrefix(b, []byte("goroutine "))
-	b = b[:bytes.IndexByte(b, ' ')]
-	n, _ := strconv.ParseUint(string(b), 10, 64)
-	return n
+// WithCallerSkip returns a sub-logger which reports the caller `skip` stack
+// frames further up. See LoggerWithCallerSkip.
+func (l *logger) WithCallerSkip(skip int) Logger {
+	sl := newSubLogger(l, nil)
+	sl.callerSkip = skip
+	return sl
 }
 
 // Trace is for low level tracing of activities. It takes an additional 'level'
@@ -640,8 +866,8 @@ func getGID() uint64 {
 func (l *logger) Trace(traceLevel int, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
-	if len(l.traceFilterSpec.filters) > 0 {
-		l.BasicLog(levelTrace, traceLevel, "", l.additionalFields, "", a...)
+	if len(l.loadFilters().trace.filters) > 0 {
+		l.log(1, levelTrace, traceLevel, "", l.additionalFields, "", a...)
 	}
 }
 
@@ -649,37 +875,37 @@ func (l *logger) Trace(traceLevel int, a ...interface{}) {
 func (l *logger) Tracef(traceLevel int, format string, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
-	if len(l.traceFilterSpec.filters) > 0 {
-		l.BasicLog(levelTrace, traceLevel, "", l.additionalFields, format, a...)
+	if len(l.loadFilters().trace.filters) > 0 {
+		l.log(1, levelTrace, traceLevel, "", l.additionalFields, format, a...)
 	}
 }
 
 // Debug prints a message if RLOG_LEVEL is set to DEBUG.
 func (l *logger) Debug(a ...interface{}) {
-	l.BasicLog(levelDebug, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelDebug, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Debugf prints a message if RLOG_LEVEL is set to DEBUG, with formatting.
 func (l *logger) Debugf(format string, a ...interface{}) {
-	l.BasicLog(levelDebug, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelDebug, notATrace, "", l.additionalFields, format, a...)
 }
 
 // Info prints a message if RLOG_LEVEL is set to INFO or lower.
 func (l *logger) Info(a ...interface{}) {
-	l.BasicLog(levelInfo, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelInfo, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Infof prints a message if RLOG_LEVEL is set to INFO or lower, with
 // formatting.
 func (l *logger) Infof(format string, a ...interface{}) {
-	l.BasicLog(levelInfo, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelInfo, notATrace, "", l.additionalFields, format, a...)
 }
 
 // Println prints a message if RLOG_LEVEL is set to INFO or lower.
 // Println shouldn't be used except for backward compatibility
 // with standard log package, directly using Info is preferred way.
 func (l *logger) Println(a ...interface{}) {
-	l.BasicLog(levelInfo, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelInfo, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Printf prints a message if RLOG_LEVEL is set to INFO or lower, with
@@ -687,40 +913,40 @@ func (l *logger) Println(a ...interface{}) {
 // Printf shouldn't be used except for backward compatibility
 // with standard log package, directly using Infof is preferred way.
 func (l *logger) Printf(format string, a ...interface{}) {
-	l.BasicLog(levelInfo, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelInfo, notATrace, "", l.additionalFields, format, a...)
 }
 
 // Warn prints a message if RLOG_LEVEL is set to WARN or lower.
 func (l *logger) Warn(a ...interface{}) {
-	l.BasicLog(levelWarn, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelWarn, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Warnf prints a message if RLOG_LEVEL is set to WARN or lower, with
 // formatting.
 func (l *logger) Warnf(format string, a ...interface{}) {
-	l.BasicLog(levelWarn, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelWarn, notATrace, "", l.additionalFields, format, a...)
 }
 
 // Error prints a message if RLOG_LEVEL is set to ERROR or lower.
 func (l *logger) Error(a ...interface{}) {
-	l.BasicLog(levelErr, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelErr, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Errorf prints a message if RLOG_LEVEL is set to ERROR or lower, with
 // formatting.
 func (l *logger) Errorf(format string, a ...interface{}) {
-	l.BasicLog(levelErr, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelErr, notATrace, "", l.additionalFields, format, a...)
 }
 
 // Critical prints a message if RLOG_LEVEL is set to CRITICAL or lower.
 func (l *logger) Critical(a ...interface{}) {
-	l.BasicLog(levelCrit, notATrace, "", l.additionalFields, "", a...)
+	l.log(1, levelCrit, notATrace, "", l.additionalFields, "", a...)
 }
 
 // Criticalf prints a message if RLOG_LEVEL is set to CRITICAL or lower, with
 // formatting.
 func (l *logger) Criticalf(format string, a ...interface{}) {
-	l.BasicLog(levelCrit, notATrace, "", l.additionalFields, format, a...)
+	l.log(1, levelCrit, notATrace, "", l.additionalFields, format, a...)
 }
 
 // WithField returns a new sublogger with the new field in the context.
@@ -736,8 +962,8 @@ func WithFields(fields Fields) Logger {
 func Trace(traceLevel int, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
-	if len(DefaultLogger.traceFilterSpec.filters) > 0 {
-		DefaultLogger.BasicLog(levelTrace, traceLevel, "", nil, "", a...)
+	if len(DefaultLogger.loadFilters().trace.filters) > 0 {
+		DefaultLogger.log(1, levelTrace, traceLevel, "", nil, "", a...)
 	}
 }
 
@@ -745,37 +971,37 @@ func Trace(traceLevel int, a ...interface{}) {
 func Tracef(traceLevel int, format string, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
-	if len(DefaultLogger.traceFilterSpec.filters) > 0 {
-		DefaultLogger.BasicLog(levelTrace, traceLevel, "", nil, format, a...)
+	if len(DefaultLogger.loadFilters().trace.filters) > 0 {
+		DefaultLogger.log(1, levelTrace, traceLevel, "", nil, format, a...)
 	}
 }
 
 // Debug prints a message if RLOG_LEVEL is set to DEBUG.
 func Debug(a ...interface{}) {
-	DefaultLogger.BasicLog(levelDebug, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelDebug, notATrace, "", nil, "", a...)
 }
 
 // Debugf prints a message if RLOG_LEVEL is set to DEBUG, with formatting.
 func Debugf(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelDebug, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelDebug, notATrace, "", nil, format, a...)
 }
 
 // Info prints a message if RLOG_LEVEL is set to INFO or lower.
 func Info(a ...interface{}) {
-	DefaultLogger.BasicLog(levelInfo, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelInfo, notATrace, "", nil, "", a...)
 }
 
 // Infof prints a message if RLOG_LEVEL is set to INFO or lower, with
 // formatting.
 func Infof(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelInfo, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelInfo, notATrace, "", nil, format, a...)
 }
 
 // Println prints a message if RLOG_LEVEL is set to INFO or lower.
 // Println shouldn't be used except for backward compatibility
 // with standard log package, directly using Info is preferred way.
 func Println(a ...interface{}) {
-	DefaultLogger.BasicLog(levelInfo, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelInfo, notATrace, "", nil, "", a...)
 }
 
 // Printf prints a message if RLOG_LEVEL is set to INFO or lower, with
@@ -783,38 +1009,38 @@ func Println(a ...interface{}) {
 // Printf shouldn't be used except for backward compatibility
 // with standard log package, directly using Infof is preferred way.
 func Printf(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelInfo, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelInfo, notATrace, "", nil, format, a...)
 }
 
 // Warn prints a message if RLOG_LEVEL is set to WARN or lower.
 func Warn(a ...interface{}) {
-	DefaultLogger.BasicLog(levelWarn, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelWarn, notATrace, "", nil, "", a...)
 }
 
 // Warnf prints a message if RLOG_LEVEL is set to WARN or lower, with
 // formatting.
 func Warnf(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelWarn, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelWarn, notATrace, "", nil, format, a...)
 }
 
 // Error prints a message if RLOG_LEVEL is set to ERROR or lower.
 func Error(a ...interface{}) {
-	DefaultLogger.BasicLog(levelErr, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelErr, notATrace, "", nil, "", a...)
 }
 
 // Errorf prints a message if RLOG_LEVEL is set to ERROR or lower, with
 // formatting.
 func Errorf(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelErr, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelErr, notATrace, "", nil, format, a...)
 }
 
 // Critical prints a message if RLOG_LEVEL is set to CRITICAL or lower.
 func Critical(a ...interface{}) {
-	DefaultLogger.BasicLog(levelCrit, notATrace, "", nil, "", a...)
+	DefaultLogger.log(1, levelCrit, notATrace, "", nil, "", a...)
 }
 
 // Criticalf prints a message if RLOG_LEVEL is set to CRITICAL or lower, with
 // formatting.
 func Criticalf(format string, a ...interface{}) {
-	DefaultLogger.BasicLog(levelCrit, notATrace, "", nil, format, a...)
+	DefaultLogger.log(1, levelCrit, notATrace, "", nil, format, a...)
 }
@@ -19,11 +19,13 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -426,6 +428,86 @@ var _ = Describe("RLog Test Suite", func() {
 level=TRACE(10) msg="this is a TRACE(10)"`))
 		})
 	})
+
+	Describe("Filters", func() {
+		It("should apply the log level of a matching file", func() {
+			logger, err := NewLogger(Config{
+				Formatter: "text",
+				LogNoTime: true,
+				LogLevel:  "rlog_test.go=ERROR,DEBUG",
+			})
+			buff := bytes.NewBuffer(nil)
+			logger.SetOutput(buff)
+			Expect(err).ToNot(HaveOccurred())
+			logger.Info("this is a INFO")
+			logger.Error("this is a ERROR")
+			Expect(strings.TrimSpace(buff.String())).To(Equal(`level=ERROR msg="this is a ERROR"`))
+		})
+
+		It("should apply the trace level of a matching file", func() {
+			logger, err := NewLogger(Config{
+				Formatter:  "text",
+				LogNoTime:  true,
+				TraceLevel: "other.go=10,rlog_*.go=2",
+			})
+			buff := bytes.NewBuffer(nil)
+			logger.SetOutput(buff)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 1; i <= 3; i++ {
+				logger.Trace(i, "this is a TRACE")
+			}
+			Expect(strings.TrimSpace(buff.String())).To(Equal(`level=TRACE(1) msg="this is a TRACE"
+level=TRACE(2) msg="this is a TRACE"`))
+		})
+
+		It("should re-evaluate cached call sites when the filters change", func() {
+			logger, err := NewLogger(Config{
+				Formatter: "text",
+				LogNoTime: true,
+				LogLevel:  "rlog_test.go=ERROR",
+			})
+			buff := bytes.NewBuffer(nil)
+			logger.SetOutput(buff)
+			Expect(err).ToNot(HaveOccurred())
+			logAt := func(msg string) {
+				logger.Info(msg)
+			}
+			logAt("first")
+			logger.SetLogLevel("rlog_test.go=INFO")
+			logAt("second")
+			logger.SetLogLevel("rlog_test.go=WARN")
+			logAt("third")
+			Expect(strings.TrimSpace(buff.String())).To(Equal(`level=INFO msg="second"`))
+		})
+
+		It("should change the filters while logging concurrently", func() {
+			logger, err := NewLogger(Config{
+				Formatter: "text",
+				LogNoTime: true,
+				LogLevel:  "rlog_test.go=ERROR",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			logger.SetOutput(ioutil.Discard)
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for n := 0; n < 200; n++ {
+						logger.Info("info")
+						logger.Trace(1, "trace")
+						logger.mayLog(levelDebug, notATrace)
+					}
+				}()
+			}
+			for n := 0; n < 100; n++ {
+				logger.SetLogLevel("rlog_test.go=INFO")
+				logger.SetTraceLevel("*=2")
+				logger.SetLogLevel("WARN")
+			}
+			wg.Wait()
+		})
+	})
 })
 
 // writeLogfile is a small utility function for the creation of unique config
@@ -442,7 +524,7 @@ func writeLogfile(lines []string) string {
 
 // checkLogFilter simplifies the checking of correct log levels in the tests.
 func checkLogFilter(t *testing.T, shouldPattern string, shouldLevel int) {
-	f := DefaultLogger.logFilterSpec.filters[0]
+	f := DefaultLogger.loadFilters().log.filters[0]
 	if f.Pattern != shouldPattern || int(f.Level) != shouldLevel {
 		t.Fatalf("Incorrect default filter '%s' / %d. Should be: '%s' / %d",
 			f.Pattern, f.Level, shouldPattern, shouldLevel)
@@ -526,6 +608,48 @@ func BenchmarkWithCallerInfo(b *testing.B) {
 	}
 }
 
+// BenchmarkFilters compares global filtering with per-file filtering for a
+// growing number of file patterns. Both the case where the message passes the
+// filters and where it is dropped are measured.
+func BenchmarkFilters(b *testing.B) {
+	for _, filterCount := range []int{0, 1, 4, 16} {
+		patterns := make([]string, 0, filterCount+1)
+		for i := 0; i < filterCount; i++ {
+			patterns = append(patterns, fmt.Sprintf("file%d.go=DEBUG", i))
+		}
+		patterns = append(patterns, "WARN")
+		logLevel := strings.Join(patterns, ",")
+
+		b.Run(fmt.Sprintf("filters=%d/dropped", filterCount), func(b *testing.B) {
+			logger, err := NewLogger(Config{
+				LogLevel: logLevel,
+			})
+			if err != nil {
+				panic(err)
+			}
+			logger.SetOutput(ioutil.Discard)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				logger.Info("this is a test")
+			}
+		})
+
+		b.Run(fmt.Sprintf("filters=%d/logged", filterCount), func(b *testing.B) {
+			logger, err := NewLogger(Config{
+				LogLevel: logLevel,
+			})
+			if err != nil {
+				panic(err)
+			}
+			logger.SetOutput(ioutil.Discard)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				logger.Warn("this is a test")
+			}
+		})
+	}
+}
+
 func BenchmarkMaps(b *testing.B) {
 	b.ResetTimer()
 	s := 0
@@ -0,0 +1,202 @@
+package rlogtest
+
+import (
+	"fmt"
+
+	"github.com/lab259/rlog/v2"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveLevel succeeds if the actual rlog.Entry has the level.
+func HaveLevel(level rlog.Level) types.GomegaMatcher {
+	return &entryMatcher{
+		description: fmt.Sprintf("level %s", level),
+		match: func(entry *rlog.Entry) (bool, error) {
+			return entry.Level == level, nil
+		},
+	}
+}
+
+// HaveTraceLevel succeeds if the actual rlog.Entry is a trace entry of the
+// trace level.
+func HaveTraceLevel(traceLevel int) types.GomegaMatcher {
+	return &entryMatcher{
+		description: fmt.Sprintf("trace level %d", traceLevel),
+		match: func(entry *rlog.Entry) (bool, error) {
+			return entry.Level == rlog.LevelTrace && entry.TraceLevel == traceLevel, nil
+		},
+	}
+}
+
+// HaveMessage succeeds if the message of the actual rlog.Entry equals the
+// expected string, or satisfies the expected matcher.
+func HaveMessage(expected interface{}) types.GomegaMatcher {
+	matcher := asMatcher(expected)
+	return &entryMatcher{
+		description: "message " + describeExpected(expected),
+		match: func(entry *rlog.Entry) (bool, error) {
+			return matcher.Match(entry.Message)
+		},
+	}
+}
+
+// HaveField succeeds if the actual rlog.Entry has a field with the key. If a
+// value is given, the field has to equal it, or satisfy it if it's a matcher.
+func HaveField(key string, value ...interface{}) types.GomegaMatcher {
+	if len(value) > 1 {
+		panic("HaveField takes at most one value")
+	}
+	description := fmt.Sprintf("field %q", key)
+	var matcher types.GomegaMatcher
+	if len(value) == 1 {
+		matcher = asMatcher(value[0])
+		description += " with value " + describeExpected(value[0])
+	}
+	return &entryMatcher{
+		description: description,
+		match: func(entry *rlog.Entry) (bool, error) {
+			v, ok := Field(*entry, key)
+			if !ok || matcher == nil {
+				return ok, nil
+			}
+			return matcher.Match(v)
+		},
+	}
+}
+
+// ContainEntry succeeds if the actual *Logger or Entries has an entry which
+// satisfies all the matchers, for example HaveLevel and HaveField.
+func ContainEntry(matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return &containEntryMatcher{matcher: gomega.SatisfyAll(matchers...)}
+}
+
+// asMatcher wraps a value into an Equal matcher, unless it's a matcher.
+func asMatcher(expected interface{}) types.GomegaMatcher {
+	if matcher, ok := expected.(types.GomegaMatcher); ok {
+		return matcher
+	}
+	return gomega.Equal(expected)
+}
+
+// describeExpected renders an expected value, or names the expecting matcher.
+func describeExpected(expected interface{}) string {
+	if _, ok := expected.(types.GomegaMatcher); ok {
+		return fmt.Sprintf("satisfying %T", expected)
+	}
+	return fmt.Sprintf("%#v", expected)
+}
+
+// toEntry accepts an rlog.Entry or a pointer to one.
+func toEntry(actual interface{}) (*rlog.Entry, error) {
+	switch entry := actual.(type) {
+	case rlog.Entry:
+		return &entry, nil
+	case *rlog.Entry:
+		if entry != nil {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("expected an rlog.Entry, got:\n%s", format.Object(actual, 1))
+}
+
+// toEntries accepts a *Logger or Entries.
+func toEntries(actual interface{}) (Entries, error) {
+	switch entries := actual.(type) {
+	case *Logger:
+		return entries.Entries(), nil
+	case Entries:
+		return entries, nil
+	case []rlog.Entry:
+		return entries, nil
+	}
+	return nil, fmt.Errorf("expected a *rlogtest.Logger or rlogtest.Entries, got:\n%s", format.Object(actual, 1))
+}
+
+// entryMatcher matches a single entry.
+type entryMatcher struct {
+	description string
+	match       func(entry *rlog.Entry) (bool, error)
+}
+
+func (m *entryMatcher) Match(actual interface{}) (bool, error) {
+	entry, err := toEntry(actual)
+	if err != nil {
+		return false, err
+	}
+	return m.match(entry)
+}
+
+func (m *entryMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n%s%s\nto have %s", format.Indent, describeEntry(actual), m.description)
+}
+
+func (m *entryMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n%s%s\nnot to have %s", format.Indent, describeEntry(actual), m.description)
+}
+
+// containEntryMatcher looks for a matching entry.
+type containEntryMatcher struct {
+	matcher types.GomegaMatcher
+}
+
+func (m *containEntryMatcher) Match(actual interface{}) (bool, error) {
+	entries, err := toEntries(actual)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		ok, err := m.matcher.Match(entry)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *containEntryMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected an entry matching all of the matchers in\n%s", describeEntries(actual))
+}
+
+func (m *containEntryMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected no entry matching all of the matchers in\n%s", describeEntries(actual))
+}
+
+// describeEntry renders an entry for failure messages.
+func describeEntry(actual interface{}) string {
+	entry, err := toEntry(actual)
+	if err != nil {
+		return format.Object(actual, 1)
+	}
+	s := fmt.Sprintf("%s %q", entry.Level, entry.Message)
+	if entry.Level == rlog.LevelTrace {
+		s = fmt.Sprintf("%s(%d) %q", entry.Level, entry.TraceLevel, entry.Message)
+	}
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		s += fmt.Sprintf(" %v=%v", entry.Fields[i], entry.Fields[i+1])
+	}
+	return s
+}
+
+// describeEntries renders the entries for failure messages, one per line.
+func describeEntries(actual interface{}) string {
+	entries, err := toEntries(actual)
+	if err != nil {
+		return format.Object(actual, 1)
+	}
+	if len(entries) == 0 {
+		return format.Indent + "(no entries)"
+	}
+	s := ""
+	for i, entry := range entries {
+		if i > 0 {
+			s += "\n"
+		}
+		s += format.Indent + describeEntry(entry)
+	}
+	return s
+}
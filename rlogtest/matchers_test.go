@@ -0,0 +1,62 @@
+package rlogtest
+
+import (
+	"github.com/lab259/rlog/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Matchers", func() {
+	var logs *Logger
+
+	BeforeEach(func() {
+		logs = New()
+		logs.WithFieldsArr("user", "joe", "attempt", 3).Warn("retrying")
+		logs.Trace(2, "details")
+	})
+
+	It("should match single entries", func() {
+		entry := logs.Entries()[0]
+		Expect(entry).To(HaveLevel(rlog.LevelWarn))
+		Expect(entry).ToNot(HaveLevel(rlog.LevelError))
+		Expect(entry).To(HaveMessage("retrying"))
+		Expect(entry).To(HaveMessage(ContainSubstring("retry")))
+		Expect(&entry).To(HaveField("user"))
+		Expect(entry).To(HaveField("attempt", 3))
+		Expect(entry).To(HaveField("attempt", BeNumerically(">", 2)))
+		Expect(entry).ToNot(HaveField("attempt", 4))
+		Expect(entry).ToNot(HaveField("missing"))
+		Expect(logs.Entries()[1]).To(HaveTraceLevel(2))
+		Expect(entry).ToNot(HaveTraceLevel(2))
+	})
+
+	It("should look for an entry matching all matchers", func() {
+		Expect(logs).To(ContainEntry(HaveLevel(rlog.LevelWarn), HaveField("user", "joe")))
+		Expect(logs).ToNot(ContainEntry(HaveLevel(rlog.LevelWarn), HaveMessage("details")))
+		Expect(logs.Entries()).To(ContainEntry(HaveTraceLevel(2)))
+		Expect(Entries(nil)).ToNot(ContainEntry())
+	})
+
+	It("should describe the entries on failure", func() {
+		matcher := ContainEntry(HaveLevel(rlog.LevelError))
+		Expect(matcher.Match(logs)).To(BeFalse())
+		Expect(matcher.FailureMessage(logs)).To(Equal("Expected an entry matching all of the matchers in\n" +
+			`    WARN "retrying" user=joe attempt=3` + "\n" +
+			`    TRACE(2) "details"`))
+
+		matcher = HaveField("user", "ann")
+		Expect(matcher.Match(logs.Entries()[0])).To(BeFalse())
+		Expect(matcher.FailureMessage(logs.Entries()[0])).To(Equal("Expected\n" +
+			`    WARN "retrying" user=joe attempt=3` + "\n" +
+			`to have field "user" with value "ann"`))
+		Expect(HaveMessage(HavePrefix("x")).FailureMessage(logs.Entries()[0])).To(HaveSuffix(
+			"to have message satisfying *matchers.HavePrefixMatcher"))
+	})
+
+	It("should reject other values", func() {
+		_, err := HaveLevel(rlog.LevelInfo).Match("line")
+		Expect(err).To(HaveOccurred())
+		_, err = ContainEntry().Match([]string{"line"})
+		Expect(err).To(HaveOccurred())
+	})
+})
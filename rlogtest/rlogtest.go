@@ -0,0 +1,249 @@
+// Package rlogtest helps testing code which logs. Its Logger records the
+// entries in memory, so tests can check the level, message and fields of what
+// was logged instead of matching formatted lines:
+//
+//	logs := rlogtest.New()
+//	service := NewService(logs)
+//	service.Run()
+//	Expect(logs).To(rlogtest.ContainEntry(
+//		rlogtest.HaveLevel(rlog.LevelWarn),
+//		rlogtest.HaveField("attempt", 3),
+//	))
+package rlogtest
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lab259/rlog/v2"
+)
+
+// Logger is an rlog.Logger which records the entries in memory. It's safe
+// for concurrent use.
+type Logger struct {
+	rlog.Logger
+	mutex   sync.Mutex
+	entries Entries
+}
+
+// New creates a Logger recording all levels, including every trace level,
+// with caller info.
+func New() *Logger {
+	logs, err := NewWithConfig(rlog.Config{
+		LogLevel:       "DEBUG",
+		TraceLevel:     strconv.Itoa(math.MaxInt32),
+		ShowCallerInfo: true,
+	})
+	if err != nil {
+		// The config above is valid.
+		panic(err)
+	}
+	return logs
+}
+
+// NewWithConfig creates a Logger recording what passes the filters of the
+// config, for testing how code behaves at certain levels. The log stream and
+// log file of the config are ignored, so nothing is written or sent anywhere.
+// It fails if another setting of the config is invalid.
+func NewWithConfig(config rlog.Config) (*Logger, error) {
+	config.LogStream = "NONE"
+	config.LogFile = ""
+	l, err := rlog.NewLogger(config)
+	if err != nil {
+		return nil, err
+	}
+	logs := &Logger{Logger: l}
+	l.SetOutput(logs.writer())
+	return logs, nil
+}
+
+// WithCallerSkip returns a sub-logger which reports the caller `skip` stack
+// frames further up. See rlog.LoggerWithCallerSkip.
+func (logs *Logger) WithCallerSkip(skip int) rlog.Logger {
+	return rlog.LoggerWithCallerSkip(logs.Logger, skip)
+}
+
+// WithGroup returns a sub-logger which nests the fields added later under
+// name. See rlog.LoggerWithGroup.
+func (logs *Logger) WithGroup(name string) rlog.Logger {
+	return rlog.LoggerWithGroup(logs.Logger, name)
+}
+
+// WithContext returns a sub-logger whose entries carry the trace_id and
+// span_id of the span active in ctx. See rlog.LoggerWithContext.
+func (logs *Logger) WithContext(ctx context.Context) rlog.Logger {
+	return rlog.LoggerWithContext(logs.Logger, ctx)
+}
+
+// Entries returns a copy of the entries recorded so far.
+func (logs *Logger) Entries() Entries {
+	logs.mutex.Lock()
+	defer logs.mutex.Unlock()
+	entries := make(Entries, len(logs.entries))
+	for i := range logs.entries {
+		entries[i] = copyEntry(&logs.entries[i])
+	}
+	return entries
+}
+
+// Len returns the number of entries recorded so far.
+func (logs *Logger) Len() int {
+	logs.mutex.Lock()
+	defer logs.mutex.Unlock()
+	return len(logs.entries)
+}
+
+// Reset forgets the entries recorded so far.
+func (logs *Logger) Reset() {
+	logs.mutex.Lock()
+	logs.entries = nil
+	logs.mutex.Unlock()
+}
+
+// String renders the entries recorded so far with the formatter of the
+// logger, one line each.
+func (logs *Logger) String() string {
+	var sb strings.Builder
+	for _, entry := range logs.Entries() {
+		line := logs.Formatter().Format(&entry)
+		sb.Write(line)
+		rlog.ReleaseOutput(line)
+	}
+	return sb.String()
+}
+
+func (logs *Logger) writer() *recorder {
+	return &recorder{logs: logs}
+}
+
+// recorder is the output of the logger.
+type recorder struct {
+	logs *Logger
+}
+
+// WriteEntry records a copy of the entry, as the logger reuses it.
+func (r *recorder) WriteEntry(entry *rlog.Entry) error {
+	e := copyEntry(entry)
+	r.logs.mutex.Lock()
+	r.logs.entries = append(r.logs.entries, e)
+	r.logs.mutex.Unlock()
+	return nil
+}
+
+// copyEntry copies the entry along with its fields and stack.
+func copyEntry(entry *rlog.Entry) rlog.Entry {
+	e := *entry
+	e.Fields = append(rlog.FieldsArr(nil), entry.Fields...)
+	e.Stack = append([]rlog.StackFrame(nil), entry.Stack...)
+	return e
+}
+
+// Write records a line written without entry as INFO entry.
+func (r *recorder) Write(p []byte) (int, error) {
+	err := r.WriteEntry(&rlog.Entry{
+		Level:   rlog.LevelInfo,
+		Message: strings.TrimRight(string(p), "\n"),
+	})
+	return len(p), err
+}
+
+// Entries is a list of recorded entries.
+type Entries []rlog.Entry
+
+// FilterLevel returns the entries of the level.
+func (entries Entries) FilterLevel(level rlog.Level) Entries {
+	return entries.filter(func(entry *rlog.Entry) bool {
+		return entry.Level == level
+	})
+}
+
+// FilterTraceLevel returns the trace entries of the trace level.
+func (entries Entries) FilterTraceLevel(traceLevel int) Entries {
+	return entries.filter(func(entry *rlog.Entry) bool {
+		return entry.Level == rlog.LevelTrace && entry.TraceLevel == traceLevel
+	})
+}
+
+// FilterMessage returns the entries whose message contains s.
+func (entries Entries) FilterMessage(s string) Entries {
+	return entries.filter(func(entry *rlog.Entry) bool {
+		return strings.Contains(entry.Message, s)
+	})
+}
+
+// FilterField returns the entries which have the field with the value.
+func (entries Entries) FilterField(key string, value interface{}) Entries {
+	return entries.filter(func(entry *rlog.Entry) bool {
+		v, ok := Field(*entry, key)
+		return ok && reflect.DeepEqual(v, value)
+	})
+}
+
+// Messages returns the messages of the entries.
+func (entries Entries) Messages() []string {
+	messages := make([]string, len(entries))
+	for i := range entries {
+		messages[i] = entries[i].Message
+	}
+	return messages
+}
+
+func (entries Entries) filter(keep func(entry *rlog.Entry) bool) Entries {
+	var result Entries
+	for i := range entries {
+		if keep(&entries[i]) {
+			result = append(result, entries[i])
+		}
+	}
+	return result
+}
+
+// HasField tells whether the entry has a field with the key.
+func HasField(entry rlog.Entry, key string) bool {
+	_, ok := Field(entry, key)
+	return ok
+}
+
+// Field returns the value of the field with the key. If the entry has several
+// fields with the key, the last one wins. Fields nested by groups or Valuers
+// are addressed with dotted keys, e.g. db.query.
+func Field(entry rlog.Entry, key string) (interface{}, bool) {
+	return field(entry.Fields, key)
+}
+
+func field(fields rlog.FieldsArr, key string) (interface{}, bool) {
+	for i := len(fields)/2*2 - 2; i >= 0; i -= 2 {
+		k, ok := fields[i].(string)
+		if !ok {
+			continue
+		}
+		if k == key {
+			return fields[i+1], true
+		}
+		if strings.HasPrefix(key, k+".") {
+			if v, ok := nestedField(fields[i+1], key[len(k)+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// nestedField looks up a key in nested fields.
+func nestedField(data interface{}, key string) (interface{}, bool) {
+	switch nested := data.(type) {
+	case rlog.FieldsArr:
+		return field(nested, key)
+	case rlog.Fields:
+		fields := make(rlog.FieldsArr, 0, 2*len(nested))
+		for k, v := range nested {
+			fields = append(fields, k, v)
+		}
+		return field(fields, key)
+	}
+	return nil, false
+}
@@ -0,0 +1,152 @@
+package rlogtest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lab259/rlog/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRLogTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RLog Test Helpers Test Suite")
+}
+
+var _ = Describe("Logger", func() {
+	It("should record all levels", func() {
+		logs := New()
+		logs.Trace(7, "trace")
+		logs.Debug("debug")
+		logs.Infof("info %d", 1)
+		logs.Warn("warn")
+		logs.Error("error")
+		logs.Critical("critical")
+
+		entries := logs.Entries()
+		Expect(entries.Messages()).To(Equal([]string{"trace", "debug", "info 1", "warn", "error", "critical"}))
+		Expect(entries[0].Level).To(Equal(rlog.LevelTrace))
+		Expect(entries[0].TraceLevel).To(Equal(7))
+		Expect(entries[5].Level).To(Equal(rlog.LevelCritical))
+		Expect(logs.Len()).To(Equal(6))
+	})
+
+	It("should record the fields of sub-loggers", func() {
+		logs := New()
+		logs.WithField("user", "joe").WithFieldsArr("attempt", 3).Warn("retrying")
+
+		entry := logs.Entries()[0]
+		value, ok := Field(entry, "user")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("joe"))
+		Expect(HasField(entry, "attempt")).To(BeTrue())
+		Expect(HasField(entry, "missing")).To(BeFalse())
+	})
+
+	It("should look up grouped fields by dotted keys", func() {
+		logs := New()
+		rlog.LoggerWithGroup(logs.WithGroup("db").WithField("query", "SELECT 1"), "pool").WithField("size", 4).Info("query")
+
+		entry := logs.Entries()[0]
+		value, ok := Field(entry, "db.query")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("SELECT 1"))
+		Expect(entry).To(HaveField("db.pool.size", 4))
+		Expect(HasField(entry, "db.missing")).To(BeFalse())
+	})
+
+	It("should record the caller", func() {
+		logs := New()
+		logs.Info("here")
+		caller := logs.Entries()[0].CallerInfo
+		Expect(caller.FileName).To(Equal("rlogtest/rlogtest_test.go"))
+		Expect(caller.Package).To(Equal("github.com/lab259/rlog/v2/rlogtest"))
+	})
+
+	It("should apply the filters of the config", func() {
+		logs, err := NewWithConfig(rlog.Config{LogLevel: "WARN", TraceLevel: "1"})
+		Expect(err).ToNot(HaveOccurred())
+		logs.Info("dropped")
+		logs.Trace(2, "dropped")
+		logs.Trace(1, "kept")
+		logs.Warn("kept")
+		Expect(logs.Entries().Messages()).To(Equal([]string{"kept", "kept"}))
+	})
+
+	It("should keep the entries independent of the logger", func() {
+		logs := New()
+		sub := logs.WithField("a", 1)
+		sub.Info("first")
+		sub.Info("second")
+		entries := logs.Entries()
+		Expect(entries[0].Message).To(Equal("first"))
+		Expect(entries[0].Fields).To(Equal(rlog.FieldsArr{"a", 1}))
+		entries[0].Fields[1] = 2
+		Expect(logs.Entries()[0].Fields[1]).To(Equal(1))
+	})
+
+	It("should be safe for concurrent use", func() {
+		logs := New()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					logs.Info("hello")
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(logs.Len()).To(Equal(100))
+	})
+
+	It("should forget the entries on reset", func() {
+		logs := New()
+		logs.Info("hello")
+		logs.Reset()
+		Expect(logs.Entries()).To(BeEmpty())
+	})
+
+	It("should render the entries", func() {
+		logs, err := NewWithConfig(rlog.Config{LogNoTime: true, Formatter: "json"})
+		Expect(err).ToNot(HaveOccurred())
+		logs.WithField("a", 1).Info("hello")
+		Expect(logs.String()).To(Equal(`{"level":"INFO","msg":"hello","a":1}` + "\n"))
+	})
+
+	It("should fail with invalid settings", func() {
+		_, err := NewWithConfig(rlog.Config{Formatter: "xml"})
+		Expect(err).To(MatchError(ContainSubstring("xml")))
+	})
+})
+
+var _ = Describe("Entries", func() {
+	var entries Entries
+
+	BeforeEach(func() {
+		logs := New()
+		logs.WithField("user", "joe").Info("login")
+		logs.WithField("user", "ann").Warn("login failed")
+		logs.Trace(2, "details")
+		logs.Trace(3, "more details")
+		entries = logs.Entries()
+	})
+
+	It("should filter by level", func() {
+		Expect(entries.FilterLevel(rlog.LevelWarn).Messages()).To(Equal([]string{"login failed"}))
+		Expect(entries.FilterLevel(rlog.LevelTrace)).To(HaveLen(2))
+		Expect(entries.FilterTraceLevel(3).Messages()).To(Equal([]string{"more details"}))
+	})
+
+	It("should filter by message", func() {
+		Expect(entries.FilterMessage("login")).To(HaveLen(2))
+		Expect(entries.FilterMessage("logout")).To(BeEmpty())
+	})
+
+	It("should filter by field", func() {
+		Expect(entries.FilterField("user", "ann").Messages()).To(Equal([]string{"login failed"}))
+		Expect(entries.FilterField("user", "bob")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,27 @@
+package rlogtest
+
+import (
+	"testing"
+)
+
+// DumpOnFailure writes the recorded entries to the log of the test, if the
+// test failed. It's meant to be deferred, or called from an AfterEach:
+//
+//	logs := rlogtest.New()
+//	defer logs.DumpOnFailure(t)
+func (logs *Logger) DumpOnFailure(t testing.TB) {
+	t.Helper()
+	if t.Failed() {
+		logs.Dump(t)
+	}
+}
+
+// Dump writes the recorded entries to the log of the test.
+func (logs *Logger) Dump(t testing.TB) {
+	t.Helper()
+	if logs.Len() == 0 {
+		t.Log("rlogtest: no entries were logged")
+		return
+	}
+	t.Logf("rlogtest: %d entries were logged:\n%s", logs.Len(), logs.String())
+}
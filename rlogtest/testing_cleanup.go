@@ -0,0 +1,18 @@
+//go:build go1.14
+// +build go1.14
+
+package rlogtest
+
+import (
+	"testing"
+)
+
+// NewT creates a Logger like New, which writes the recorded entries to the
+// log of the test when the test fails.
+func NewT(t testing.TB) *Logger {
+	logs := New()
+	t.Cleanup(func() {
+		logs.DumpOnFailure(t)
+	})
+	return logs
+}
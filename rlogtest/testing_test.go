@@ -0,0 +1,68 @@
+package rlogtest
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeT records what is logged to a test.
+type fakeT struct {
+	testing.TB
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()      {}
+func (t *fakeT) Failed() bool { return t.failed }
+
+func (t *fakeT) Log(args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprint(args...))
+}
+
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+var _ = Describe("testing.TB integration", func() {
+	It("should dump the entries when the test failed", func() {
+		t := &fakeT{failed: true}
+		logs := New()
+		logs.Info("hello")
+		logs.DumpOnFailure(t)
+		Expect(t.logs).To(HaveLen(1))
+		Expect(t.logs[0]).To(HavePrefix("rlogtest: 1 entries were logged:\n"))
+		Expect(t.logs[0]).To(ContainSubstring("hello"))
+	})
+
+	It("should stay quiet when the test passed", func() {
+		t := &fakeT{}
+		logs := New()
+		logs.Info("hello")
+		logs.DumpOnFailure(t)
+		Expect(t.logs).To(BeEmpty())
+	})
+
+	It("should tell that nothing was logged", func() {
+		t := &fakeT{failed: true}
+		New().DumpOnFailure(t)
+		Expect(t.logs).To(Equal([]string{"rlogtest: no entries were logged"}))
+	})
+
+	It("should dump on cleanup with NewT", func() {
+		t := &fakeT{}
+		logs := NewT(t)
+		logs.Info("hello")
+		t.failed = true
+		Expect(t.cleanups).To(HaveLen(1))
+		t.cleanups[0]()
+		Expect(t.logs).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,149 @@
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandlerOptions configures a SlogHandler.
+type SlogHandlerOptions struct {
+	// TraceLevel maps the slog levels below slog.LevelDebug to trace levels.
+	// By default, slog.LevelDebug-1 is trace level 1, slog.LevelDebug-2 is
+	// trace level 2 and so on.
+	TraceLevel func(level slog.Level) int
+}
+
+// SlogHandler is a slog.Handler which passes the records to a Logger. The
+// levels are mapped as follows:
+//
+//   - slog.LevelError+4 and above: CRITICAL
+//   - slog.LevelError and above: ERROR
+//   - slog.LevelWarn and above: WARN
+//   - slog.LevelInfo and above: INFO
+//   - slog.LevelDebug and above: DEBUG
+//   - below slog.LevelDebug: TRACE, see SlogHandlerOptions.TraceLevel
+//
+// The attributes become the fields of the entry, with groups turned into
+// dotted keys ("request.method"). The caller info, and so the per-file
+// filters, refer to the code calling the slog.Logger.
+type SlogHandler struct {
+	logger     Logger
+	traceLevel func(level slog.Level) int
+	// groups is the prefix of the keys, made of the open groups.
+	groups string
+}
+
+// NewSlogHandler creates a handler logging to l. The options may be nil.
+func NewSlogHandler(l Logger, options *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{
+		logger:     l,
+		traceLevel: defaultSlogTraceLevel,
+	}
+	if options != nil && options.TraceLevel != nil {
+		h.traceLevel = options.TraceLevel
+	}
+	return h
+}
+
+// defaultSlogTraceLevel counts the steps below slog.LevelDebug.
+func defaultSlogTraceLevel(level slog.Level) int {
+	return int(slog.LevelDebug - level)
+}
+
+// level translates a slog level into log and trace level.
+func (h *SlogHandler) level(level slog.Level) (Level, int) {
+	switch {
+	case level >= slog.LevelError+4:
+		return levelCrit, notATrace
+	case level >= slog.LevelError:
+		return levelErr, notATrace
+	case level >= slog.LevelWarn:
+		return levelWarn, notATrace
+	case level >= slog.LevelInfo:
+		return levelInfo, notATrace
+	case level >= slog.LevelDebug:
+		return levelDebug, notATrace
+	}
+	traceLevel := h.traceLevel(level)
+	if traceLevel < 0 {
+		traceLevel = 0
+	}
+	return levelTrace, traceLevel
+}
+
+// Enabled tells whether any filter of the logger lets records of the level
+// through. The per-file filters are applied in Handle.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if checker, ok := h.logger.(levelChecker); ok {
+		return checker.mayLog(h.level(level))
+	}
+	return true
+}
+
+// Handle logs the record.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	logLevel, traceLevel := h.level(r.Level)
+	var fields FieldsArr
+	if r.NumAttrs() > 0 {
+		fields = make(FieldsArr, 0, 2*r.NumAttrs())
+		r.Attrs(func(attr slog.Attr) bool {
+			fields = appendSlogAttr(fields, h.groups, attr)
+			return true
+		})
+	}
+	if l, ok := h.logger.(callerLogger); ok && r.PC != 0 {
+		l.logPC(r.PC, logLevel, traceLevel, "", fields, "", r.Message)
+	} else {
+		h.logger.BasicLog(logLevel, traceLevel, "", fields, "", r.Message)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler logging to a sub-logger with the attributes as
+// fields.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make(FieldsArr, 0, 2*len(attrs))
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, h.groups, attr)
+	}
+	c := *h
+	c.logger = h.logger.WithFieldsArr(fields...)
+	return &c
+}
+
+// WithGroup returns a handler which puts the keys of all further attributes
+// into the group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = h.groups + name + "."
+	return &c
+}
+
+// appendSlogAttr appends the attribute as key/value pairs, following the rules
+// of slog.Handler: values are resolved, empty attributes are ignored, groups
+// without a key are inlined and empty groups are dropped.
+func appendSlogAttr(fields FieldsArr, prefix string, attr slog.Attr) FieldsArr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			fields = appendSlogAttr(fields, prefix, a)
+		}
+		return fields
+	}
+	return append(fields, prefix+attr.Key, attr.Value.Any())
+}
@@ -0,0 +1,138 @@
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// slogToken is resolved lazily by slog.
+type slogToken string
+
+func (t slogToken) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+var _ = Describe("SlogHandler", func() {
+	var buff *bytes.Buffer
+
+	newLogger := func(config Config) *logger {
+		config.Formatter = "json"
+		var l *logger
+		l, buff = newTestLogger(config)
+		return l
+	}
+
+	// entries decodes the JSON lines written so far.
+	entries := func() []map[string]interface{} {
+		return decodeEntries(buff)
+	}
+
+	It("should map the levels", func() {
+		l := newLogger(Config{LogLevel: "DEBUG", TraceLevel: "2"})
+		s := slog.New(NewSlogHandler(l, nil))
+		s.Log(context.Background(), slog.LevelError+4, "critical")
+		s.Error("error")
+		s.Warn("warn")
+		s.Info("info")
+		s.Debug("debug")
+		s.Log(context.Background(), slog.LevelDebug-1, "trace 1")
+		s.Log(context.Background(), slog.LevelDebug-2, "trace 2")
+		s.Log(context.Background(), slog.LevelDebug-3, "trace 3")
+
+		result := entries()
+		Expect(result).To(HaveLen(7))
+		levels := []string{"CRITICAL", "ERROR", "WARN", "INFO", "DEBUG", "TRACE", "TRACE"}
+		for i, level := range levels {
+			Expect(result[i]).To(HaveKeyWithValue("level", level))
+		}
+		Expect(result[5]).To(HaveKeyWithValue("trace_level", 1.0))
+		Expect(result[6]).To(HaveKeyWithValue("trace_level", 2.0))
+		Expect(result[6]).To(HaveKeyWithValue("msg", "trace 2"))
+	})
+
+	It("should use the trace mapping of the options", func() {
+		l := newLogger(Config{TraceLevel: "5"})
+		s := slog.New(NewSlogHandler(l, &SlogHandlerOptions{
+			TraceLevel: func(level slog.Level) int {
+				return 2 * int(slog.LevelDebug-level)
+			},
+		}))
+		s.Log(context.Background(), slog.LevelDebug-2, "deep")
+		s.Log(context.Background(), slog.LevelDebug-3, "too deep")
+		Expect(entries()).To(HaveLen(1))
+		Expect(entries()[0]).To(HaveKeyWithValue("trace_level", 4.0))
+	})
+
+	It("should turn the attributes and groups into fields", func() {
+		l := newLogger(Config{})
+		s := slog.New(NewSlogHandler(l, nil))
+		s.Info("request",
+			"status", 200,
+			slog.Group("req", "method", "GET", slog.Group("url", "path", "/")),
+			slog.Group("empty"),
+			slog.Group("", "inline", true),
+			slog.Any("token", slogToken("secret")),
+		)
+		entry := entries()[0]
+		Expect(entry).To(HaveKeyWithValue("msg", "request"))
+		Expect(entry).To(HaveKeyWithValue("status", 200.0))
+		Expect(entry).To(HaveKeyWithValue("req.method", "GET"))
+		Expect(entry).To(HaveKeyWithValue("req.url.path", "/"))
+		Expect(entry).To(HaveKeyWithValue("inline", true))
+		Expect(entry).To(HaveKeyWithValue("token", "***"))
+		Expect(entry).ToNot(HaveKey("empty"))
+	})
+
+	It("should implement WithAttrs and WithGroup", func() {
+		l := newLogger(Config{})
+		s := slog.New(NewSlogHandler(l, nil)).
+			With("service", "api").
+			WithGroup("http").
+			With("method", "POST").
+			WithGroup("response")
+		s.Info("done", "status", 201)
+		entry := entries()[0]
+		Expect(entry).To(HaveKeyWithValue("service", "api"))
+		Expect(entry).To(HaveKeyWithValue("http.method", "POST"))
+		Expect(entry).To(HaveKeyWithValue("http.response.status", 201.0))
+	})
+
+	It("should report the code calling the slog.Logger", func() {
+		l := newLogger(Config{ShowCallerInfo: true})
+		s := slog.New(NewSlogHandler(l.WithField("lib", "db"), nil))
+		_, file, line, _ := runtime.Caller(0)
+		s.Info("connected")
+		entry := entries()[0]
+		Expect(entry).To(HaveKeyWithValue("lib", "db"))
+		caller := entry["caller"].(map[string]interface{})
+		Expect(caller).To(HaveKeyWithValue("path", file))
+		Expect(caller).To(HaveKeyWithValue("line", float64(line+1)))
+	})
+
+	It("should apply the per-file filters to the call site", func() {
+		l := newLogger(Config{LogLevel: "slog_test.go=ERROR,DEBUG"})
+		s := slog.New(NewSlogHandler(l, nil))
+		s.Info("dropped")
+		s.Error("kept")
+		Expect(entries()).To(HaveLen(1))
+		Expect(entries()[0]).To(HaveKeyWithValue("msg", "kept"))
+	})
+
+	It("should tell which levels are enabled", func() {
+		l := newLogger(Config{LogLevel: "WARN"})
+		h := NewSlogHandler(l, nil)
+		Expect(h.Enabled(context.Background(), slog.LevelError)).To(BeTrue())
+		Expect(h.Enabled(context.Background(), slog.LevelWarn)).To(BeTrue())
+		Expect(h.Enabled(context.Background(), slog.LevelInfo)).To(BeFalse())
+		Expect(h.Enabled(context.Background(), slog.LevelDebug-1)).To(BeFalse())
+		Expect(h.WithGroup("g").Enabled(context.Background(), slog.LevelInfo)).To(BeFalse())
+	})
+})
@@ -0,0 +1,76 @@
+package rlog
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth limits the number of frames captured for a stack trace.
+const maxStackDepth = 64
+
+// StackFrame is a single frame of the stack trace attached to an entry.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// parseStacktraceLevel translates the RLOG_STACKTRACE_LEVEL setting. Entries
+// of this level or more severe get a stack trace. An empty setting (or NONE)
+// disables stack traces.
+func parseStacktraceLevel(s string) (Level, error) {
+	if s == "" {
+		return levelNone, nil
+	}
+	level, ok := levelNumbers[strings.ToUpper(s)]
+	if !ok {
+		return levelNone, fmt.Errorf("stack trace level '%s' is unknown", s)
+	}
+	return level, nil
+}
+
+// appendStack captures the stack of the calling goroutine and appends its
+// frames to stack. The skip argument has the same meaning as for
+// runtime.Caller, seen from the function calling appendStack, so the frames of
+// rlog itself can be left out.
+func appendStack(stack []StackFrame, skip int) []StackFrame {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	if n == 0 {
+		return stack
+	}
+	return appendFrames(stack, pcs[:n])
+}
+
+// appendStackAt captures the stack of the calling goroutine, starting at the
+// call site with the given program counter, and appends its frames to stack.
+// If that call site isn't on the stack, nothing is appended.
+func appendStackAt(stack []StackFrame, pc uintptr) []StackFrame {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i := 0; i < n; i++ {
+		if pcs[i] == pc {
+			return appendFrames(stack, pcs[i:n])
+		}
+	}
+	return stack
+}
+
+// appendFrames resolves the program counters and appends their frames to
+// stack.
+func appendFrames(stack []StackFrame, pcs []uintptr) []StackFrame {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, StackFrame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,67 @@
+package rlog
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stacktrace", func() {
+	newStackLogger := func(formatter string) (*logger, *bytes.Buffer) {
+		return newTestLogger(Config{Formatter: formatter, StacktraceLevel: "ERROR"})
+	}
+
+	It("should attach a stack trace starting at the call site", func() {
+		logger, buff := newStackLogger("json")
+		logger.Error("this is a ERROR")
+		logger.WithField("var1", "value1").WithPrefix("prefix").Critical("this is a CRITICAL")
+
+		entries := decodeEntries(buff)
+		Expect(entries).To(HaveLen(2))
+		for _, entry := range entries {
+			Expect(entry).To(HaveKey("stack"))
+			stack := entry["stack"].([]interface{})
+			Expect(len(stack)).To(BeNumerically(">", 1))
+			first := stack[0].(map[string]interface{})
+			Expect(first["file"]).To(HaveSuffix("/stack_test.go"))
+			Expect(first["line"]).To(BeNumerically(">", 0))
+		}
+	})
+
+	It("should not attach a stack trace below the configured level", func() {
+		logger, buff := newStackLogger("json")
+		logger.Warn("this is a WARN")
+		Expect(decodeEntries(buff)[0]).ToNot(HaveKey("stack"))
+	})
+
+	It("should not attach a stack trace when disabled", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		logger.Critical("this is a CRITICAL")
+		Expect(decodeEntries(buff)[0]).ToNot(HaveKey("stack"))
+	})
+
+	It("should print the stack trace indented with the default formatter", func() {
+		logger, buff := newStackLogger("")
+		logger.Error("this is a ERROR")
+		lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+		Expect(lines[0]).To(Equal("ERRO[00000] this is a ERROR"))
+		Expect(len(lines)).To(BeNumerically(">", 2))
+		Expect(lines[1]).To(HavePrefix("\tgithub.com/lab259/rlog/v2."))
+		Expect(lines[2]).To(MatchRegexp(`^\t\t.*/stack_test.go:\d+$`))
+	})
+
+	It("should print the stack trace as an escaped field with the text formatter", func() {
+		logger, buff := newStackLogger("text")
+		logger.Error("this is a ERROR")
+		out := buff.String()
+		Expect(strings.Count(out, "\n")).To(Equal(1))
+		Expect(out).To(MatchRegexp(`^level=ERROR stack="github.com/lab259/rlog/v2\.[^ ]+ \([^"]*/stack_test.go:\d+\)\\n[^"]*" msg="this is a ERROR"\n$`))
+	})
+
+	It("should fail with an unknown stack trace level", func() {
+		_, err := NewLogger(Config{StacktraceLevel: "FATAL"})
+		Expect(err).To(MatchError("stack trace level 'FATAL' is unknown"))
+	})
+})
@@ -0,0 +1,95 @@
+package rlog
+
+import (
+	"log"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// The log levels, for the functions which take a Level.
+const (
+	LevelCritical = levelCrit
+	LevelError    = levelErr
+	LevelWarn     = levelWarn
+	LevelInfo     = levelInfo
+	LevelDebug    = levelDebug
+	LevelTrace    = levelTrace
+)
+
+// What the flags of the log package put in front of a message. Used when the
+// flags of the logger can't be read.
+var (
+	stdLogDateRegexp = regexp.MustCompile(`^[0-9]{4}/[0-9]{2}/[0-9]{2} `)
+	stdLogTimeRegexp = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]{6})? `)
+	stdLogFileRegexp = regexp.MustCompile(`^[^ ]*?:[0-9]+: `)
+)
+
+// stdLogWriter turns the lines written by a *log.Logger into entries.
+type stdLogWriter struct {
+	logger Logger
+	level  Level
+	// std is the logger writing to this writer, or nil for the standard
+	// logger of the log package.
+	std *log.Logger
+}
+
+// NewStdLogger returns a *log.Logger which passes everything logged with it
+// to l, at the given level (trace level 1 for LevelTrace). It's meant for
+// libraries which only accept a *log.Logger. The caller info refers to the
+// code calling the *log.Logger.
+func NewStdLogger(l Logger, level Level) *log.Logger {
+	w := &stdLogWriter{logger: l, level: level}
+	w.std = log.New(w, "", 0)
+	return w.std
+}
+
+// RedirectStdLog sends everything logged through the standard logger of the
+// log package to l, at the given level. It returns a function restoring the
+// previous output, flags and prefix of the standard logger.
+func RedirectStdLog(l Logger, level Level) (restore func()) {
+	flags, prefix, writer := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(&stdLogWriter{logger: l, level: level})
+	return func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// Write logs a line written by the log package.
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := w.strip(strings.TrimSuffix(string(p), "\n"))
+
+	traceLevel := notATrace
+	if w.level == levelTrace {
+		traceLevel = 1
+	}
+	if cl, ok := w.logger.(callerLogger); ok {
+		cl.log(stdLogCallerSkip(), w.level, traceLevel, "", nil, "", msg)
+	} else {
+		w.logger.BasicLog(w.level, traceLevel, "", nil, "", msg)
+	}
+	return len(p), nil
+}
+
+// stdLogCallerSkip finds the code which called the log package, seen from
+// stdLogWriter.Write. How many frames the log package itself has on the stack
+// differs between Go versions, so they are counted.
+func stdLogCallerSkip() int {
+	var pcs [16]uintptr
+	// Skip runtime.Callers, this function and Write.
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := 1
+	for {
+		frame, more := frames.Next()
+		pkg, _ := splitFunctionName(frame.Function)
+		if pkg != "log" || !more {
+			return skip
+		}
+		skip++
+	}
+}
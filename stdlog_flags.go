@@ -0,0 +1,35 @@
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"log"
+	"strings"
+)
+
+// strip removes the prefix, date, time and file the log package may have put
+// in front of the message. Since Go 1.21, the flags and prefix of the logger
+// can be read while it writes.
+func (w *stdLogWriter) strip(msg string) string {
+	flags, prefix := log.Flags(), log.Prefix()
+	if w.std != nil {
+		flags, prefix = w.std.Flags(), w.std.Prefix()
+	}
+	if flags&log.Lmsgprefix == 0 {
+		msg = strings.TrimPrefix(msg, prefix)
+	}
+	if flags&log.Ldate != 0 {
+		msg = stdLogDateRegexp.ReplaceAllString(msg, "")
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		msg = stdLogTimeRegexp.ReplaceAllString(msg, "")
+	}
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		msg = stdLogFileRegexp.ReplaceAllString(msg, "")
+	}
+	if flags&log.Lmsgprefix != 0 {
+		msg = strings.TrimPrefix(msg, prefix)
+	}
+	return msg
+}
@@ -0,0 +1,14 @@
+//go:build !go1.21
+// +build !go1.21
+
+package rlog
+
+// strip removes the date, time and file the log package may have put in front
+// of the message. Before Go 1.21, the flags of the logger can't be read, since
+// the logger holds its lock while it writes, so only what looks like their
+// output is removed.
+func (w *stdLogWriter) strip(msg string) string {
+	msg = stdLogDateRegexp.ReplaceAllString(msg, "")
+	msg = stdLogTimeRegexp.ReplaceAllString(msg, "")
+	return stdLogFileRegexp.ReplaceAllString(msg, "")
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StdLog flags", func() {
+	It("should strip the prefix and the flags", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		std := NewStdLogger(logger, LevelInfo)
+		std.SetPrefix("[lib] ")
+		std.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+		std.Print("first")
+		std.SetFlags(log.Ldate | log.Llongfile | log.Lmsgprefix)
+		std.Print("second")
+
+		entries := decodeEntries(buff)
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0]).To(HaveKeyWithValue("msg", "first"))
+		Expect(entries[1]).To(HaveKeyWithValue("msg", "second"))
+	})
+})
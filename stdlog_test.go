@@ -0,0 +1,103 @@
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StdLog", func() {
+	var (
+		logger *logger
+		buff   *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger, buff = newTestLogger(Config{
+			ShowCallerInfo: true,
+			Formatter:      "json",
+			LogLevel:       "DEBUG",
+		})
+	})
+
+	// entries decodes the JSON lines written so far.
+	entries := func() []map[string]interface{} {
+		return decodeEntries(buff)
+	}
+
+	Describe("NewStdLogger", func() {
+		It("should log the lines at the given level", func() {
+			std := NewStdLogger(logger, LevelWarn)
+			std.Printf("disk %d%% full", 90)
+			Expect(entries()).To(HaveLen(1))
+			Expect(entries()[0]).To(HaveKeyWithValue("level", "WARN"))
+			Expect(entries()[0]).To(HaveKeyWithValue("msg", "disk 90% full"))
+		})
+
+		It("should report the code calling the *log.Logger", func() {
+			std := NewStdLogger(logger, LevelInfo)
+			_, file, line, _ := runtime.Caller(0)
+			std.Print("hello")
+			caller := entries()[0]["caller"].(map[string]interface{})
+			Expect(caller).To(HaveKeyWithValue("path", file))
+			Expect(caller).To(HaveKeyWithValue("line", float64(line+1)))
+		})
+
+		It("should report the caller through sub-loggers", func() {
+			std := NewStdLogger(logger.WithField("lib", "db"), LevelInfo)
+			_, file, line, _ := runtime.Caller(0)
+			std.Println("connected")
+			entry := entries()[0]
+			Expect(entry).To(HaveKeyWithValue("lib", "db"))
+			Expect(entry).To(HaveKeyWithValue("msg", "connected"))
+			caller := entry["caller"].(map[string]interface{})
+			Expect(caller).To(HaveKeyWithValue("path", file))
+			Expect(caller).To(HaveKeyWithValue("line", float64(line+1)))
+		})
+
+		It("should log trace lines at trace level 1", func() {
+			l, err := NewLogger(Config{LogNoTime: true, Formatter: "json", TraceLevel: "1"})
+			Expect(err).ToNot(HaveOccurred())
+			l.SetOutput(buff)
+			NewStdLogger(l, LevelTrace).Print("tracing")
+			Expect(entries()[0]).To(HaveKeyWithValue("level", "TRACE"))
+			Expect(entries()[0]).To(HaveKeyWithValue("trace_level", 1.0))
+		})
+	})
+
+	Describe("RedirectStdLog", func() {
+		It("should send the standard logger to rlog and restore it", func() {
+			previous := bytes.NewBuffer(nil)
+			log.SetOutput(previous)
+			log.SetFlags(log.LstdFlags | log.Lshortfile)
+			log.SetPrefix("app: ")
+			defer func() {
+				log.SetOutput(ioutil.Discard)
+				log.SetFlags(log.LstdFlags)
+				log.SetPrefix("")
+			}()
+
+			restore := RedirectStdLog(logger, LevelError)
+			_, file, line, _ := runtime.Caller(0)
+			log.Printf("failed: %s", "timeout")
+			restore()
+
+			Expect(entries()).To(HaveLen(1))
+			entry := entries()[0]
+			Expect(entry).To(HaveKeyWithValue("level", "ERROR"))
+			Expect(entry).To(HaveKeyWithValue("msg", "failed: timeout"))
+			caller := entry["caller"].(map[string]interface{})
+			Expect(caller).To(HaveKeyWithValue("path", file))
+			Expect(caller).To(HaveKeyWithValue("line", float64(line+1)))
+
+			Expect(previous.Len()).To(BeZero())
+			Expect(log.Writer()).To(BeIdenticalTo(previous))
+			Expect(log.Flags()).To(Equal(log.LstdFlags | log.Lshortfile))
+			Expect(log.Prefix()).To(Equal("app: "))
+		})
+	})
+})
@@ -0,0 +1,347 @@
+package rlog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Translation from log level to syslog severity.
+var syslogSeverities = map[Level]int{
+	levelCrit:  2, // crit
+	levelErr:   3, // err
+	levelWarn:  4, // warning
+	levelInfo:  6, // info
+	levelDebug: 7, // debug
+	levelTrace: 7, // debug
+}
+
+// Translation from facility name to syslog facility code.
+var syslogFacilities = map[string]int{
+	"KERN":     0,
+	"USER":     1,
+	"MAIL":     2,
+	"DAEMON":   3,
+	"AUTH":     4,
+	"SYSLOG":   5,
+	"LPR":      6,
+	"NEWS":     7,
+	"UUCP":     8,
+	"CRON":     9,
+	"AUTHPRIV": 10,
+	"FTP":      11,
+	"LOCAL0":   16,
+	"LOCAL1":   17,
+	"LOCAL2":   18,
+	"LOCAL3":   19,
+	"LOCAL4":   20,
+	"LOCAL5":   21,
+	"LOCAL6":   22,
+	"LOCAL7":   23,
+}
+
+// How messages are delimited on the connection.
+const (
+	syslogFramingNone = iota // datagrams, one message per packet
+	syslogFramingNewline
+	syslogFramingOctetCounting
+)
+
+// The places where the local syslog daemon usually listens.
+var syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogOptions configures a SyslogWriter.
+type SyslogOptions struct {
+	// Network is "udp", "tcp", "unix" or "unixgram". If it is empty, the
+	// local syslog daemon is used.
+	Network string
+	// Address of the syslog server (e.g. "localhost:514" or "/dev/log").
+	Address string
+	// RFC3164 selects the old BSD syslog format instead of RFC 5424.
+	RFC3164 bool
+	// Facility is the syslog facility code. See ParseSyslogFacility.
+	Facility int
+	// AppName identifies the program. By default, it's the name of the
+	// executable.
+	AppName string
+	// StructuredDataID is the SD-ID (e.g. "fields@32473") under which the
+	// fields of an entry are sent as RFC 5424 structured data. If it is
+	// empty, or the RFC 3164 format is used, the fields are appended to the
+	// message instead.
+	StructuredDataID string
+}
+
+// SyslogWriter sends entries to a syslog daemon, mapping the rlog levels to
+// syslog severities. It implements EntryWriter, so it can be used with
+// SetOutput.
+type SyslogWriter struct {
+	mutex    sync.Mutex
+	options  SyslogOptions
+	hostname string
+	pid      int
+	framing  int
+	conn     net.Conn
+}
+
+// NewSyslogWriter connects to the syslog daemon described by the options.
+func NewSyslogWriter(options SyslogOptions) (*SyslogWriter, error) {
+	if options.AppName == "" {
+		options.AppName = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	w := &SyslogWriter{
+		options:  options,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ParseSyslogFacility translates a facility name, like "local0" or "daemon",
+// to its code. An empty name selects the "user" facility.
+func ParseSyslogFacility(name string) (int, error) {
+	if name == "" {
+		return syslogFacilities["USER"], nil
+	}
+	facility, ok := syslogFacilities[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("syslog facility '%s' is unknown", name)
+	}
+	return facility, nil
+}
+
+// syslogOptionsFromConfig translates the RLOG_SYSLOG_* settings.
+func syslogOptionsFromConfig(config Config) (SyslogOptions, error) {
+	var options SyslogOptions
+	if config.SyslogAddress != "" {
+		tokens := strings.SplitN(config.SyslogAddress, "://", 2)
+		if len(tokens) != 2 {
+			return options, fmt.Errorf("syslog address '%s' is malformed, expected <network>://<address>", config.SyslogAddress)
+		}
+		options.Network = strings.ToLower(tokens[0])
+		options.Address = tokens[1]
+	}
+	switch strings.ToUpper(config.SyslogFormat) {
+	case "", "RFC5424":
+	case "RFC3164":
+		options.RFC3164 = true
+	default:
+		return options, fmt.Errorf("syslog format '%s' is unknown", config.SyslogFormat)
+	}
+	facility, err := ParseSyslogFacility(config.SyslogFacility)
+	if err != nil {
+		return options, err
+	}
+	options.Facility = facility
+	options.AppName = config.SyslogAppName
+	options.StructuredDataID = config.SyslogStructuredDataID
+	return options, nil
+}
+
+// connect (re-)establishes the connection to the syslog daemon.
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	var err error
+	switch w.options.Network {
+	case "":
+		// Like log/syslog, try the usual sockets of the local daemon.
+		err = errors.New("no local syslog daemon found")
+		for _, address := range syslogLocalAddresses {
+			if w.conn, err = net.Dial("unixgram", address); err == nil {
+				w.framing = syslogFramingNone
+				return nil
+			}
+			if w.conn, err = net.Dial("unix", address); err == nil {
+				w.framing = syslogFramingNewline
+				return nil
+			}
+		}
+		return err
+	case "tcp", "tcp4", "tcp6":
+		// RFC 5424 messages may contain line breaks, so they are sent with
+		// octet counting. RFC 3164 receivers expect one message per line.
+		if w.options.RFC3164 {
+			w.framing = syslogFramingNewline
+		} else {
+			w.framing = syslogFramingOctetCounting
+		}
+	case "unix":
+		w.framing = syslogFramingNewline
+	case "udp", "udp4", "udp6", "unixgram":
+		w.framing = syslogFramingNone
+	default:
+		return fmt.Errorf("syslog network '%s' is not supported", w.options.Network)
+	}
+	w.conn, err = net.Dial(w.options.Network, w.options.Address)
+	return err
+}
+
+// WriteEntry sends the entry to the syslog daemon. If that fails, the
+// connection is re-established once and the entry is sent again.
+func (w *SyslogWriter) WriteEntry(entry *Entry) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	msg := w.format(entry, entryTime(entry))
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	// The framing may differ after reconnecting to the local daemon.
+	_, err := w.conn.Write(w.format(entry, entryTime(entry)))
+	return err
+}
+
+// Write sends an already formatted line at INFO level. It allows the writer
+// to be used wherever an io.Writer is expected.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	err := w.WriteEntry(&Entry{
+		Level:   levelInfo,
+		Message: strings.TrimRight(string(p), "\n"),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// format renders the entry as syslog message, including the framing needed by
+// the transport.
+func (w *SyslogWriter) format(entry *Entry, timestamp time.Time) []byte {
+	severity, ok := syslogSeverities[entry.Level]
+	if !ok {
+		severity = syslogSeverities[levelInfo]
+	}
+	priority := w.options.Facility*8 + severity
+
+	msg := make([]byte, 0, 256)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(priority), 10)
+	msg = append(msg, '>')
+
+	useStructuredData := !w.options.RFC3164 && w.options.StructuredDataID != ""
+	if w.options.RFC3164 {
+		// <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+		msg = timestamp.AppendFormat(msg, time.Stamp)
+		msg = append(msg, ' ')
+		// The local daemon adds the hostname itself.
+		if w.options.Network != "" {
+			msg = append(msg, w.hostname...)
+			msg = append(msg, ' ')
+		}
+		msg = append(msg, w.options.AppName...)
+		msg = append(msg, '[')
+		msg = strconv.AppendInt(msg, int64(w.pid), 10)
+		msg = append(msg, "]: "...)
+	} else {
+		// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+		msg = append(msg, "1 "...)
+		msg = timestamp.AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+		msg = append(msg, ' ')
+		msg = appendSyslogHeaderField(msg, w.hostname)
+		msg = append(msg, ' ')
+		msg = appendSyslogHeaderField(msg, w.options.AppName)
+		msg = append(msg, ' ')
+		msg = strconv.AppendInt(msg, int64(w.pid), 10)
+		msg = append(msg, " - "...)
+		if useStructuredData && len(entry.Fields) > 0 {
+			msg = w.appendStructuredData(msg, entry.Fields)
+		} else {
+			msg = append(msg, '-')
+		}
+		msg = append(msg, ' ')
+	}
+
+	msg = append(msg, entry.Message...)
+	if !useStructuredData && len(entry.Fields) > 0 {
+		msg = append(msg, ' ')
+		msg = append(msg, defaultTextFormatter.FormatFields(entry.Fields)...)
+	}
+
+	switch w.framing {
+	case syslogFramingNewline:
+		// Non-transparent framing (RFC 6587 3.4.2).
+		return append(msg, '\n')
+	case syslogFramingOctetCounting:
+		// Octet counting (RFC 6587 3.4.1).
+		framed := strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		return append(framed, msg...)
+	}
+	return msg
+}
+
+// appendStructuredData renders the fields as a single SD-ELEMENT.
+func (w *SyslogWriter) appendStructuredData(msg []byte, fields FieldsArr) []byte {
+	msg = append(msg, '[')
+	msg = append(msg, w.options.StructuredDataID...)
+	for i := 0; i+1 < len(fields); i += 2 {
+		msg = append(msg, ' ')
+		msg = appendSyslogParamName(msg, fmt.Sprint(fields[i]))
+		msg = append(msg, `="`...)
+		for _, c := range []byte(fmt.Sprint(fields[i+1])) {
+			// '"', '\' and ']' must be escaped in PARAM-VALUE.
+			if c == '"' || c == '\\' || c == ']' {
+				msg = append(msg, '\\')
+			}
+			msg = append(msg, c)
+		}
+		msg = append(msg, '"')
+	}
+	return append(msg, ']')
+}
+
+// appendSyslogParamName appends a PARAM-NAME, which may only hold up to 32
+// printable US-ASCII characters except '=', ' ', ']' and '"'. Anything else is
+// replaced by '_'.
+func appendSyslogParamName(msg []byte, name string) []byte {
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		msg = append(msg, c)
+	}
+	return msg
+}
+
+// appendSyslogHeaderField appends a header field, using the NILVALUE if it is
+// empty. Header fields can't contain spaces.
+func appendSyslogHeaderField(msg []byte, value string) []byte {
+	if value == "" {
+		return append(msg, '-')
+	}
+	return append(msg, strings.Replace(value, " ", "_", -1)...)
+}
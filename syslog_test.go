@@ -0,0 +1,238 @@
+package rlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Syslog", func() {
+	var (
+		hostname string
+		pid      int
+	)
+
+	BeforeEach(func() {
+		hostname, _ = os.Hostname()
+		pid = os.Getpid()
+	})
+
+	// listenUDP starts a local UDP receiver and returns its address along with
+	// a function returning the next datagram.
+	listenUDP := func() (string, func() string, func()) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		next := func() string {
+			buf := make([]byte, 4096)
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			Expect(err).ToNot(HaveOccurred())
+			return string(buf[:n])
+		}
+		return conn.LocalAddr().String(), next, func() { conn.Close() }
+	}
+
+	It("should map the levels to syslog severities", func() {
+		address, next, stop := listenUDP()
+		defer stop()
+
+		w, err := NewSyslogWriter(SyslogOptions{
+			Network:  "udp",
+			Address:  address,
+			Facility: 16,
+			AppName:  "app",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer w.Close()
+
+		expected := map[Level]int{
+			levelCrit:  16*8 + 2,
+			levelErr:   16*8 + 3,
+			levelWarn:  16*8 + 4,
+			levelInfo:  16*8 + 6,
+			levelDebug: 16*8 + 7,
+			levelTrace: 16*8 + 7,
+		}
+		for level, priority := range expected {
+			Expect(w.WriteEntry(&Entry{Level: level, Message: "message"})).To(Succeed())
+			Expect(next()).To(MatchRegexp(`^<%d>1 \S+ %s app %d - - message$`, priority, hostname, pid))
+		}
+	})
+
+	It("should send the fields as structured data", func() {
+		address, next, stop := listenUDP()
+		defer stop()
+
+		w, err := NewSyslogWriter(SyslogOptions{
+			Network:          "udp",
+			Address:          address,
+			Facility:         1,
+			AppName:          "app",
+			StructuredDataID: "fields@32473",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer w.Close()
+
+		Expect(w.WriteEntry(&Entry{
+			Level:   levelInfo,
+			Message: "message",
+			Fields:  FieldsArr{"user id", 10, "query", `a "b" [c]`},
+		})).To(Succeed())
+		Expect(next()).To(MatchRegexp(`^<14>1 \S+ \S+ app \d+ - \[fields@32473 user_id="10" query="a \\"b\\" \[c\\]"\] message$`))
+	})
+
+	It("should send RFC 3164 messages with the fields appended", func() {
+		address, next, stop := listenUDP()
+		defer stop()
+
+		w, err := NewSyslogWriter(SyslogOptions{
+			Network:  "udp",
+			Address:  address,
+			RFC3164:  true,
+			Facility: 3,
+			AppName:  "app",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer w.Close()
+
+		Expect(w.WriteEntry(&Entry{
+			Level:   levelErr,
+			Message: "message",
+			Fields:  FieldsArr{"var1", "value1"},
+		})).To(Succeed())
+		Expect(next()).To(MatchRegexp(`^<27>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d %s app\[%d\]: message var1=value1$`, hostname, pid))
+	})
+
+	It("should use octet counting over TCP", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+		received := make(chan string, 2)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for i := 0; i < 2; i++ {
+				var length int
+				if _, err := fmt.Fscanf(r, "%d ", &length); err != nil {
+					return
+				}
+				msg := make([]byte, length)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					return
+				}
+				received <- string(msg)
+			}
+		}()
+
+		w, err := NewSyslogWriter(SyslogOptions{
+			Network:  "tcp",
+			Address:  listener.Addr().String(),
+			Facility: 1,
+			AppName:  "app",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer w.Close()
+
+		Expect(w.WriteEntry(&Entry{Level: levelWarn, Message: "first\nline"})).To(Succeed())
+		Expect(w.WriteEntry(&Entry{Level: levelInfo, Message: "second"})).To(Succeed())
+		Eventually(received, 5*time.Second).Should(Receive(HaveSuffix(" - - first\nline")))
+		Eventually(received, 5*time.Second).Should(Receive(HaveSuffix(" - - second")))
+	})
+
+	It("should write to a local unix datagram socket", func() {
+		dir, err := ioutil.TempDir("", "rlog-syslog")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		socket := filepath.Join(dir, "log")
+		conn, err := net.ListenPacket("unixgram", socket)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		logger, err := NewLogger(Config{
+			LogStream:      "SYSLOG",
+			SyslogAddress:  "unixgram://" + socket,
+			SyslogFacility: "daemon",
+			SyslogAppName:  "app",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		logger.Warn("this is a WARN")
+
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf[:n])).To(MatchRegexp(`^<28>1 \S+ \S+ app \d+ - - this is a WARN$`))
+	})
+
+	It("should reconnect when the connection was lost", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+		connections := make(chan net.Conn, 2)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				connections <- conn
+			}
+		}()
+
+		w, err := NewSyslogWriter(SyslogOptions{Network: "tcp", Address: listener.Addr().String(), AppName: "app"})
+		Expect(err).ToNot(HaveOccurred())
+		defer w.Close()
+
+		var first net.Conn
+		Eventually(connections, 5*time.Second).Should(Receive(&first))
+		first.Close()
+		// The first write after the peer closed the connection may still
+		// succeed, the following ones fail and cause a reconnect.
+		Eventually(func() int {
+			w.WriteEntry(&Entry{Level: levelInfo, Message: "message"})
+			return len(connections)
+		}, 5*time.Second).Should(Equal(1))
+		second := <-connections
+		second.Close()
+	})
+
+	It("should reject malformed settings", func() {
+		_, err := syslogOptionsFromConfig(Config{SyslogAddress: "localhost:514"})
+		Expect(err).To(HaveOccurred())
+		_, err = syslogOptionsFromConfig(Config{SyslogFormat: "RFC1234"})
+		Expect(err).To(MatchError("syslog format 'RFC1234' is unknown"))
+		_, err = syslogOptionsFromConfig(Config{SyslogFacility: "local9"})
+		Expect(err).To(MatchError("syslog facility 'local9' is unknown"))
+	})
+
+	It("should translate the settings", func() {
+		options, err := syslogOptionsFromConfig(Config{
+			SyslogAddress:          "TCP://localhost:601",
+			SyslogFormat:           "rfc3164",
+			SyslogFacility:         "LOCAL3",
+			SyslogAppName:          "app",
+			SyslogStructuredDataID: "fields@1",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(options).To(Equal(SyslogOptions{
+			Network:          "tcp",
+			Address:          "localhost:601",
+			RFC3164:          true,
+			Facility:         19,
+			AppName:          "app",
+			StructuredDataID: "fields@1",
+		}))
+	})
+})
@@ -0,0 +1,204 @@
+package rlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// EpochUnit is the unit of timestamps rendered as time since the Unix epoch.
+type EpochUnit int
+
+const (
+	// NoEpoch renders timestamps with the layout of the TimeFormat.
+	NoEpoch EpochUnit = iota
+	// EpochSeconds renders timestamps as seconds since the Unix epoch.
+	EpochSeconds
+	// EpochMillis renders timestamps as milliseconds since the Unix epoch.
+	EpochMillis
+	// EpochNanos renders timestamps as nanoseconds since the Unix epoch.
+	EpochNanos
+)
+
+// TimeFormat tells the formatters how to render the timestamps of the
+// entries. A nil TimeFormat is the DefaultTimeFormat.
+//
+// Layouts without fractional seconds are only rendered once per second: the
+// formatted timestamp of the last second is cached.
+type TimeFormat struct {
+	// Layout is the layout of the timestamps, as in time.Format. It is
+	// ignored if Epoch is set.
+	Layout string
+	// Epoch, if set, renders the timestamps as numbers, which is what most
+	// JSON sinks expect.
+	Epoch EpochUnit
+	// Location is the time zone of the timestamps. When not set UTC is used.
+	Location *time.Location
+
+	cache atomic.Value // *timeCache of the last second rendered
+}
+
+// timeCache holds a timestamp rendered with the layout and location.
+type timeCache struct {
+	layout   string
+	location *time.Location
+	second   int64
+	text     []byte
+}
+
+// DefaultTimeFormat renders RFC3339 timestamps in UTC.
+var DefaultTimeFormat = &TimeFormat{Layout: time.RFC3339, Location: time.UTC}
+
+// Translation from the well known names of RLOG_TIME_FORMAT to layouts.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UNIXDATE":    time.UnixDate,
+	"RUBYDATE":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339NANO": time.RFC3339Nano,
+	"KITCHEN":     time.Kitchen,
+}
+
+// Translation from the names of RLOG_TIME_FORMAT to epoch units.
+var timeEpochs = map[string]EpochUnit{
+	"UNIX":      EpochSeconds,
+	"UNIXMILLI": EpochMillis,
+	"UNIXNANO":  EpochNanos,
+}
+
+// ParseTimeFormat translates the RLOG_TIME_FORMAT and RLOG_TIME_ZONE
+// settings. The format is one of the layout names of package time (e.g.
+// RFC3339, the default, or UnixDate), unix, unixmilli or unixnano for the time
+// since the Unix epoch, or an example date/time as in time.Format. The zone is
+// UTC (default), Local or an IANA name like Europe/Berlin.
+func ParseTimeFormat(format, zone string) (*TimeFormat, error) {
+	timeFormat := &TimeFormat{Layout: format}
+	if layout, ok := timeLayouts[strings.ToUpper(format)]; ok {
+		timeFormat.Layout = layout
+	} else if epoch, ok := timeEpochs[strings.ToUpper(format)]; ok {
+		timeFormat.Layout = ""
+		timeFormat.Epoch = epoch
+	} else if format == "" {
+		timeFormat.Layout = time.RFC3339
+	}
+
+	switch {
+	case zone == "" || strings.EqualFold(zone, "UTC"):
+		timeFormat.Location = time.UTC
+	case strings.EqualFold(zone, "Local"):
+		timeFormat.Location = time.Local
+	default:
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("time zone '%s' is unknown: %s", zone, err)
+		}
+		timeFormat.Location = location
+	}
+	return timeFormat, nil
+}
+
+// Numeric tells whether the timestamps are rendered as numbers.
+func (f *TimeFormat) Numeric() bool {
+	return f != nil && f.Epoch != NoEpoch
+}
+
+// Append appends the timestamp t to the output.
+func (f *TimeFormat) Append(output []byte, t time.Time) []byte {
+	if f == nil {
+		f = DefaultTimeFormat
+	}
+	switch f.Epoch {
+	case EpochSeconds:
+		return strconv.AppendInt(output, t.Unix(), 10)
+	case EpochMillis:
+		return strconv.AppendInt(output, t.UnixNano()/int64(time.Millisecond), 10)
+	case EpochNanos:
+		return strconv.AppendInt(output, t.UnixNano(), 10)
+	}
+	location := f.Location
+	if location == nil {
+		location = time.UTC
+	}
+	layout := f.Layout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	second := t.Unix()
+	// The layout and location are compared too, in case they were changed.
+	if cache, ok := f.cache.Load().(*timeCache); ok && cache.second == second &&
+		cache.layout == layout && cache.location == location {
+		return append(output, cache.text...)
+	}
+	if hasFractionalSeconds(layout) {
+		return t.In(location).AppendFormat(output, layout)
+	}
+	text := t.In(location).AppendFormat(nil, layout)
+	f.cache.Store(&timeCache{layout: layout, location: location, second: second, text: text})
+	return append(output, text...)
+}
+
+// hasFractionalSeconds tells whether the layout renders fractional seconds
+// (.000, ,999 and the like), in which case timestamps of the same second
+// differ. It may report layouts which don't as well, which then aren't cached.
+func hasFractionalSeconds(layout string) bool {
+	for i := 0; i+1 < len(layout); i++ {
+		if (layout[i] == '.' || layout[i] == ',') && (layout[i+1] == '0' || layout[i+1] == '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders the timestamp t.
+func (f *TimeFormat) Format(t time.Time) string {
+	return string(f.Append(make([]byte, 0, 64), t))
+}
+
+// Clock tells the time of the entries. It is time.Now unless replaced with
+// SetClock, e.g. to get deterministic timestamps in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns the time returned by the function.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SetClock sets the clock the logger takes the time of the entries from, or
+// restores time.Now if clock is nil.
+func (l *logger) SetClock(clock Clock) {
+	l.clock = clock
+}
+
+// SetClock sets the clock the default logger takes the time of the entries
+// from, or restores time.Now if clock is nil.
+func SetClock(clock Clock) {
+	DefaultLogger.SetClock(clock)
+}
+
+// now returns the time of a new entry.
+func (l *logger) now() time.Time {
+	if l.clock != nil {
+		return l.clock.Now()
+	}
+	return time.Now()
+}
+
+// entryTime returns the time of the entry, or the current time if it has
+// none, for sinks which always need a timestamp.
+func entryTime(entry *Entry) time.Time {
+	if entry.Time.IsZero() {
+		return time.Now()
+	}
+	return entry.Time
+}
@@ -0,0 +1,187 @@
+package rlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimeFormat", func() {
+	// 2019-04-12T10:00:00.123456789Z
+	t := time.Date(2019, 4, 12, 10, 0, 0, 123456789, time.UTC)
+
+	format := func(format, zone string) string {
+		timeFormat, err := ParseTimeFormat(format, zone)
+		Expect(err).ToNot(HaveOccurred())
+		return timeFormat.Format(t)
+	}
+
+	It("should render RFC3339 in UTC by default", func() {
+		Expect(format("", "")).To(Equal("2019-04-12T10:00:00Z"))
+		Expect((*TimeFormat)(nil).Format(t)).To(Equal("2019-04-12T10:00:00Z"))
+		Expect((&TimeFormat{}).Format(t.In(time.FixedZone("X", 3600)))).To(Equal("2019-04-12T10:00:00Z"))
+	})
+
+	It("should translate the well known layout names", func() {
+		Expect(format("rfc3339nano", "")).To(Equal("2019-04-12T10:00:00.123456789Z"))
+		Expect(format("Kitchen", "")).To(Equal("10:00AM"))
+		Expect(format("2006/01/02 15:04", "")).To(Equal("2019/04/12 10:00"))
+	})
+
+	It("should render the time since the Unix epoch", func() {
+		Expect(format("unix", "")).To(Equal("1555063200"))
+		Expect(format("UnixMilli", "")).To(Equal("1555063200123"))
+		Expect(format("unixnano", "")).To(Equal("1555063200123456789"))
+		timeFormat, err := ParseTimeFormat("unix", "Europe/Berlin")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeFormat.Numeric()).To(BeTrue())
+		Expect(timeFormat.Format(t)).To(Equal("1555063200"))
+	})
+
+	It("should render the time in the time zone", func() {
+		Expect(format("", "utc")).To(Equal("2019-04-12T10:00:00Z"))
+		Expect(format("", "Europe/Berlin")).To(Equal("2019-04-12T12:00:00+02:00"))
+		timeFormat, err := ParseTimeFormat("", "Local")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeFormat.Location).To(Equal(time.Local))
+	})
+
+	It("should fail with unknown time zones", func() {
+		_, err := ParseTimeFormat("", "Mars/Olympus_Mons")
+		Expect(err).To(MatchError(ContainSubstring("Mars/Olympus_Mons")))
+		_, err = NewLogger(Config{TimeZone: "Mars/Olympus_Mons"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should render the timestamps of the same second once", func() {
+		timeFormat, err := ParseTimeFormat("", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeFormat.Format(t)).To(Equal("2019-04-12T10:00:00Z"))
+		Expect(timeFormat.Append([]byte("at "), t.Add(500*time.Millisecond))).To(Equal([]byte("at 2019-04-12T10:00:00Z")))
+		Expect(timeFormat.Format(t.Add(time.Second))).To(Equal("2019-04-12T10:00:01Z"))
+		Expect(timeFormat.Format(t.Add(-time.Second))).To(Equal("2019-04-12T09:59:59Z"))
+	})
+
+	It("should not cache layouts with fractional seconds", func() {
+		timeFormat, err := ParseTimeFormat("15:04:05,000", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeFormat.Format(t)).To(Equal("10:00:00,123"))
+		Expect(timeFormat.Format(t.Add(500 * time.Millisecond))).To(Equal("10:00:00,623"))
+	})
+
+	It("should not use the cache after the format was changed", func() {
+		timeFormat, err := ParseTimeFormat("", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeFormat.Format(t)).To(Equal("2019-04-12T10:00:00Z"))
+		timeFormat.Layout = time.Kitchen
+		Expect(timeFormat.Format(t)).To(Equal("10:00AM"))
+		timeFormat.Location = time.FixedZone("UTC+1", 3600)
+		Expect(timeFormat.Format(t)).To(Equal("11:00AM"))
+	})
+
+	It("should escape layouts in JSON", func() {
+		formatter := &JSONFormatter{TimeFormat: &TimeFormat{Layout: `15:04 "Z"`}}
+		line := formatter.Format(&Entry{Time: t, Level: levelInfo})
+		Expect(string(line)).To(HavePrefix(`{"time":"10:00 \"Z\"",`))
+	})
+
+	Describe("Clock", func() {
+		newClockLogger := func(config Config) (*logger, *bytes.Buffer) {
+			logger, buff := newBufferLogger(config)
+			logger.SetClock(ClockFunc(func() time.Time {
+				return t
+			}))
+			return logger, buff
+		}
+
+		It("should take the time of the entries from the clock", func() {
+			logger, buff := newClockLogger(Config{Color: "never", TimeZone: "Europe/Berlin"})
+			logger.Info("hello")
+			Expect(buff.String()).To(Equal("2019-04-12T12:00:00+02:00 INFO[00000] hello\n"))
+
+			logger, buff = newClockLogger(Config{Formatter: "text"})
+			logger.Info("hello")
+			Expect(buff.String()).To(Equal(`date="2019-04-12T10:00:00Z" level=INFO msg="hello"` + "\n"))
+		})
+
+		It("should render the time since the Unix epoch as number in JSON", func() {
+			logger, buff := newClockLogger(Config{Formatter: "json", logTimeFormat: "unixmilli"})
+			logger.Info("hello")
+			entry := decodeEntry(buff)
+			Expect(entry).To(HaveKeyWithValue("time", float64(1555063200123)))
+
+			logger, buff = newClockLogger(Config{Formatter: "json"})
+			logger.Info("hello")
+			Expect(buff.String()).To(HavePrefix(`{"time":"2019-04-12T10:00:00Z",`))
+		})
+
+		It("should not log the time if disabled", func() {
+			logger, buff := newClockLogger(Config{Formatter: "json", LogNoTime: true})
+			logger.Info("hello")
+			Expect(buff.String()).ToNot(ContainSubstring(`"time"`))
+		})
+
+		It("should fall back to the system clock", func() {
+			logger, buff := newClockLogger(Config{Formatter: "json", logTimeFormat: "unix"})
+			logger.SetClock(nil)
+			before := time.Now().Unix()
+			logger.Info("hello")
+			entry := decodeEntry(buff)
+			Expect(entry["time"]).To(BeNumerically(">=", before))
+		})
+	})
+})
+
+func BenchmarkTimeFormat(b *testing.B) {
+	now := time.Now()
+	output := make([]byte, 0, 64)
+
+	b.Run("time.Format", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			output = now.UTC().AppendFormat(output[:0], time.RFC3339)
+		}
+	})
+	b.Run("RFC3339", func(b *testing.B) {
+		timeFormat := &TimeFormat{Layout: time.RFC3339}
+		for n := 0; n < b.N; n++ {
+			output = timeFormat.Append(output[:0], now)
+		}
+	})
+	b.Run("RFC3339Nano", func(b *testing.B) {
+		timeFormat := &TimeFormat{Layout: time.RFC3339Nano}
+		for n := 0; n < b.N; n++ {
+			output = timeFormat.Append(output[:0], now)
+		}
+	})
+	b.Run("unix", func(b *testing.B) {
+		timeFormat := &TimeFormat{Epoch: EpochSeconds}
+		for n := 0; n < b.N; n++ {
+			output = timeFormat.Append(output[:0], now)
+		}
+	})
+}
+
+func BenchmarkJSONFormatterTime(b *testing.B) {
+	entry := &Entry{
+		Time:    time.Now(),
+		Level:   levelInfo,
+		Message: "this is a test",
+		Fields:  FieldsArr{"var1", "value1"},
+	}
+
+	b.Run("RFC3339", func(b *testing.B) {
+		formatter := &JSONFormatter{TimeFormat: &TimeFormat{Layout: time.RFC3339}}
+		for n := 0; n < b.N; n++ {
+			ReleaseOutput(formatter.Format(entry))
+		}
+	})
+	b.Run("RFC3339Nano", func(b *testing.B) {
+		formatter := &JSONFormatter{TimeFormat: &TimeFormat{Layout: time.RFC3339Nano}}
+		for n := 0; n < b.N; n++ {
+			ReleaseOutput(formatter.Format(entry))
+		}
+	})
+}
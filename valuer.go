@@ -0,0 +1,137 @@
+package rlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Valuer is implemented by types which control how they are logged. When a
+// field value is a Valuer, it is replaced by what LogValue returns. That
+// happens only after the entry passed the filters, so LogValue may be
+// expensive.
+//
+// LogValue may return Fields or a FieldsArr, which are rendered as nested
+// object by the JSON formatters and as dotted keys (e.g. user.id=1) by the
+// text formatters. It may also hide secrets by leaving them out.
+type Valuer interface {
+	LogValue() interface{}
+}
+
+// maxValuerDepth limits how often LogValue is called for one value, in case
+// LogValue returns a Valuer.
+const maxValuerDepth = 100
+
+// resolveValue calls LogValue until the value is no Valuer anymore. Nested
+// fields are resolved as well.
+func resolveValue(data interface{}) interface{} {
+	for i := 0; i < maxValuerDepth; i++ {
+		valuer, ok := data.(Valuer)
+		if !ok {
+			break
+		}
+		data = callLogValue(valuer)
+	}
+	switch v := data.(type) {
+	case FieldsArr:
+		if resolved, changed := resolveFields(v); changed {
+			return resolved
+		}
+	case Fields:
+		if hasValuer(v) {
+			resolved := make(Fields, len(v))
+			for key, value := range v {
+				resolved[key] = resolveValue(value)
+			}
+			return resolved
+		}
+	}
+	return data
+}
+
+// callLogValue calls LogValue, turning a panic into the value.
+func callLogValue(valuer Valuer) (value interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = fmt.Sprintf("!PANIC in LogValue: %v", r)
+		}
+	}()
+	return valuer.LogValue()
+}
+
+// resolveFields resolves the values of the fields. The fields may be shared,
+// so they are copied before a change.
+func resolveFields(fields FieldsArr) (FieldsArr, bool) {
+	var resolved FieldsArr
+	for i := 1; i < len(fields); i += 2 {
+		if resolved == nil {
+			if !hasValuer(fields[i]) {
+				continue
+			}
+			resolved = make(FieldsArr, len(fields))
+			copy(resolved, fields)
+		}
+		resolved[i] = resolveValue(fields[i])
+	}
+	if resolved == nil {
+		return fields, false
+	}
+	return resolved, true
+}
+
+// hasValuer tells whether the value is a Valuer or nested fields containing
+// one.
+func hasValuer(data interface{}) bool {
+	switch v := data.(type) {
+	case Valuer:
+		return true
+	case FieldsArr:
+		for i := 1; i < len(v); i += 2 {
+			if hasValuer(v[i]) {
+				return true
+			}
+		}
+	case Fields:
+		for _, value := range v {
+			if hasValuer(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nestedFields returns the key/value pairs of nested fields, in order for a
+// FieldsArr and sorted by key for Fields.
+func nestedFields(data interface{}) (FieldsArr, bool) {
+	switch v := data.(type) {
+	case FieldsArr:
+		return v, true
+	case Fields:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make(FieldsArr, 0, 2*len(v))
+		for _, key := range keys {
+			fields = append(fields, key, v[key])
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// formatDottedFields renders nested fields as fields whose keys are prefixed
+// by the key of the parent, e.g. user.id=1 user.name=joe.
+func formatDottedFields(key string, fields FieldsArr, separator string, format func(key string, data interface{}) string) string {
+	s := make([]string, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		k, ok := fields[i].(string)
+		if !ok {
+			k = fmt.Sprint(fields[i])
+		}
+		s = append(s, format(key+"."+k, fields[i+1]))
+	}
+	return strings.Join(s, separator)
+}
@@ -0,0 +1,134 @@
+package rlog
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// testUser hides its password and counts how often it was resolved.
+type testUser struct {
+	id       int
+	name     string
+	password string
+	calls    *int
+}
+
+func (u testUser) LogValue() interface{} {
+	*u.calls++
+	return FieldsArr{"id", u.id, "name", u.name}
+}
+
+type testPanicValuer struct{}
+
+func (testPanicValuer) LogValue() interface{} {
+	panic("broken")
+}
+
+type testChainedValuer struct {
+	next Valuer
+}
+
+func (v testChainedValuer) LogValue() interface{} {
+	return v.next
+}
+
+var _ = Describe("Valuer", func() {
+	var calls int
+
+	BeforeEach(func() {
+		calls = 0
+	})
+
+	user := func() testUser {
+		return testUser{id: 1, name: "joe", password: "secret", calls: &calls}
+	}
+
+	It("should render nested objects in JSON", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		logger.WithField("user", user()).Info("login")
+
+		entry := decodeEntry(buff)
+		Expect(entry).To(HaveKeyWithValue("user", map[string]interface{}{
+			"id":   1.0,
+			"name": "joe",
+		}))
+		Expect(buff.String()).ToNot(ContainSubstring("secret"))
+	})
+
+	It("should render dotted keys in text", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text"})
+		logger.WithField("user", user()).WithField("attempt", 2).Info("login")
+
+		Expect(buff.String()).To(ContainSubstring("user.id=1 user.name=joe attempt=2"))
+		Expect(buff.String()).ToNot(ContainSubstring("secret"))
+	})
+
+	It("should render dotted keys in the default formatter", func() {
+		logger, buff := newTestLogger(Config{})
+		logger.WithField("user", user()).Info("login")
+
+		Expect(buff.String()).To(ContainSubstring("user.id=1 user.name=joe"))
+		Expect(buff.String()).ToNot(ContainSubstring("secret"))
+	})
+
+	It("should render dotted keys in GELF", func() {
+		logger, buff := newTestLogger(Config{Formatter: "gelf"})
+		logger.WithField("user", user()).Info("login")
+
+		Expect(buff.String()).To(ContainSubstring(`"_user.id":1,"_user.name":"joe"`))
+	})
+
+	It("should render key/value lists in OTLP", func() {
+		logger, buff := newTestLogger(Config{Formatter: "otlp"})
+		logger.WithField("user", user()).Info("login")
+
+		Expect(buff.String()).To(ContainSubstring(`{"key":"user","value":{"kvlistValue":{"values":[{"key":"id","value":{"intValue":"1"}}`))
+	})
+
+	It("should sort the keys of Fields", func() {
+		logger, buff := newTestLogger(Config{Formatter: "text"})
+		logger.WithField("map", Fields{"b": 2, "a": 1}).Info("sorted")
+
+		Expect(buff.String()).To(ContainSubstring("map.a=1 map.b=2"))
+	})
+
+	It("should resolve only the entries passing the filters", func() {
+		logger, _ := newTestLogger(Config{Formatter: "json"})
+		sub := logger.WithField("user", user())
+		Expect(calls).To(Equal(0))
+		sub.Debug("filtered")
+		Expect(calls).To(Equal(0))
+		sub.Info("logged")
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should pass the resolved fields to the redactor", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		redactor, err := NewRedactor(RedactRule{Keys: []string{"user"}, Strategy: RedactDrop})
+		Expect(err).ToNot(HaveOccurred())
+		logger.SetRedactor(redactor)
+		logger.WithField("user", user()).Info("login")
+
+		Expect(buff.String()).ToNot(ContainSubstring("user"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should resolve chained and nested valuers", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		logger.WithFieldsArr(
+			"chained", testChainedValuer{next: user()},
+			"nested", Fields{"user": user()},
+		).Info("nested")
+
+		entry := decodeEntry(buff)
+		Expect(entry["chained"]).To(HaveKeyWithValue("name", "joe"))
+		Expect(entry["nested"]).To(HaveKeyWithValue("user", HaveKeyWithValue("name", "joe")))
+	})
+
+	It("should survive a panicking LogValue", func() {
+		logger, buff := newTestLogger(Config{Formatter: "json"})
+		logger.WithField("broken", testPanicValuer{}).Info("panic")
+
+		Expect(buff.String()).To(ContainSubstring(`"broken":"!PANIC in LogValue: broken"`))
+	})
+})